@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// vboxManageErrorHints contains human friendly explanations for well-known VirtualBox result codes
+var vboxManageErrorHints = []struct {
+	code string
+	hint string
+}{
+	{virtualboxapi.ObjectNotFound, "VirtualBox object (vm, medium or network) doesn't exist, it was probably removed outside of Terraform."},
+	{virtualboxapi.FileError, "VirtualBox failed to access file, check that path exists and is readable."},
+	{virtualboxapi.InvalidVMState, "Operation is not allowed in current vm state, vm is probably running or locked by another process."},
+	{virtualboxapi.ObjectInUse, "VirtualBox object is in use by another vm or session."},
+	{virtualboxapi.AccessDenied, "Access denied, check permissions of VirtualBox user and vm files."},
+	{virtualboxapi.UnknownOption, "VBoxManage doesn't know one of the options, it's either not supported by installed VirtualBox version " +
+		"or comes from import_extra_args or virt_sysprep_extra_args."},
+	{virtualboxapi.SyntaxError, "VBoxManage rejected command line syntax, check values which end up in it as is, e.g. port forwarding rule " +
+		"name and addresses, which must not contain commas, or import_extra_args."},
+	{virtualboxapi.InvalidArg, "VBoxManage rejected one of the arguments, check attribute values."},
+}
+
+// addError appends error diagnostic, VBoxManage errors with known
+// result codes get an explanation in front of raw command output
+func addError(diags *diag.Diagnostics, summary string, err error) {
+	var vboxErr *virtualboxapi.VBoxManageError
+	if errors.As(err, &vboxErr) {
+		for _, h := range vboxManageErrorHints {
+			if vboxErr.HasCode(h.code) {
+				diags.AddError(summary, h.hint+"\n\n"+err.Error())
+				return
+			}
+		}
+	}
+	diags.AddError(summary, err.Error())
+}
+
+// requireVersion appends attribute error and returns false if installed VirtualBox is older
+// than required. Undetected version isn't checked, VBoxManage reports the failure then
+func requireVersion(ctx context.Context, diags *diag.Diagnostics, attribute string, required virtualboxapi.Version) bool {
+	version, err := virtualboxapi.GetVersion(ctx)
+	if err != nil || !version.Less(required) {
+		return true
+	}
+	diags.AddAttributeError(
+		path.Root(attribute),
+		"Unsupported VirtualBox version",
+		fmt.Sprintf("The %s attribute requires VirtualBox >= %s, but found %s", attribute, required, version),
+	)
+	return false
+}
+
+// destroyFailedVM cleans up partially created vm after failed Create
+func destroyFailedVM(ctx context.Context, vmName string, stopTimeout time.Duration, diags *diag.Diagnostics) {
+	err := virtualboxapi.DestroyVM(ctx, vmName, stopTimeout)
+	if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+		addError(diags, "Error destroying vm", err)
+	}
+}
+
+// unregisterFailedVM cleans up vm registered from existing settings file after failed Create,
+// vm files are kept
+func unregisterFailedVM(ctx context.Context, vmName string, stopTimeout time.Duration, diags *diag.Diagnostics) {
+	_, err := virtualboxapi.PowerOffVM(ctx, vmName, stopTimeout)
+	if err == nil {
+		err = virtualboxapi.UnregisterVM(ctx, vmName)
+	}
+	if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+		addError(diags, "Error unregistering vm", err)
+	}
+}
+
+// destroyFailedVMKeepingDisks cleans up partially created vm after failed Create,
+// standalone disks are detached first, so that they are not deleted with vm files
+func destroyFailedVMKeepingDisks(ctx context.Context, vmName string, diskIDs []string, stopTimeout time.Duration, diags *diag.Diagnostics) {
+	err := detachDisks(ctx, vmName, diskIDs, stopTimeout)
+	if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+		addError(diags, "Error detaching disks", err)
+		// destroying vm would delete disks
+		return
+	}
+	destroyFailedVM(ctx, vmName, stopTimeout, diags)
+}
+
+// vmDescription names vm by last known name and UUID, vm may be renamed outside of Terraform
+func vmDescription(data *VirtualboxVMResourceModel) string {
+	if data.Name.IsNull() || data.Name.ValueString() == "" || data.Name.ValueString() == data.Id.ValueString() {
+		return data.Id.ValueString()
+	}
+	return fmt.Sprintf("%q (%s)", data.Name.ValueString(), data.Id.ValueString())
+}
+
+// addVMError appends error diagnostic of operation on existing vm
+func addVMError(diags *diag.Diagnostics, summary string, data *VirtualboxVMResourceModel, err error) {
+	addError(diags, summary, fmt.Errorf("vm %s: %w", vmDescription(data), err))
+}
+
+// addRemoteUnsupportedError appends attribute error for features which need local access to VirtualBox host
+func addRemoteUnsupportedError(diags *diag.Diagnostics, attribute string) {
+	diags.AddAttributeError(
+		path.Root(attribute),
+		"Not supported with remote host",
+		fmt.Sprintf("The %s attribute needs local access to VirtualBox host and can't be used with provider remote_host", attribute),
+	)
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+const testVMID = "1b2f5d3e-0000-4000-8000-000000000001"
+
+func TestVMDescription(t *testing.T) {
+	tests := []struct {
+		name   string
+		vmName types.String
+		want   string
+	}{
+		{name: "named vm", vmName: types.StringValue("web"), want: `"web" (` + testVMID + `)`},
+		{name: "imported vm without name", vmName: types.StringNull(), want: testVMID},
+		{name: "empty name", vmName: types.StringValue(""), want: testVMID},
+		{name: "name equals id", vmName: types.StringValue(testVMID), want: testVMID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &VirtualboxVMResourceModel{Id: types.StringValue(testVMID), Name: tt.vmName}
+			if got := vmDescription(data); got != tt.want {
+				t.Errorf("vmDescription = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddVMError(t *testing.T) {
+	data := &VirtualboxVMResourceModel{Id: types.StringValue(testVMID), Name: types.StringValue("web")}
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "plain error",
+			err:  errors.New("StopVM: timeout"),
+			want: []string{`vm "web" (` + testVMID + `): StopVM: timeout`},
+		},
+		{
+			// vm renamed or removed outside of Terraform is found by UUID in the message
+			name: "VBoxManage error keeps hint",
+			err: &virtualboxapi.VBoxManageError{
+				Command:  []string{"VBoxManage", "showvminfo", testVMID},
+				ExitCode: 1,
+				Stderr:   "VBoxManage: error: Could not find a registered machine\nVBOX_E_OBJECT_NOT_FOUND",
+			},
+			want: []string{"it was probably removed outside of Terraform", testVMID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			addVMError(&diags, "Error reading vm", data, tt.err)
+			if len(diags) != 1 {
+				t.Fatalf("diagnostics = %v, want one error", diags)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(diags[0].Detail(), want) {
+					t.Errorf("detail %q doesn't contain %q", diags[0].Detail(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddErrorHints(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   string
+	}{
+		{stderr: "VBoxManage: error: Unknown option: --foo", want: "import_extra_args"},
+		{stderr: "Syntax error: Invalid parameter 'rule,name'", want: "must not contain commas"},
+		{stderr: "VBoxManage: error: something else", want: "VBoxManage: error: something else"},
+	}
+	for _, tt := range tests {
+		var diags diag.Diagnostics
+		addError(&diags, "Error", &virtualboxapi.VBoxManageError{Command: []string{"VBoxManage", "modifyvm"}, ExitCode: 1, Stderr: tt.stderr})
+		if len(diags) != 1 || !strings.Contains(diags[0].Detail(), tt.want) {
+			t.Errorf("addError(%q) = %v, want detail containing %q", tt.stderr, diags, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"fmt"
+	"sync"
+)
+
+// hostPortClaim is host port planned by port forwarding rule
+type hostPortClaim struct {
+	hostIP string
+	// owner is `<vm_id>/<rule name>`, empty when vm_id isn't known during plan
+	owner string
+}
+
+// hostPortRegistry collects statically known host ports of all rules planned by provider process.
+// Terraform starts new provider process for every graph walk, so registry covers single plan or apply
+type hostPortRegistry struct {
+	mu     sync.Mutex
+	claims map[string][]hostPortClaim
+}
+
+var plannedHostPorts = &hostPortRegistry{claims: map[string][]hostPortClaim{}}
+
+// claim registers host port and returns owner of conflicting claim, if any.
+// Empty host ip listens on all addresses and conflicts with every other address
+func (r *hostPortRegistry) claim(protocol, hostIP string, hostPort int64, owner string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", protocol, hostPort)
+	for _, existing := range r.claims[key] {
+		if existing.hostIP != "" && hostIP != "" && existing.hostIP != hostIP {
+			continue
+		}
+		// the same rule may be planned more than once, rules of unknown vms are always distinct
+		if owner != "" && existing.owner == owner {
+			return "", false
+		}
+		conflicting := existing.owner
+		if conflicting == "" {
+			conflicting = "rule of vm created by this plan"
+		}
+		return conflicting, true
+	}
+	r.claims[key] = append(r.claims[key], hostPortClaim{hostIP: hostIP, owner: owner})
+	return "", false
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// resolvePath expands leading `~` to home directory of user running Terraform and makes
+// relative path absolute against Terraform working directory. URLs are returned as is
+func resolvePath(p string) (string, error) {
+	if strings.Contains(p, "://") {
+		return p, nil
+	}
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to expand %q: %w", p, err)
+		}
+		p = filepath.Join(home, p[1:])
+	}
+	return filepath.Abs(p)
+}
+
+// resolveAttributePath resolves path attribute value and logs the result, VirtualBox
+// errors mention only the path they got, so resolved one is reported as well
+func resolveAttributePath(ctx context.Context, attr path.Path, value types.String, diags *diag.Diagnostics) string {
+	resolved, err := resolvePath(value.ValueString())
+	if err != nil {
+		diags.AddAttributeError(attr, "Invalid path", err.Error())
+		return ""
+	}
+	tflog.Info(ctx, "resolved path", map[string]interface{}{"attribute": attr.String(), "path": resolved})
+	return resolved
+}
+
+// checkLocalFile reports missing file at plan time. Only new or changed values are checked,
+// so removing file used by existing resource doesn't break plans. Files of remote host aren't checked
+func checkLocalFile(ctx context.Context, attr path.Path, planned, prior types.String, diags *diag.Diagnostics) {
+	if planned.IsNull() || planned.IsUnknown() || planned.Equal(prior) || virtualboxapi.IsRemote() {
+		return
+	}
+	resolved := resolveAttributePath(ctx, attr, planned, diags)
+	if resolved == "" || strings.Contains(resolved, "://") {
+		return
+	}
+	if _, err := os.Stat(resolved); err != nil {
+		diags.AddAttributeError(
+			attr,
+			"File not found",
+			fmt.Sprintf("%q resolved to %s: %s", planned.ValueString(), resolved, err),
+		)
+	}
+}
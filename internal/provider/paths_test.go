@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestResolvePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"~":                             home,
+		"~/.ssh/id_ed25519.pub":         filepath.Join(home, ".ssh/id_ed25519.pub"),
+		"images/ubuntu.ova":             filepath.Join(wd, "images/ubuntu.ova"),
+		"/var/lib/images/../ubuntu.ova": "/var/lib/ubuntu.ova",
+		// only leading ~ of current user is expanded
+		"~other/ubuntu.ova":                 filepath.Join(wd, "~other/ubuntu.ova"),
+		"https://example.com/~/ubuntu.ova":  "https://example.com/~/ubuntu.ova",
+		"file:///var/lib/images/ubuntu.ova": "file:///var/lib/images/ubuntu.ova",
+	}
+	for p, want := range tests {
+		got, err := resolvePath(p)
+		if err != nil {
+			t.Errorf("resolvePath(%q): %v", p, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("resolvePath(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestCheckLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "ubuntu.ova")
+	if err := os.WriteFile(existing, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.ova")
+	tests := []struct {
+		name    string
+		planned types.String
+		prior   types.String
+		wantErr bool
+	}{
+		{name: "existing file", planned: types.StringValue(existing)},
+		{name: "missing file", planned: types.StringValue(missing), wantErr: true},
+		{name: "url", planned: types.StringValue("https://example.com/ubuntu.ova")},
+		{name: "not set", planned: types.StringNull()},
+		{name: "unknown", planned: types.StringUnknown()},
+		// file of existing resource may be removed after vm was created
+		{name: "unchanged missing file", planned: types.StringValue(missing), prior: types.StringValue(missing)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			checkLocalFile(context.Background(), path.Root("image"), tt.planned, tt.prior, &diags)
+			if diags.HasError() != tt.wantErr {
+				t.Errorf("errors = %v, want error %v", diags, tt.wantErr)
+			}
+		})
+	}
+}
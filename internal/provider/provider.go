@@ -2,17 +2,31 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
 )
 
 // Ensure VirtualboxProvider satisfies various provider interfaces.
 var _ provider.Provider = &VirtualboxProvider{}
 
+// experimentalEnabled is set by enable_experimental provider attribute, it guards
+// features which may change or be removed in future versions
+var experimentalEnabled bool
+
+// providerVersion is version of configured provider, reported by virtualbox_environment
+var providerVersion string
+
 // VirtualboxProvider defines the provider implementation.
 type VirtualboxProvider struct {
 	// version is set to the provider version on release, "dev" when the
@@ -23,6 +37,28 @@ type VirtualboxProvider struct {
 
 // VirtualboxProviderModel describes the provider data model.
 type VirtualboxProviderModel struct {
+	RunAsUser  types.String `tfsdk:"run_as_user"`
+	DebugStats types.Bool   `tfsdk:"debug_stats"`
+	TmpDir     types.String `tfsdk:"tmp_dir"`
+
+	VBoxManageTimeoutSeconds types.Int64 `tfsdk:"vboxmanage_timeout_seconds"`
+	SSHPortRangeStart        types.Int64 `tfsdk:"ssh_port_range_start"`
+	SSHPortRangeEnd          types.Int64 `tfsdk:"ssh_port_range_end"`
+
+	VBoxUserHome types.String `tfsdk:"vbox_user_home"`
+
+	RemoteHost types.String `tfsdk:"remote_host"`
+	RemoteUser types.String `tfsdk:"remote_user"`
+	RemoteKey  types.String `tfsdk:"remote_key"`
+	RemotePort types.Int64  `tfsdk:"remote_port"`
+
+	VirtSysprepOperations types.List `tfsdk:"virt_sysprep_operations"`
+	VirtSysprepExtraArgs  types.List `tfsdk:"virt_sysprep_extra_args"`
+
+	StartRetryableErrors types.List `tfsdk:"start_retryable_errors"`
+
+	EnableExperimental types.Bool   `tfsdk:"enable_experimental"`
+	VersionMinimum     types.String `tfsdk:"version_minimum"`
 }
 
 func (p *VirtualboxProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -31,7 +67,89 @@ func (p *VirtualboxProvider) Metadata(ctx context.Context, req provider.Metadata
 }
 
 func (p *VirtualboxProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
-	resp.Schema = schema.Schema{}
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"run_as_user": schema.StringAttribute{
+				MarkdownDescription: "Run VBoxManage as given user, VirtualBox vms are registered per user. " +
+					"Provider must run as root or as the same user. Not supported on Windows.",
+				Optional: true,
+			},
+			"debug_stats": schema.BoolAttribute{
+				MarkdownDescription: "Collect VBoxManage call counters and durations, summary is logged " +
+					"at the end of each resource operation and on provider shutdown. `false` by default.",
+				Optional: true,
+			},
+			"tmp_dir": schema.StringAttribute{
+				MarkdownDescription: "Directory for temporary disk image copies made while injecting ssh key into running vm, " +
+					"disks of stopped vms are modified in place. System temporary directory by default, or directory of disk image when it is on NFS.",
+				Optional: true,
+			},
+			"ssh_port_range_start": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("First host port used for vm port forwarding. `%d` by default.", virtualboxapi.DefaultPortRangeStart),
+				Optional:            true,
+			},
+			"ssh_port_range_end": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Last host port used for vm port forwarding, range must contain at least %d ports. `%d` by default.", virtualboxapi.MinPortRangeSize, virtualboxapi.DefaultPortRangeEnd),
+				Optional:            true,
+			},
+			"vbox_user_home": schema.StringAttribute{
+				MarkdownDescription: "Directory with VirtualBox configuration and vm registry, passed to VirtualBox as `VBOX_USER_HOME`. " +
+					"Provider aliases with different directories manage separate sets of vms.",
+				Optional: true,
+			},
+			"remote_host": schema.StringAttribute{
+				MarkdownDescription: "Run VBoxManage on given host over ssh instead of locally, with system `ssh` client. " +
+					"Host key must be known already. Paths of images and disks refer to files on remote host then. " +
+					"Operations which need local access to VirtualBox host, like ssh key injection and readiness probes, are not supported.",
+				Optional: true,
+			},
+			"remote_user": schema.StringAttribute{
+				MarkdownDescription: "User of `remote_host` ssh connection, ssh client configuration is used by default",
+				Optional:            true,
+			},
+			"remote_key": schema.StringAttribute{
+				MarkdownDescription: "Private key file of `remote_host` ssh connection, ssh agent and client configuration are used by default",
+				Optional:            true,
+			},
+			"remote_port": schema.Int64Attribute{
+				MarkdownDescription: "Port of `remote_host` ssh connection, `22` by default",
+				Optional:            true,
+			},
+			"virt_sysprep_operations": schema.ListAttribute{
+				MarkdownDescription: "Operations run by `virt-sysprep` when ssh key is injected, passed as `--operations`. " +
+					"`[\"ssh-inject\"]` by default, see `virt-sysprep --list-operations` for available ones.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"virt_sysprep_extra_args": schema.ListAttribute{
+				MarkdownDescription: "Additional arguments passed to `virt-sysprep` as is",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"start_retryable_errors": schema.ListAttribute{
+				MarkdownDescription: "Additional `VBoxManage startvm` error fragments treated as transient, failed start " +
+					"is retried up to 3 times when its stderr contains any of them. VM process dying during startup and " +
+					"`VERR_MAIN_CONFIG_CONSTRUCTOR_COM_ERROR` are always retried.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"version_minimum": schema.StringAttribute{
+				MarkdownDescription: "Oldest VirtualBox version provider may work with, e.g. `7.0.0`. " +
+					"Provider configuration fails on older VirtualBox, which pins test environments to known versions.",
+				Optional: true,
+			},
+			"vboxmanage_timeout_seconds": schema.Int64Attribute{
+				MarkdownDescription: "How long single VBoxManage call may run before it is killed, in seconds. " +
+					"Applies to `import` of vm image as well, increase it for large images. `120` by default.",
+				Optional: true,
+			},
+			"enable_experimental": schema.BoolAttribute{
+				MarkdownDescription: "Allow experimental features, like `teleport` of `virtualbox_vm`. " +
+					"They may change or be removed in future versions. `false` by default.",
+				Optional: true,
+			},
+		},
+	}
 }
 
 func (p *VirtualboxProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
@@ -43,8 +161,137 @@ func (p *VirtualboxProvider) Configure(ctx context.Context, req provider.Configu
 		return
 	}
 
-	// Configuration values are now available.
-	// if data.Endpoint.IsNull() { /* ... */ }
+	if !data.RunAsUser.IsNull() {
+		err := virtualboxapi.SetRunAsUser(data.RunAsUser.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("run_as_user"), "Unable to run VBoxManage as another user", err.Error())
+			return
+		}
+	}
+
+	if !data.TmpDir.IsNull() {
+		err := virtualboxapi.SetTmpDir(data.TmpDir.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("tmp_dir"), "Invalid temporary directory", err.Error())
+			return
+		}
+	}
+
+	if !data.VBoxManageTimeoutSeconds.IsNull() {
+		if data.VBoxManageTimeoutSeconds.ValueInt64() <= 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("vboxmanage_timeout_seconds"), "Invalid VBoxManage timeout", "Timeout must be positive")
+			return
+		}
+		virtualboxapi.SetCommandTimeout(time.Duration(data.VBoxManageTimeoutSeconds.ValueInt64()) * time.Second)
+	}
+
+	if !data.SSHPortRangeStart.IsNull() || !data.SSHPortRangeEnd.IsNull() {
+		start, end := int64(virtualboxapi.DefaultPortRangeStart), int64(virtualboxapi.DefaultPortRangeEnd)
+		if !data.SSHPortRangeStart.IsNull() {
+			start = data.SSHPortRangeStart.ValueInt64()
+		}
+		if !data.SSHPortRangeEnd.IsNull() {
+			end = data.SSHPortRangeEnd.ValueInt64()
+		}
+		err := virtualboxapi.SetPortRange(int(start), int(end))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("ssh_port_range_end"), "Invalid ssh port range", err.Error())
+			return
+		}
+	}
+
+	if !data.VBoxUserHome.IsNull() {
+		err := virtualboxapi.SetVBoxUserHome(data.VBoxUserHome.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("vbox_user_home"), "Invalid VirtualBox user home", err.Error())
+			return
+		}
+	}
+
+	if !data.RemoteHost.IsNull() {
+		if !data.RunAsUser.IsNull() || !data.VBoxUserHome.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("remote_host"),
+				"Conflicting provider configuration",
+				"run_as_user and vbox_user_home are not supported with remote_host, configure ssh user and its VirtualBox instead",
+			)
+			return
+		}
+		err := virtualboxapi.SetRemoteHost(virtualboxapi.RemoteHost{
+			Host:    data.RemoteHost.ValueString(),
+			User:    data.RemoteUser.ValueString(),
+			KeyPath: data.RemoteKey.ValueString(),
+			Port:    int(data.RemotePort.ValueInt64()),
+		})
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("remote_host"), "Invalid remote host", err.Error())
+			return
+		}
+	}
+
+	if !data.VirtSysprepOperations.IsNull() || !data.VirtSysprepExtraArgs.IsNull() {
+		operations := virtualboxapi.DefaultVirtSysprepOperations
+		if !data.VirtSysprepOperations.IsNull() {
+			resp.Diagnostics.Append(data.VirtSysprepOperations.ElementsAs(ctx, &operations, false)...)
+		}
+		extraArgs := []string{}
+		if !data.VirtSysprepExtraArgs.IsNull() {
+			resp.Diagnostics.Append(data.VirtSysprepExtraArgs.ElementsAs(ctx, &extraArgs, false)...)
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		err := virtualboxapi.SetVirtSysprepOptions(operations, extraArgs)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("virt_sysprep_operations"), "Invalid virt-sysprep options", err.Error())
+			return
+		}
+	}
+
+	if !data.StartRetryableErrors.IsNull() {
+		patterns := []string{}
+		resp.Diagnostics.Append(data.StartRetryableErrors.ElementsAs(ctx, &patterns, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		virtualboxapi.AddRetryableStartErrors(patterns...)
+	}
+
+	// every operation runs VBoxManage, missing one would fail them with opaque exec errors
+	if err := virtualboxapi.CheckVBoxManage(); err != nil {
+		resp.Diagnostics.AddError(
+			"VBoxManage not found",
+			fmt.Sprintf("VirtualBox must be installed and VBoxManage must be on $PATH of the user running Terraform: %s", err),
+		)
+		return
+	}
+
+	// version is detected once and cached, it guards version-specific modifyvm flags
+	version, err := virtualboxapi.GetVersion(ctx)
+	if err != nil {
+		resp.Diagnostics.AddWarning("Unable to detect VirtualBox version", err.Error())
+	} else {
+		tflog.Info(ctx, "detected VirtualBox version "+version.String())
+	}
+	if !data.VersionMinimum.IsNull() {
+		minimum, parseErr := virtualboxapi.ParseVersion(data.VersionMinimum.ValueString())
+		if parseErr != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("version_minimum"), "Invalid minimum VirtualBox version", parseErr.Error())
+			return
+		}
+		if err == nil && version.Less(minimum) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("version_minimum"),
+				"Unsupported VirtualBox version",
+				fmt.Sprintf("Provider configuration requires VirtualBox >= %s, but found %s", minimum, version),
+			)
+			return
+		}
+	}
+
+	virtualboxapi.EnableStats(data.DebugStats.ValueBool())
+	experimentalEnabled = data.EnableExperimental.ValueBool()
+	providerVersion = p.version
 
 	// Example client configuration for data sources and resources
 	client := http.DefaultClient
@@ -55,11 +302,27 @@ func (p *VirtualboxProvider) Configure(ctx context.Context, req provider.Configu
 func (p *VirtualboxProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewVirtualboxVMResource,
+		NewVirtualboxPortForwardingRuleResource,
+		NewVirtualboxHostOnlyIfResource,
+		NewVirtualboxDHCPServerResource,
+		NewVirtualboxDiskResource,
+		NewVirtualboxKeyboardInputResource,
+		NewVirtualboxBandwidthGroupResource,
+		NewVirtualboxVMGroupResource,
 	}
 }
 
 func (p *VirtualboxProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewVirtualboxHostOnlyNetworkDataSource,
+		NewVirtualboxProviderInfoDataSource,
+		NewVirtualboxNetworksDataSource,
+		NewVirtualboxVMsDataSource,
+		NewVirtualboxSnapshotsDataSource,
+		NewVirtualboxEnvironmentDataSource,
+		NewVirtualboxMediumDataSource,
+		NewVirtualboxAccessibleVMsDataSource,
+	}
 }
 
 func New(version string) func() provider.Provider {
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// privateSSHKeyHashKey is private state key holding sha256 of injected ssh key content
+const privateSSHKeyHashKey = "ssh_key_sha256"
+
+// privateStateGetter and privateStateSetter are implemented by private state of framework
+// requests and responses, its type is internal to the framework
+type privateStateGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// sshKeyHash returns sha256 of ssh key file content
+func sshKeyHash(keyPath string) (string, error) {
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// storeSSHKeyHash remembers content of injected ssh key, so that plan could tell whether key file changed or disappeared
+func storeSSHKeyHash(ctx context.Context, private privateStateSetter, keyPath string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	hash, err := sshKeyHash(keyPath)
+	if err != nil {
+		diags.AddAttributeWarning(path.Root("ssh_key"), "Unable to hash ssh key", err.Error())
+		return diags
+	}
+	// private state values have to be JSON
+	value, err := json.Marshal(hash)
+	if err != nil {
+		diags.AddAttributeWarning(path.Root("ssh_key"), "Unable to hash ssh key", err.Error())
+		return diags
+	}
+	return private.SetKey(ctx, privateSSHKeyHashKey, value)
+}
+
+// storedSSHKeyHash returns hash saved by storeSSHKeyHash, empty for vms created by older versions
+func storedSSHKeyHash(ctx context.Context, private privateStateGetter) (string, diag.Diagnostics) {
+	value, diags := private.GetKey(ctx, privateSSHKeyHashKey)
+	if diags.HasError() || len(value) == 0 {
+		return "", diags
+	}
+	var hash string
+	if err := json.Unmarshal(value, &hash); err != nil {
+		diags.AddWarning("Invalid stored ssh key hash", err.Error())
+		return "", diags
+	}
+	return hash, diags
+}
+
+// checkSSHKeyFile reports missing or changed ssh key file of existing vm at plan time. Key is injected when vm
+// is created or ssh_key is changed, so missing file of unchanged ssh_key breaks only replacement. Otherwise ssh_key_rehash decides whether missing file is an error
+// or stored hash is trusted
+func checkSSHKeyFile(ctx context.Context, plan, state *VirtualboxVMResourceModel, private privateStateGetter, replacing bool, diags *diag.Diagnostics) {
+	if plan.SSHKey.IsNull() || plan.SSHKey.IsUnknown() || !plan.SSHKey.Equal(state.SSHKey) {
+		// new value is checked by checkLocalFile
+		return
+	}
+	keyPath := resolveAttributePath(ctx, path.Root("ssh_key"), plan.SSHKey, diags)
+	if keyPath == "" {
+		return
+	}
+	stored, storedDiags := storedSSHKeyHash(ctx, private)
+	diags.Append(storedDiags...)
+	hash, err := sshKeyHash(keyPath)
+	switch {
+	case err != nil && replacing:
+		diags.AddAttributeError(
+			path.Root("ssh_key"),
+			"Ssh key file not found",
+			fmt.Sprintf("Vm is replaced and ssh key %s has to be injected into new vm, but it can't be read: %s. Restore the file or point ssh_key to another key.", keyPath, err),
+		)
+	case err != nil && plan.SSHKeyRehash.ValueBool():
+		diags.AddAttributeError(
+			path.Root("ssh_key"),
+			"Ssh key file not found",
+			fmt.Sprintf("Ssh key %s injected into vm can't be read: %s. Restore the file, point ssh_key to another key "+
+				"or set ssh_key_rehash = false to keep using the injected key.", keyPath, err),
+		)
+	case err != nil:
+		diags.AddAttributeWarning(
+			path.Root("ssh_key"),
+			"Ssh key file not found",
+			fmt.Sprintf("Ssh key %s can't be read: %s. Key injected into vm is kept, as ssh_key_rehash = false.", keyPath, err),
+		)
+	case plan.SSHKeyRehash.ValueBool() && stored != "" && hash != stored && !replacing:
+		diags.AddAttributeWarning(
+			path.Root("ssh_key"),
+			"Ssh key file changed",
+			fmt.Sprintf("Content of ssh key %s differs from the key injected into vm. Key is injected when vm is created or ssh_key is changed, "+
+				"replace vm or point ssh_key to another file to inject the new key.", keyPath),
+		)
+	}
+}
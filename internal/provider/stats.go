@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// logStats dumps VBoxManage call counters accumulated by the provider process,
+// it is deferred at the end of each resource and data source operation
+func logStats(ctx context.Context, operation string) {
+	if !virtualboxapi.StatsEnabled() {
+		return
+	}
+	tflog.Info(ctx, "VBoxManage call stats after "+operation+"\n"+virtualboxapi.StatsSummary())
+}
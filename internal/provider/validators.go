@@ -0,0 +1,817 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure validators fully satisfy framework interfaces.
+var _ validator.String = stringNoneOfCharsValidator{}
+var _ validator.List = listNoneOfFlagsValidator{}
+var _ validator.String = stringOneOfValidator{}
+var _ validator.String = stringIsDurationValidator{}
+var _ validator.String = stringIsWritableDirValidator{}
+var _ validator.String = stringIsGlobValidator{}
+var _ validator.String = stringIsBase64Validator{}
+var _ validator.String = stringIsGroupPathValidator{}
+var _ validator.Int64 = int64BetweenValidator{}
+var _ validator.Int64 = int64MultipleOfValidator{}
+var _ resource.ConfigValidator = sshUserValidator{}
+var _ resource.ConfigValidator = exactlyOneOfValidator{}
+var _ resource.ConfigValidator = atMostOneOfValidator{}
+var _ resource.ConfigValidator = installFromISOValidator{}
+var _ resource.ConfigValidator = cpuChipsetValidator{}
+var _ resource.ConfigValidator = cpuIOAPICValidator{}
+var _ resource.ConfigValidator = tpmFirmwareValidator{}
+var _ resource.ConfigValidator = applianceValidator{}
+var _ resource.ConfigValidator = ipConfigValidator{}
+var _ resource.ConfigValidator = networkAdapterValidator{}
+
+// stringNoneOfCharsValidator rejects strings containing any of given characters.
+type stringNoneOfCharsValidator struct {
+	chars string
+}
+
+func stringNoneOfChars(chars string) validator.String {
+	return stringNoneOfCharsValidator{chars: chars}
+}
+
+func (v stringNoneOfCharsValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must not contain any of the following characters: %q", v.chars)
+}
+
+func (v stringNoneOfCharsValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringNoneOfCharsValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+	if strings.ContainsAny(value, v.chars) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %q", req.Path, v.Description(ctx), value),
+		)
+	}
+}
+
+// listNoneOfFlagsValidator rejects command line arguments managed by provider itself.
+// Both "--flag value" and "--flag=value" forms are detected.
+type listNoneOfFlagsValidator struct {
+	flags []string
+}
+
+func listNoneOfFlags(flags ...string) validator.List {
+	return listNoneOfFlagsValidator{flags: flags}
+}
+
+func (v listNoneOfFlagsValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("list must not contain any of the following flags: %s", strings.Join(v.flags, ", "))
+}
+
+func (v listNoneOfFlagsValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v listNoneOfFlagsValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	for i, element := range req.ConfigValue.Elements() {
+		value, ok := element.(types.String)
+		if !ok || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		arg := strings.SplitN(value.ValueString(), "=", 2)[0]
+		for _, flag := range v.flags {
+			if arg == flag {
+				resp.Diagnostics.AddAttributeError(
+					req.Path.AtListIndex(i),
+					"Invalid Attribute Value",
+					fmt.Sprintf("Flag %s is managed by provider, use corresponding resource attribute instead", flag),
+				)
+			}
+		}
+	}
+}
+
+// listSizeAtMostValidator checks that list has at most max elements.
+type listSizeAtMostValidator struct {
+	max int
+}
+
+func listSizeAtMost(max int) validator.List {
+	return listSizeAtMostValidator{max: max}
+}
+
+func (v listSizeAtMostValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("list must contain at most %d elements", v.max)
+}
+
+func (v listSizeAtMostValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v listSizeAtMostValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if count := len(req.ConfigValue.Elements()); count > v.max {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Expected at most %d elements, got: %d", v.max, count),
+		)
+	}
+}
+
+// stringOneOfValidator checks that string is one of allowed values.
+type stringOneOfValidator struct {
+	values []string
+}
+
+func stringOneOf(values ...string) validator.String {
+	return stringOneOfValidator{values: values}
+}
+
+func (v stringOneOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be one of: %s", strings.Join(v.values, ", "))
+}
+
+func (v stringOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringOneOfValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+	for _, allowed := range v.values {
+		if value == allowed {
+			return
+		}
+	}
+	resp.Diagnostics.AddAttributeError(
+		req.Path,
+		"Invalid Attribute Value",
+		fmt.Sprintf("Attribute %s %s, got: %q", req.Path, v.Description(ctx), value),
+	)
+}
+
+// stringIsDurationValidator checks that string could be parsed by time.ParseDuration.
+type stringIsDurationValidator struct{}
+
+func stringIsDuration() validator.String {
+	return stringIsDurationValidator{}
+}
+
+func (v stringIsDurationValidator) Description(ctx context.Context) string {
+	return "value must be a positive duration, e.g. 30s or 5m"
+}
+
+func (v stringIsDurationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringIsDurationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %q", req.Path, v.Description(ctx), value),
+		)
+	}
+}
+
+// stringIsGlobValidator checks that string is valid filepath.Match pattern.
+type stringIsGlobValidator struct{}
+
+func stringIsGlob() validator.String {
+	return stringIsGlobValidator{}
+}
+
+func (v stringIsGlobValidator) Description(ctx context.Context) string {
+	return "value must be a valid glob pattern, e.g. /data/*.vdi"
+}
+
+func (v stringIsGlobValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringIsGlobValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+	if _, err := filepath.Match(value, ""); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %q", req.Path, v.Description(ctx), value),
+		)
+	}
+}
+
+// stringIsBase64Validator checks that string is standard base64 encoding.
+type stringIsBase64Validator struct{}
+
+func stringIsBase64() validator.String {
+	return stringIsBase64Validator{}
+}
+
+func (v stringIsBase64Validator) Description(ctx context.Context) string {
+	return "value must be base64 encoded"
+}
+
+func (v stringIsBase64Validator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringIsBase64Validator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(req.ConfigValue.ValueString()); err != nil {
+		// value may be sensitive, so it's not included
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s: %s", req.Path, v.Description(ctx), err),
+		)
+	}
+}
+
+// stringIsGroupPathValidator checks that string is VirtualBox vm group path.
+type stringIsGroupPathValidator struct{}
+
+func stringIsGroupPath() validator.String {
+	return stringIsGroupPathValidator{}
+}
+
+func (v stringIsGroupPathValidator) Description(ctx context.Context) string {
+	return "value must be vm group path without commas, e.g. /production/web"
+}
+
+func (v stringIsGroupPathValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringIsGroupPathValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	err := virtualboxapi.ValidateGroupPath(req.ConfigValue.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s: %s", req.Path, v.Description(ctx), err),
+		)
+	}
+}
+
+// stringIsWritableDirValidator checks that string is path of existing writable directory.
+type stringIsWritableDirValidator struct{}
+
+func stringIsWritableDir() validator.String {
+	return stringIsWritableDirValidator{}
+}
+
+func (v stringIsWritableDirValidator) Description(ctx context.Context) string {
+	return "value must be path of existing writable directory"
+}
+
+func (v stringIsWritableDirValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringIsWritableDirValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+	info, err := os.Stat(value)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("not a directory")
+	}
+	if err == nil {
+		// the only reliable way to check permissions, including ACLs and read-only mounts
+		var probe *os.File
+		probe, err = os.CreateTemp(value, ".terraform-provider-virtualbox-*")
+		if err == nil {
+			probe.Close()
+			os.Remove(probe.Name())
+		}
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %q: %s", req.Path, v.Description(ctx), value, err),
+		)
+	}
+}
+
+// int64BetweenValidator checks that number is within inclusive range.
+type int64BetweenValidator struct {
+	min, max int64
+}
+
+func int64Between(min, max int64) validator.Int64 {
+	return int64BetweenValidator{min: min, max: max}
+}
+
+func (v int64BetweenValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be between %d and %d", v.min, v.max)
+}
+
+func (v int64BetweenValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v int64BetweenValidator) ValidateInt64(ctx context.Context, req validator.Int64Request, resp *validator.Int64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueInt64()
+	if value < v.min || value > v.max {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %d", req.Path, v.Description(ctx), value),
+		)
+	}
+}
+
+// int64MultipleOfValidator checks that number is multiple of given unit.
+type int64MultipleOfValidator struct {
+	unit int64
+}
+
+func int64MultipleOf(unit int64) validator.Int64 {
+	return int64MultipleOfValidator{unit: unit}
+}
+
+func (v int64MultipleOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be multiple of %d", v.unit)
+}
+
+func (v int64MultipleOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v int64MultipleOfValidator) ValidateInt64(ctx context.Context, req validator.Int64Request, resp *validator.Int64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueInt64()
+	if value%v.unit != 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %d", req.Path, v.Description(ctx), value),
+		)
+	}
+}
+
+// posixUserNameRegexp matches portable user names accepted by useradd
+var posixUserNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+// sshUserValidator checks that ssh_user is valid POSIX user name when ssh key is injected.
+type sshUserValidator struct{}
+
+func (v sshUserValidator) Description(ctx context.Context) string {
+	return "ssh_user must be valid POSIX user name when ssh_key is set"
+}
+
+func (v sshUserValidator) MarkdownDescription(ctx context.Context) string {
+	return "`ssh_user` must be valid POSIX user name when `ssh_key` is set"
+}
+
+func (v sshUserValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var sshKey, sshUser types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("ssh_key"), &sshKey)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("ssh_user"), &sshUser)...)
+
+	if resp.Diagnostics.HasError() || sshKey.IsNull() || sshUser.IsNull() || sshUser.IsUnknown() {
+		return
+	}
+
+	if !posixUserNameRegexp.MatchString(sshUser.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("ssh_user"),
+			"Invalid Attribute Value",
+			fmt.Sprintf("%s, got: %q", v.Description(ctx), sshUser.ValueString()),
+		)
+	}
+}
+
+// exactlyOneOfValidator checks that exactly one of mutually exclusive attributes is set.
+type exactlyOneOfValidator struct {
+	attributes []string
+}
+
+func exactlyOneOf(attributes ...string) resource.ConfigValidator {
+	return exactlyOneOfValidator{attributes: attributes}
+}
+
+func (v exactlyOneOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("exactly one of %s must be set", strings.Join(v.attributes, ", "))
+}
+
+func (v exactlyOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("exactly one of `%s` must be set", strings.Join(v.attributes, "`, `"))
+}
+
+func (v exactlyOneOfValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	set := []string{}
+	for _, name := range v.attributes {
+		var value types.String
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(name), &value)...)
+
+		if resp.Diagnostics.HasError() || value.IsUnknown() {
+			// unknown value may turn out to be null
+			return
+		}
+		if !value.IsNull() {
+			set = append(set, name)
+		}
+	}
+
+	if len(set) != 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(v.attributes[0]),
+			"Invalid Attribute Combination",
+			fmt.Sprintf("%s, got: %d", v.Description(ctx), len(set)),
+		)
+	}
+}
+
+// atMostOneOfValidator checks that no more than one of mutually exclusive attributes is set.
+type atMostOneOfValidator struct {
+	attributes []string
+}
+
+func atMostOneOf(attributes ...string) resource.ConfigValidator {
+	return atMostOneOfValidator{attributes: attributes}
+}
+
+func (v atMostOneOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("at most one of %s can be set", strings.Join(v.attributes, ", "))
+}
+
+func (v atMostOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("at most one of `%s` can be set", strings.Join(v.attributes, "`, `"))
+}
+
+func (v atMostOneOfValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	set := []string{}
+	for _, name := range v.attributes {
+		var value types.String
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(name), &value)...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// unknown value may turn out to be null
+		if !value.IsNull() && !value.IsUnknown() {
+			set = append(set, name)
+		}
+	}
+
+	if len(set) > 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(set[1]),
+			"Invalid Attribute Combination",
+			fmt.Sprintf("%s, got: %s", v.Description(ctx), strings.Join(set, ", ")),
+		)
+	}
+}
+
+// installFromISOValidator checks that vm installed from ISO gets empty base disk and no ssh key,
+// key can't be injected into disk without guest OS.
+type installFromISOValidator struct{}
+
+func (v installFromISOValidator) Description(ctx context.Context) string {
+	return "install_from_iso requires base_disk_uuid and conflicts with image and ssh_key"
+}
+
+func (v installFromISOValidator) MarkdownDescription(ctx context.Context) string {
+	return "`install_from_iso` requires `base_disk_uuid` and conflicts with `image` and `ssh_key`"
+}
+
+func (v installFromISOValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var installFromISO types.Object
+	var baseDiskUUID, sshKey types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("install_from_iso"), &installFromISO)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("base_disk_uuid"), &baseDiskUUID)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("ssh_key"), &sshKey)...)
+
+	if resp.Diagnostics.HasError() || installFromISO.IsNull() || installFromISO.IsUnknown() {
+		return
+	}
+
+	if baseDiskUUID.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("install_from_iso"),
+			"Invalid Attribute Combination",
+			"Guest OS is installed on copy of base disk, set base_disk_uuid to UUID of empty disk",
+		)
+	}
+	if !sshKey.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("ssh_key"),
+			"Invalid Attribute Combination",
+			"ssh_key can't be injected into disk without guest OS, remove it when install_from_iso is set",
+		)
+	}
+}
+
+// cpuChipsetValidator checks that vm with more than 32 cpus uses ICH9 chipset,
+// VirtualBox rejects such vm with PIIX3 only when it is started.
+type cpuChipsetValidator struct{}
+
+func (v cpuChipsetValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("cpu above %d requires chipset ich9", maxPIIX3CPUs)
+}
+
+func (v cpuChipsetValidator) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("`cpu` above %d requires `chipset = \"ich9\"`", maxPIIX3CPUs)
+}
+
+func (v cpuChipsetValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cpu types.Int64
+	var chipset types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("cpu"), &cpu)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("chipset"), &chipset)...)
+
+	if resp.Diagnostics.HasError() || cpu.IsNull() || cpu.IsUnknown() || chipset.IsUnknown() {
+		return
+	}
+
+	// chipset declared by appliance isn't known before import, it has to be set explicitly
+	if cpu.ValueInt64() > maxPIIX3CPUs && chipset.ValueString() != "ich9" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("cpu"),
+			"Invalid Attribute Combination",
+			fmt.Sprintf("VirtualBox supports up to %d cpus with PIIX3 chipset, got: %d. Set chipset = \"ich9\" for more cpus, "+
+				"guest also needs I/O APIC enabled, which is the default of most appliances.", maxPIIX3CPUs, cpu.ValueInt64()),
+		)
+	}
+}
+
+// cpuIOAPICValidator warns that vm with several cpus has I/O APIC disabled,
+// VirtualBox starts such vm, but guest sees only one cpu.
+type cpuIOAPICValidator struct{}
+
+func (v cpuIOAPICValidator) Description(ctx context.Context) string {
+	return "cpu above 1 needs ioapic enabled"
+}
+
+func (v cpuIOAPICValidator) MarkdownDescription(ctx context.Context) string {
+	return "`cpu` above 1 needs `ioapic = true`"
+}
+
+func (v cpuIOAPICValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cpu types.Int64
+	var ioapic types.Bool
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("cpu"), &cpu)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("ioapic"), &ioapic)...)
+
+	if resp.Diagnostics.HasError() || cpu.IsNull() || cpu.IsUnknown() || ioapic.IsNull() || ioapic.IsUnknown() {
+		return
+	}
+
+	if cpu.ValueInt64() > 1 && !ioapic.ValueBool() {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("ioapic"),
+			"Cpus unused without I/O APIC",
+			fmt.Sprintf("Vm has %d cpus, but guest uses only one of them with ioapic = false.", cpu.ValueInt64()),
+		)
+	}
+}
+
+// tpmFirmwareValidator checks that vm with emulated TPM uses EFI firmware,
+// guests needing TPM, e.g. Windows 11, don't boot with BIOS.
+type tpmFirmwareValidator struct{}
+
+func (v tpmFirmwareValidator) Description(ctx context.Context) string {
+	return "tpm other than none requires firmware efi"
+}
+
+func (v tpmFirmwareValidator) MarkdownDescription(ctx context.Context) string {
+	return "`tpm` other than `none` requires `firmware = \"efi\"`"
+}
+
+func (v tpmFirmwareValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var tpm types.String
+	var firmware types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("tpm"), &tpm)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("firmware"), &firmware)...)
+
+	if resp.Diagnostics.HasError() || tpm.IsNull() || tpm.IsUnknown() || tpm.ValueString() == "none" || firmware.IsUnknown() {
+		return
+	}
+
+	// firmware of appliance isn't known until import, so it has to be set explicitly
+	if firmware.ValueString() != "efi" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("tpm"),
+			"Invalid Attribute Combination",
+			fmt.Sprintf("tpm = %q requires firmware = \"efi\", got firmware: %q", tpm.ValueString(), firmware.ValueString()),
+		)
+	}
+}
+
+// applianceValidator runs import dry run of local .ova or .ovf image, so broken appliance or
+// missing disk space is reported before anything is changed. Images on remote host or behind URL,
+// missing files and hosts without VBoxManage, e.g. CI running terraform validate, are skipped
+type applianceValidator struct{}
+
+func (v applianceValidator) Description(ctx context.Context) string {
+	return "image must be importable appliance"
+}
+
+func (v applianceValidator) MarkdownDescription(ctx context.Context) string {
+	return "`image` must be importable appliance"
+}
+
+func (v applianceValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var image types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("image"), &image)...)
+
+	if resp.Diagnostics.HasError() || image.IsNull() || image.IsUnknown() || virtualboxapi.IsRemote() {
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(image.ValueString()))
+	if strings.Contains(image.ValueString(), "://") || (ext != ".ova" && ext != ".ovf") {
+		return
+	}
+	// missing file is reported at plan time by checkLocalFile
+	imagePath, err := resolvePath(image.ValueString())
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(imagePath); err != nil || virtualboxapi.CheckVBoxManage() != nil {
+		return
+	}
+
+	result, err := virtualboxapi.ValidateOVA(ctx, imagePath)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("image"), "Invalid appliance", err.Error())
+		return
+	}
+	for _, warning := range result.Warnings {
+		resp.Diagnostics.AddAttributeWarning(path.Root("image"), "Appliance import may fail", fmt.Sprintf("%s: %s", imagePath, warning))
+	}
+}
+
+// ipConfigValidator checks that network_adapter ip_config is written together with ssh key,
+// that its adapter is attached and that addresses form valid configuration
+type ipConfigValidator struct{}
+
+func (v ipConfigValidator) Description(ctx context.Context) string {
+	return "network_adapter ip_config requires ssh_key and valid IPv4 addresses"
+}
+
+func (v ipConfigValidator) MarkdownDescription(ctx context.Context) string {
+	return "`network_adapter` `ip_config` requires `ssh_key` and valid IPv4 addresses"
+}
+
+func (v ipConfigValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var adapters types.List
+	var sshKey types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("network_adapter"), &adapters)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("ssh_key"), &sshKey)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ipConfigs, diags := networkAdapterIPConfigs(ctx, adapters)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for i, adapter := range networkAdapters(adapters) {
+		ipConfig, ok := ipConfigs[i]
+		if !ok {
+			continue
+		}
+		attrPath := path.Root("network_adapter").AtListIndex(i).AtName("ip_config")
+		if sshKey.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				attrPath,
+				"Invalid Attribute Combination",
+				"ip_config is written into guest disk in the same pass as ssh key, set ssh_key as well",
+			)
+			continue
+		}
+		if adapter.Type == "none" {
+			resp.Diagnostics.AddAttributeError(attrPath, "Invalid Attribute Combination", "ip_config is not used by none adapter")
+			continue
+		}
+		if ipConfig.Address.IsUnknown() || ipConfig.Netmask.IsUnknown() || ipConfig.Gateway.IsUnknown() || ipConfig.DNS.IsUnknown() {
+			continue
+		}
+		// adapter 1 is the ssh NAT adapter
+		config, diags := guestIPConfig(ctx, i+2, ipConfig)
+		resp.Diagnostics.Append(diags...)
+		if diags.HasError() {
+			continue
+		}
+		if err := config.Validate(); err != nil {
+			resp.Diagnostics.AddAttributeError(attrPath, "Invalid Attribute Value", err.Error())
+		}
+	}
+}
+
+// networkAdapterValidator checks that network is set exactly for attachment types which use it,
+// otherwise VirtualBox picks network on its own and it shows up as a diff
+type networkAdapterValidator struct{}
+
+func (v networkAdapterValidator) Description(ctx context.Context) string {
+	return "network_adapter network is required for hostonly, bridged, intnet and natnetwork adapters only"
+}
+
+func (v networkAdapterValidator) MarkdownDescription(ctx context.Context) string {
+	return "`network_adapter` `network` is required for `hostonly`, `bridged`, `intnet` and `natnetwork` adapters only"
+}
+
+func (v networkAdapterValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var adapters types.List
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("network_adapter"), &adapters)...)
+
+	if resp.Diagnostics.HasError() || adapters.IsNull() || adapters.IsUnknown() {
+		return
+	}
+
+	for i, element := range adapters.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsUnknown() {
+			continue
+		}
+		adapterType, _ := object.Attributes()["type"].(types.String)
+		network, _ := object.Attributes()["network"].(types.String)
+		if adapterType.IsUnknown() || network.IsUnknown() {
+			continue
+		}
+		needsNetwork := adapterType.ValueString() != "nat" && adapterType.ValueString() != "none"
+		if needsNetwork && network.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("network_adapter").AtListIndex(i).AtName("network"),
+				"Missing Attribute Value",
+				fmt.Sprintf("network is required for %s adapter", adapterType.ValueString()),
+			)
+		}
+		if !needsNetwork && !network.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("network_adapter").AtListIndex(i).AtName("network"),
+				"Invalid Attribute Combination",
+				fmt.Sprintf("network is not used by %s adapter", adapterType.ValueString()),
+			)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// vmConfig builds virtualbox_vm config with given attributes set, the rest is null
+func vmConfig(t *testing.T, values map[string]tftypes.Value) tfsdk.Config {
+	t.Helper()
+	ctx := context.Background()
+	schemaResp := &resource.SchemaResponse{}
+	(&VirtualboxVMResource{}).Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	attrs := map[string]tftypes.Value{}
+	for name, typ := range objectType.AttributeTypes {
+		attrs[name] = tftypes.NewValue(typ, nil)
+	}
+	for name, value := range values {
+		if _, ok := attrs[name]; !ok {
+			t.Fatalf("unknown attribute %s", name)
+		}
+		attrs[name] = value
+	}
+	return tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, attrs)}
+}
+
+func TestCPUIOAPICValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]tftypes.Value
+		warning bool
+	}{
+		{
+			name:   "ioapic not set",
+			values: map[string]tftypes.Value{"cpu": tftypes.NewValue(tftypes.Number, 4)},
+		},
+		{
+			name: "single cpu without ioapic",
+			values: map[string]tftypes.Value{
+				"cpu":    tftypes.NewValue(tftypes.Number, 1),
+				"ioapic": tftypes.NewValue(tftypes.Bool, false),
+			},
+		},
+		{
+			name: "several cpus with ioapic",
+			values: map[string]tftypes.Value{
+				"cpu":    tftypes.NewValue(tftypes.Number, 4),
+				"ioapic": tftypes.NewValue(tftypes.Bool, true),
+			},
+		},
+		{
+			name: "several cpus without ioapic",
+			values: map[string]tftypes.Value{
+				"cpu":    tftypes.NewValue(tftypes.Number, 4),
+				"ioapic": tftypes.NewValue(tftypes.Bool, false),
+			},
+			warning: true,
+		},
+		{
+			name: "unknown cpu",
+			values: map[string]tftypes.Value{
+				"cpu":    tftypes.NewValue(tftypes.Number, tftypes.UnknownValue),
+				"ioapic": tftypes.NewValue(tftypes.Bool, false),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resource.ValidateConfigRequest{Config: vmConfig(t, tt.values)}
+			resp := &resource.ValidateConfigResponse{}
+			cpuIOAPICValidator{}.ValidateResource(context.Background(), req, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+			if got := resp.Diagnostics.WarningsCount() > 0; got != tt.warning {
+				t.Errorf("warning = %v, want %v: %v", got, tt.warning, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestIPConfigValidator(t *testing.T) {
+	ctx := context.Background()
+	adapterType := types.ObjectType{AttrTypes: networkAdapterAttrTypes}.TerraformType(ctx).(tftypes.Object)
+	ipConfigType := adapterType.AttributeTypes["ip_config"].(tftypes.Object)
+	adapter := func(attachment, address string) tftypes.Value {
+		ipConfig := tftypes.NewValue(ipConfigType, nil)
+		if address != "" {
+			ipConfig = tftypes.NewValue(ipConfigType, map[string]tftypes.Value{
+				"address": tftypes.NewValue(tftypes.String, address),
+				"netmask": tftypes.NewValue(tftypes.String, "255.255.255.0"),
+				"gateway": tftypes.NewValue(tftypes.String, nil),
+				"dns":     tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+			})
+		}
+		network := tftypes.NewValue(tftypes.String, nil)
+		if attachment != "none" {
+			network = tftypes.NewValue(tftypes.String, "vboxnet0")
+		}
+		return tftypes.NewValue(adapterType, map[string]tftypes.Value{
+			"type":             tftypes.NewValue(tftypes.String, attachment),
+			"network":          network,
+			"promiscuous_mode": tftypes.NewValue(tftypes.String, nil),
+			"cable_connected":  tftypes.NewValue(tftypes.Bool, nil),
+			"ip_config":        ipConfig,
+			"ip_address":       tftypes.NewValue(tftypes.String, nil),
+		})
+	}
+	adapters := func(values ...tftypes.Value) tftypes.Value {
+		return tftypes.NewValue(tftypes.List{ElementType: adapterType}, values)
+	}
+	sshKey := tftypes.NewValue(tftypes.String, "id_ed25519.pub")
+	tests := []struct {
+		name    string
+		values  map[string]tftypes.Value
+		wantErr bool
+	}{
+		{
+			name:   "adapters without ip_config",
+			values: map[string]tftypes.Value{"network_adapter": adapters(adapter("hostonly", ""))},
+		},
+		{
+			name: "ip_config with ssh key",
+			values: map[string]tftypes.Value{
+				"ssh_key":         sshKey,
+				"network_adapter": adapters(adapter("none", ""), adapter("hostonly", "192.168.56.10")),
+			},
+		},
+		{
+			name:    "ip_config without ssh key",
+			values:  map[string]tftypes.Value{"network_adapter": adapters(adapter("hostonly", "192.168.56.10"))},
+			wantErr: true,
+		},
+		{
+			name: "ip_config of empty slot",
+			values: map[string]tftypes.Value{
+				"ssh_key":         sshKey,
+				"network_adapter": adapters(adapter("none", "192.168.56.10")),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid address",
+			values: map[string]tftypes.Value{
+				"ssh_key":         sshKey,
+				"network_adapter": adapters(adapter("hostonly", "192.168.56.300")),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resource.ValidateConfigRequest{Config: vmConfig(t, tt.values)}
+			resp := &resource.ValidateConfigResponse{}
+			ipConfigValidator{}.ValidateResource(ctx, req, resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantErr {
+				t.Errorf("error = %v, want %v: %v", got, tt.wantErr, resp.Diagnostics)
+			}
+		})
+	}
+}
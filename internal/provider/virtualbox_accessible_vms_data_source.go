@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxAccessibleVMsDataSource{}
+
+func NewVirtualboxAccessibleVMsDataSource() datasource.DataSource {
+	return &VirtualboxAccessibleVMsDataSource{}
+}
+
+// VirtualboxAccessibleVMsDataSource defines the data source implementation.
+type VirtualboxAccessibleVMsDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxAccessibleVMsDataSourceModel describes the data source data model.
+type VirtualboxAccessibleVMsDataSourceModel struct {
+	Id         types.String `tfsdk:"id"`
+	FolderPath types.String `tfsdk:"folder_path"`
+	VMIds      []string     `tfsdk:"vm_ids"`
+	VMNames    []string     `tfsdk:"vm_names"`
+}
+
+func (d *VirtualboxAccessibleVMsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_accessible_vms"
+}
+
+func (d *VirtualboxAccessibleVMsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Lists accessible vms registered in VirtualBox, optionally only ones with settings file under given folder. " +
+			"Vms which settings file is missing or broken are skipped.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier",
+				Computed:            true,
+			},
+			"folder_path": schema.StringAttribute{
+				MarkdownDescription: "List only vms which `.vbox` settings file is in this folder or its subfolders. Resolved the same way as `image` of `virtualbox_vm`.",
+				Optional:            true,
+			},
+			"vm_ids": schema.ListAttribute{
+				MarkdownDescription: "UUIDs of matching vms",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"vm_names": schema.ListAttribute{
+				MarkdownDescription: "Names of matching vms, in the same order as `vm_ids`",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *VirtualboxAccessibleVMsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxAccessibleVMsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_accessible_vms Read")
+
+	var data VirtualboxAccessibleVMsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	folder := ""
+	if !data.FolderPath.IsNull() {
+		// paths of remote host can't be resolved locally
+		folder = filepath.Clean(data.FolderPath.ValueString())
+		if !virtualboxapi.IsRemote() {
+			folder = resolveAttributePath(ctx, path.Root("folder_path"), data.FolderPath, &resp.Diagnostics)
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	vms, err := virtualboxapi.ListAccessibleVMs(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing vms", err)
+		return
+	}
+
+	data.Id = types.StringValue("accessible_vms")
+	data.VMIds = []string{}
+	data.VMNames = []string{}
+	for _, vm := range vms {
+		if folder != "" && !isUnderFolder(vm.ConfigFile, folder) {
+			continue
+		}
+		data.VMIds = append(data.VMIds, vm.ID)
+		data.VMNames = append(data.VMNames, vm.Name)
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// isUnderFolder reports whether file is in folder or its subfolders
+func isUnderFolder(file, folder string) bool {
+	rel, err := filepath.Rel(folder, filepath.Dir(file))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,247 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxBandwidthGroupResource{}
+var _ resource.ResourceWithImportState = &VirtualboxBandwidthGroupResource{}
+
+func NewVirtualboxBandwidthGroupResource() resource.Resource {
+	return &VirtualboxBandwidthGroupResource{}
+}
+
+// VirtualboxBandwidthGroupResource defines the resource implementation.
+type VirtualboxBandwidthGroupResource struct {
+	client *http.Client
+}
+
+// VirtualboxBandwidthGroupResourceModel describes the resource data model.
+type VirtualboxBandwidthGroupResourceModel struct {
+	Id             types.String `tfsdk:"id"`
+	VMId           types.String `tfsdk:"vm_id"`
+	Name           types.String `tfsdk:"name"`
+	Type           types.String `tfsdk:"type"`
+	MaxBytesPerSec types.Int64  `tfsdk:"max_bytes_per_sec"`
+}
+
+func (r *VirtualboxBandwidthGroupResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bandwidth_group"
+}
+
+func (r *VirtualboxBandwidthGroupResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Vm bandwidth group limiting disk or network I/O of disks and network adapters assigned to it",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Group identifier in `<vm_id>/<name>` format",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"vm_id": schema.StringAttribute{
+				MarkdownDescription: "Virtualbox vm id or name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Group name, must be unique per vm",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"type": schema.StringAttribute{
+				MarkdownDescription: "Limited I/O, `disk` or `network`",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf("disk", "network"),
+				},
+			},
+			"max_bytes_per_sec": schema.Int64Attribute{
+				MarkdownDescription: "Limit in bytes per second, VirtualBox accepts whole kilobytes only. " +
+					"Changing it applies to running vm immediately.",
+				Required: true,
+				Validators: []validator.Int64{
+					int64Between(0, 1024*1024*1024*1024),
+					int64MultipleOf(1024),
+				},
+			},
+		},
+	}
+}
+
+func (r *VirtualboxBandwidthGroupResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxBandwidthGroupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_bandwidth_group Create")
+
+	var data *VirtualboxBandwidthGroupResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	group, err := virtualboxapi.AddBandwidthGroup(
+		ctx,
+		data.VMId.ValueString(),
+		data.Name.ValueString(),
+		data.Type.ValueString(),
+		data.MaxBytesPerSec.ValueInt64(),
+	)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating bandwidth group", err)
+		return
+	}
+	data.Id = types.StringValue(data.VMId.ValueString() + "/" + data.Name.ValueString())
+	updateModelFromBandwidthGroup(data, group)
+
+	tflog.Trace(ctx, "created a bandwidth group")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxBandwidthGroupResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_bandwidth_group Read")
+
+	var data *VirtualboxBandwidthGroupResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	group, err := virtualboxapi.GetBandwidthGroup(ctx, data.VMId.ValueString(), data.Name.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		// vm or group was removed outside of Terraform
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting bandwidth group", err)
+		return
+	}
+	updateModelFromBandwidthGroup(data, group)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxBandwidthGroupResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	defer logStats(ctx, "virtualbox_bandwidth_group Update")
+
+	var data *VirtualboxBandwidthGroupResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// limit is the only attribute which doesn't require replacement
+	group, err := virtualboxapi.SetBandwidthGroupLimit(
+		ctx,
+		data.VMId.ValueString(),
+		data.Name.ValueString(),
+		data.MaxBytesPerSec.ValueInt64(),
+	)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error changing bandwidth group limit", err)
+		return
+	}
+	updateModelFromBandwidthGroup(data, group)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxBandwidthGroupResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_bandwidth_group Delete")
+
+	var data *VirtualboxBandwidthGroupResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.RemoveBandwidthGroup(ctx, data.VMId.ValueString(), data.Name.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		// vm or group is already destroyed outside of Terraform
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error deleting bandwidth group", err)
+		return
+	}
+}
+
+func (r *VirtualboxBandwidthGroupResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	vmID, name, found := strings.Cut(req.ID, "/")
+	if !found || vmID == "" || name == "" {
+		resp.Diagnostics.AddError(
+			"Unexpected Import Identifier",
+			fmt.Sprintf("Expected import identifier with format: <vm_id>/<name>. Got: %q", req.ID),
+		)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("vm_id"), vmID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+}
+
+// updateModelFromBandwidthGroup refreshes resource model from actual group info
+func updateModelFromBandwidthGroup(data *VirtualboxBandwidthGroupResourceModel, group *virtualboxapi.BandwidthGroupInfo) {
+	data.Type = types.StringValue(strings.ToLower(group.Type))
+	data.MaxBytesPerSec = types.Int64Value(group.MaxBytesPerSec)
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxDHCPServerResource{}
+var _ resource.ResourceWithImportState = &VirtualboxDHCPServerResource{}
+var _ resource.ResourceWithValidateConfig = &VirtualboxDHCPServerResource{}
+
+func NewVirtualboxDHCPServerResource() resource.Resource {
+	return &VirtualboxDHCPServerResource{}
+}
+
+// VirtualboxDHCPServerResource defines the resource implementation.
+type VirtualboxDHCPServerResource struct {
+	client *http.Client
+}
+
+// VirtualboxDHCPServerResourceModel describes the resource data model.
+type VirtualboxDHCPServerResourceModel struct {
+	Id          types.String `tfsdk:"id"`
+	NetworkName types.String `tfsdk:"network_name"`
+	Interface   types.String `tfsdk:"interface"`
+	ServerIP    types.String `tfsdk:"server_ip"`
+	Netmask     types.String `tfsdk:"netmask"`
+	LowerIP     types.String `tfsdk:"lower_ip"`
+	UpperIP     types.String `tfsdk:"upper_ip"`
+	Enabled     types.Bool   `tfsdk:"enabled"`
+}
+
+func (r *VirtualboxDHCPServerResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_dhcp_server"
+}
+
+func (r *VirtualboxDHCPServerResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "VirtualBox dhcp server of host-only or NAT network. " +
+			"Don't combine it with `enable_dhcp` of `virtualbox_hostonly_if` for the same interface.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Server identifier, same as network_name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"network_name": schema.StringAttribute{
+				MarkdownDescription: "VirtualBox network name, e.g. `HostInterfaceNetworking-vboxnet0` or NAT network name. " +
+					"Exactly one of `network_name` and `interface` has to be set.",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"interface": schema.StringAttribute{
+				MarkdownDescription: "Host-only interface name, e.g. `vboxnet0`",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"server_ip": schema.StringAttribute{
+				MarkdownDescription: "Address of dhcp server itself",
+				Required:            true,
+			},
+			"netmask": schema.StringAttribute{
+				MarkdownDescription: "Network mask, e.g. `255.255.255.0`",
+				Required:            true,
+			},
+			"lower_ip": schema.StringAttribute{
+				MarkdownDescription: "First address leased by dhcp server",
+				Required:            true,
+			},
+			"upper_ip": schema.StringAttribute{
+				MarkdownDescription: "Last address leased by dhcp server",
+				Required:            true,
+			},
+			"enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether dhcp server is enabled. `true` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+		},
+	}
+}
+
+func (r *VirtualboxDHCPServerResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data VirtualboxDHCPServerResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() || data.NetworkName.IsUnknown() || data.Interface.IsUnknown() {
+		return
+	}
+
+	if data.NetworkName.IsNull() == data.Interface.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("network_name"), "Invalid Attribute Combination", "Exactly one of network_name and interface has to be set")
+	}
+}
+
+func (r *VirtualboxDHCPServerResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxDHCPServerResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_dhcp_server Create")
+
+	var data *VirtualboxDHCPServerResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Interface.IsNull() {
+		data.NetworkName = types.StringValue(virtualboxapi.HostOnlyNetworkName(data.Interface.ValueString()))
+	}
+
+	err := virtualboxapi.SetDHCPServer(ctx, dhcpServer(data))
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating dhcp server", err)
+		return
+	}
+
+	// save into the Terraform state.
+	data.Id = data.NetworkName
+
+	tflog.Trace(ctx, "created a dhcp server")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxDHCPServerResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_dhcp_server Read")
+
+	var data *VirtualboxDHCPServerResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// dhcp server may outlive its host-only interface
+	if prefix := virtualboxapi.HostOnlyNetworkName(""); strings.HasPrefix(data.Id.ValueString(), prefix) {
+		_, err := virtualboxapi.GetHostOnlyInterface(ctx, strings.TrimPrefix(data.Id.ValueString(), prefix))
+		if virtualboxapi.IsObjectNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		if err != nil {
+			addError(&resp.Diagnostics, "Error getting host-only interface", err)
+			return
+		}
+	}
+
+	server, err := virtualboxapi.GetDHCPServer(ctx, data.Id.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting dhcp server", err)
+		return
+	}
+	if server == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	data.NetworkName = types.StringValue(server.NetworkName)
+	data.ServerIP = types.StringValue(server.IPAddress)
+	data.Netmask = types.StringValue(server.NetworkMask)
+	data.LowerIP = types.StringValue(server.LowerIP)
+	data.UpperIP = types.StringValue(server.UpperIP)
+	data.Enabled = types.BoolValue(server.Enabled)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxDHCPServerResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	defer logStats(ctx, "virtualbox_dhcp_server Update")
+
+	var data *VirtualboxDHCPServerResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.SetDHCPServer(ctx, dhcpServer(data))
+	if err != nil {
+		addError(&resp.Diagnostics, "Error updating dhcp server", err)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxDHCPServerResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_dhcp_server Delete")
+
+	var data *VirtualboxDHCPServerResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.RemoveDHCPServer(ctx, data.Id.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error removing dhcp server", err)
+		return
+	}
+}
+
+func (r *VirtualboxDHCPServerResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// dhcpServer converts resource model into api dhcp server
+func dhcpServer(data *VirtualboxDHCPServerResourceModel) virtualboxapi.DHCPServer {
+	return virtualboxapi.DHCPServer{
+		NetworkName: data.NetworkName.ValueString(),
+		IPAddress:   data.ServerIP.ValueString(),
+		LowerIP:     data.LowerIP.ValueString(),
+		UpperIP:     data.UpperIP.ValueString(),
+		NetworkMask: data.Netmask.ValueString(),
+		Enabled:     data.Enabled.ValueBool(),
+	}
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxDiskResource{}
+var _ resource.ResourceWithImportState = &VirtualboxDiskResource{}
+
+func NewVirtualboxDiskResource() resource.Resource {
+	return &VirtualboxDiskResource{}
+}
+
+// VirtualboxDiskResource defines the resource implementation.
+type VirtualboxDiskResource struct {
+	client *http.Client
+}
+
+// VirtualboxDiskResourceModel describes the resource data model.
+type VirtualboxDiskResourceModel struct {
+	Id      types.String `tfsdk:"id"`
+	Path    types.String `tfsdk:"path"`
+	SizeMB  types.Int64  `tfsdk:"size_mb"`
+	Format  types.String `tfsdk:"format"`
+	Variant types.String `tfsdk:"variant"`
+}
+
+func (r *VirtualboxDiskResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_disk"
+}
+
+func (r *VirtualboxDiskResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Standalone VirtualBox disk, which outlives vms it's attached to. " +
+			"Attach it with `disk_ids` of `virtualbox_vm`.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Disk UUID",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Path of disk image file",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"size_mb": schema.Int64Attribute{
+				MarkdownDescription: "Disk size (MB). Disk could only grow, vm using it must be powered off.",
+				Required:            true,
+				Validators: []validator.Int64{
+					int64Between(1, 2*1024*1024*1024),
+				},
+			},
+			"format": schema.StringAttribute{
+				MarkdownDescription: "Disk format, `VDI`, `VMDK` or `VHD`. `VDI` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("VDI"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf("VDI", "VMDK", "VHD"),
+				},
+			},
+			"variant": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("`%s` for dynamically allocated disk or `%s` for preallocated one. `%s` by default.",
+					virtualboxapi.DiskVariantStandard, virtualboxapi.DiskVariantFixed, virtualboxapi.DiskVariantStandard),
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(virtualboxapi.DiskVariantStandard),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf(virtualboxapi.DiskVariantStandard, virtualboxapi.DiskVariantFixed),
+				},
+			},
+		},
+	}
+}
+
+func (r *VirtualboxDiskResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxDiskResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_disk Create")
+
+	var data *VirtualboxDiskResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	medium, err := virtualboxapi.CreateDisk(
+		ctx,
+		data.Path.ValueString(),
+		data.SizeMB.ValueInt64(),
+		data.Format.ValueString(),
+		data.Variant.ValueString(),
+	)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating disk", err)
+		return
+	}
+	updateModelFromMedium(data, medium)
+
+	tflog.Trace(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxDiskResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_disk Read")
+
+	var data *VirtualboxDiskResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	medium, err := virtualboxapi.GetMediumInfo(ctx, data.Id.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		// disk was removed outside of Terraform, it will be recreated on next apply
+		tflog.Warn(ctx, "disk not found, removing from state", map[string]interface{}{"id": data.Id.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting disk info", err)
+		return
+	}
+	updateModelFromMedium(data, medium)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxDiskResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	defer logStats(ctx, "virtualbox_disk Update")
+
+	var data, state *VirtualboxDiskResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.SizeMB.ValueInt64() < state.SizeMB.ValueInt64() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("size_mb"),
+			"Disk can't be shrunk",
+			fmt.Sprintf("VirtualBox is only able to grow disks, current size is %d MB, got: %d MB", state.SizeMB.ValueInt64(), data.SizeMB.ValueInt64()),
+		)
+		return
+	}
+
+	medium, err := virtualboxapi.ResizeDisk(ctx, data.Id.ValueString(), data.SizeMB.ValueInt64())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error resizing disk", err)
+		return
+	}
+	updateModelFromMedium(data, medium)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxDiskResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_disk Delete")
+
+	var data *VirtualboxDiskResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.DeleteDisk(ctx, data.Id.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		// Already removed outside of Terraform
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error deleting disk", err)
+		return
+	}
+}
+
+func (r *VirtualboxDiskResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// disk could be imported either by UUID or by path, Read replaces path with UUID
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// updateModelFromMedium refreshes resource model from actual disk info
+func updateModelFromMedium(data *VirtualboxDiskResourceModel, medium *virtualboxapi.Medium) {
+	data.Id = types.StringValue(medium.ID)
+	if data.Path.IsNull() {
+		// configured path may be relative, VirtualBox reports absolute one
+		data.Path = types.StringValue(medium.Location)
+	}
+	data.SizeMB = types.Int64Value(medium.SizeMB)
+	data.Format = types.StringValue(medium.Format)
+	data.Variant = types.StringValue(medium.Variant)
+}
@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxEnvironmentDataSource{}
+
+func NewVirtualboxEnvironmentDataSource() datasource.DataSource {
+	return &VirtualboxEnvironmentDataSource{}
+}
+
+// VirtualboxEnvironmentDataSource defines the data source implementation.
+type VirtualboxEnvironmentDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxEnvironmentDataSourceModel describes the data source data model.
+type VirtualboxEnvironmentDataSourceModel struct {
+	Id                   types.String `tfsdk:"id"`
+	VBoxManageVersion    types.String `tfsdk:"vboxmanage_version"`
+	ExtensionPacks       types.Map    `tfsdk:"extension_packs"`
+	HostOS               types.String `tfsdk:"host_os"`
+	HostArch             types.String `tfsdk:"host_arch"`
+	DefaultMachineFolder types.String `tfsdk:"default_machine_folder"`
+	ProviderVersion      types.String `tfsdk:"provider_version"`
+}
+
+func (d *VirtualboxEnvironmentDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_environment"
+}
+
+func (d *VirtualboxEnvironmentDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Environment of provider and VirtualBox, output the whole data source when reporting provider issues",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier",
+				Computed:            true,
+			},
+			"vboxmanage_version": schema.StringAttribute{
+				MarkdownDescription: "VirtualBox version as reported by `VBoxManage --version`",
+				Computed:            true,
+			},
+			"extension_packs": schema.MapAttribute{
+				MarkdownDescription: "Versions of installed extension packs by name",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"host_os": schema.StringAttribute{
+				MarkdownDescription: "Operating system provider runs on, e.g. `linux`",
+				Computed:            true,
+			},
+			"host_arch": schema.StringAttribute{
+				MarkdownDescription: "Architecture provider runs on, e.g. `amd64`",
+				Computed:            true,
+			},
+			"default_machine_folder": schema.StringAttribute{
+				MarkdownDescription: "VirtualBox default machine folder",
+				Computed:            true,
+			},
+			"provider_version": schema.StringAttribute{
+				MarkdownDescription: "Provider version, `dev` for local builds",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *VirtualboxEnvironmentDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxEnvironmentDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_environment Read")
+
+	var data VirtualboxEnvironmentDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	version, err := virtualboxapi.GetVBoxVersion(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting VirtualBox version", err)
+		return
+	}
+	properties, err := virtualboxapi.GetSystemProperties(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting VirtualBox system properties", err)
+		return
+	}
+	extensionPacks, err := virtualboxapi.ListExtensionPacks(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing VirtualBox extension packs", err)
+		return
+	}
+
+	packVersions := map[string]attr.Value{}
+	for _, pack := range extensionPacks {
+		packVersions[pack.Name] = types.StringValue(pack.Version)
+	}
+
+	data.Id = types.StringValue("environment")
+	data.VBoxManageVersion = types.StringValue(version)
+	data.HostOS = types.StringValue(runtime.GOOS)
+	data.HostArch = types.StringValue(runtime.GOARCH)
+	data.DefaultMachineFolder = types.StringValue(properties["Default machine folder"])
+	data.ExtensionPacks = types.MapValueMust(types.StringType, packVersions)
+	data.ProviderVersion = types.StringValue(providerVersion)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
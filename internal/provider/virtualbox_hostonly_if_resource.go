@@ -0,0 +1,306 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxHostOnlyIfResource{}
+var _ resource.ResourceWithImportState = &VirtualboxHostOnlyIfResource{}
+var _ resource.ResourceWithValidateConfig = &VirtualboxHostOnlyIfResource{}
+
+func NewVirtualboxHostOnlyIfResource() resource.Resource {
+	return &VirtualboxHostOnlyIfResource{}
+}
+
+// VirtualboxHostOnlyIfResource defines the resource implementation.
+type VirtualboxHostOnlyIfResource struct {
+	client *http.Client
+}
+
+// VirtualboxHostOnlyIfResourceModel describes the resource data model.
+type VirtualboxHostOnlyIfResourceModel struct {
+	Id          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	IPv4Address types.String `tfsdk:"ipv4_address"`
+	IPv4Mask    types.String `tfsdk:"ipv4_mask"`
+	EnableDHCP  types.Bool   `tfsdk:"enable_dhcp"`
+	LowerIP     types.String `tfsdk:"lower_ip"`
+	UpperIP     types.String `tfsdk:"upper_ip"`
+}
+
+func (r *VirtualboxHostOnlyIfResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_hostonly_if"
+}
+
+func (r *VirtualboxHostOnlyIfResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Virtualbox host-only network interface",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Interface identifier, same as name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Interface name assigned by VirtualBox, e.g. `vboxnet0`",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"ipv4_address": schema.StringAttribute{
+				MarkdownDescription: "Host address on interface",
+				Required:            true,
+			},
+			"ipv4_mask": schema.StringAttribute{
+				MarkdownDescription: "Network mask, e.g. `255.255.255.0`",
+				Required:            true,
+			},
+			"enable_dhcp": schema.BoolAttribute{
+				MarkdownDescription: "Whether VirtualBox dhcp server is enabled for network. `false` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"lower_ip": schema.StringAttribute{
+				MarkdownDescription: "First address leased by dhcp server, required when dhcp is enabled. " +
+					"Dhcp server itself takes the address right before it.",
+				Optional: true,
+			},
+			"upper_ip": schema.StringAttribute{
+				MarkdownDescription: "Last address leased by dhcp server, required when dhcp is enabled",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *VirtualboxHostOnlyIfResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data VirtualboxHostOnlyIfResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() || !data.EnableDHCP.ValueBool() {
+		return
+	}
+
+	if data.LowerIP.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("lower_ip"), "Missing Attribute", "lower_ip is required when enable_dhcp is true")
+	}
+	if data.UpperIP.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("upper_ip"), "Missing Attribute", "upper_ip is required when enable_dhcp is true")
+	}
+}
+
+func (r *VirtualboxHostOnlyIfResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxHostOnlyIfResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_hostonly_if Create")
+
+	var data *VirtualboxHostOnlyIfResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name, err := virtualboxapi.CreateHostOnlyInterface(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating host-only interface", err)
+		return
+	}
+
+	err = configureHostOnlyInterface(ctx, data, name, false)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error configuring host-only interface", err)
+		err = virtualboxapi.RemoveHostOnlyInterface(ctx, name)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error removing host-only interface", err)
+		}
+		return
+	}
+
+	// save into the Terraform state.
+	data.Id = types.StringValue(name)
+	data.Name = types.StringValue(name)
+
+	tflog.Trace(ctx, "created a host-only interface")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxHostOnlyIfResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_hostonly_if Read")
+
+	var data *VirtualboxHostOnlyIfResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	hostOnlyIf, err := virtualboxapi.GetHostOnlyInterface(ctx, data.Id.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting host-only interface", err)
+		return
+	}
+	data.Name = types.StringValue(hostOnlyIf.Name)
+	data.IPv4Address = types.StringValue(hostOnlyIf.IPAddress)
+	data.IPv4Mask = types.StringValue(hostOnlyIf.NetworkMask)
+
+	server, err := virtualboxapi.GetDHCPServer(ctx, virtualboxapi.HostOnlyNetworkName(hostOnlyIf.Name))
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting dhcp server", err)
+		return
+	}
+	data.EnableDHCP = types.BoolValue(server != nil && server.Enabled)
+	if server != nil && server.Enabled {
+		data.LowerIP = types.StringValue(server.LowerIP)
+		data.UpperIP = types.StringValue(server.UpperIP)
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxHostOnlyIfResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	defer logStats(ctx, "virtualbox_hostonly_if Update")
+
+	var data, state *VirtualboxHostOnlyIfResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := configureHostOnlyInterface(ctx, data, state.Name.ValueString(), state.EnableDHCP.ValueBool())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error configuring host-only interface", err)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxHostOnlyIfResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_hostonly_if Delete")
+
+	var data *VirtualboxHostOnlyIfResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.RemoveDHCPServer(ctx, virtualboxapi.HostOnlyNetworkName(data.Name.ValueString()))
+	if err != nil {
+		addError(&resp.Diagnostics, "Error removing dhcp server", err)
+		return
+	}
+	err = virtualboxapi.RemoveHostOnlyInterface(ctx, data.Name.ValueString())
+	if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+		addError(&resp.Diagnostics, "Error removing host-only interface", err)
+		return
+	}
+}
+
+func (r *VirtualboxHostOnlyIfResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// configureHostOnlyInterface applies address and dhcp settings to interface,
+// hadDHCP tells whether interface has dhcp server which has to be removed when dhcp is disabled
+func configureHostOnlyInterface(ctx context.Context, data *VirtualboxHostOnlyIfResourceModel, name string, hadDHCP bool) error {
+	_, err := virtualboxapi.ConfigureHostOnlyInterface(ctx, name, data.IPv4Address.ValueString(), data.IPv4Mask.ValueString())
+	if err != nil {
+		return err
+	}
+	networkName := virtualboxapi.HostOnlyNetworkName(name)
+	if !data.EnableDHCP.ValueBool() {
+		if hadDHCP {
+			return virtualboxapi.RemoveDHCPServer(ctx, networkName)
+		}
+		return nil
+	}
+	serverIP, err := previousIP(data.LowerIP.ValueString())
+	if err != nil {
+		return err
+	}
+	return virtualboxapi.SetDHCPServer(ctx, virtualboxapi.DHCPServer{
+		NetworkName: networkName,
+		IPAddress:   serverIP,
+		LowerIP:     data.LowerIP.ValueString(),
+		UpperIP:     data.UpperIP.ValueString(),
+		NetworkMask: data.IPv4Mask.ValueString(),
+		Enabled:     true,
+	})
+}
+
+// previousIP returns ipv4 address preceding given one, e.g. 192.168.56.100 for 192.168.56.101
+func previousIP(address string) (string, error) {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return "", fmt.Errorf("Invalid ipv4 address: %q", address)
+	}
+	result := make(net.IP, len(ip))
+	copy(result, ip)
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i]--
+		if result[i] != 255 {
+			break
+		}
+	}
+	return result.String(), nil
+}
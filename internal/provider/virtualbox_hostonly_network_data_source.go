@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxHostOnlyNetworkDataSource{}
+
+func NewVirtualboxHostOnlyNetworkDataSource() datasource.DataSource {
+	return &VirtualboxHostOnlyNetworkDataSource{}
+}
+
+// VirtualboxHostOnlyNetworkDataSource defines the data source implementation.
+type VirtualboxHostOnlyNetworkDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxHostOnlyNetworkDataSourceModel describes the data source data model.
+type VirtualboxHostOnlyNetworkDataSourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	CIDRContains  types.String `tfsdk:"cidr_contains"`
+	InterfaceName types.String `tfsdk:"interface_name"`
+	Name          types.String `tfsdk:"name"`
+	CIDR          types.String `tfsdk:"cidr"`
+	DHCPEnabled   types.Bool   `tfsdk:"dhcp_enabled"`
+}
+
+func (d *VirtualboxHostOnlyNetworkDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_hostonly_network"
+}
+
+func (d *VirtualboxHostOnlyNetworkDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Looks up existing host-only network, exactly one network has to match filters",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Host-only interface identifier",
+				Computed:            true,
+			},
+			"cidr_contains": schema.StringAttribute{
+				MarkdownDescription: "Filter networks by ip address, which must belong to network",
+				Optional:            true,
+			},
+			"interface_name": schema.StringAttribute{
+				MarkdownDescription: "Host-only interface name, e.g. `vboxnet0`. Could be used as a filter.",
+				Optional:            true,
+				Computed:            true,
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "VirtualBox network name",
+				Computed:            true,
+			},
+			"cidr": schema.StringAttribute{
+				MarkdownDescription: "Network address in CIDR notation",
+				Computed:            true,
+			},
+			"dhcp_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether VirtualBox dhcp server is enabled for network",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *VirtualboxHostOnlyNetworkDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxHostOnlyNetworkDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_hostonly_network Read")
+
+	var data VirtualboxHostOnlyNetworkDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var contains net.IP
+	if !data.CIDRContains.IsNull() {
+		contains = net.ParseIP(data.CIDRContains.ValueString())
+		if contains == nil {
+			resp.Diagnostics.AddError("Invalid cidr_contains", fmt.Sprintf("%q is not an ip address", data.CIDRContains.ValueString()))
+			return
+		}
+	}
+
+	interfaces, err := virtualboxapi.ListHostOnlyInterfaces(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing host-only interfaces", err)
+		return
+	}
+	servers, err := virtualboxapi.ListDHCPServers(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing dhcp servers", err)
+		return
+	}
+
+	matched := []virtualboxapi.HostOnlyInterface{}
+	for _, hostOnlyIf := range interfaces {
+		if !data.InterfaceName.IsNull() && hostOnlyIf.Name != data.InterfaceName.ValueString() {
+			continue
+		}
+		if contains != nil && !hostOnlyNetwork(hostOnlyIf).Contains(contains) {
+			continue
+		}
+		matched = append(matched, hostOnlyIf)
+	}
+	if len(matched) != 1 {
+		names := []string{}
+		for _, hostOnlyIf := range matched {
+			names = append(names, hostOnlyIf.Name)
+		}
+		resp.Diagnostics.AddError(
+			"Unable to find host-only network",
+			fmt.Sprintf("Exactly one host-only network has to match filters, found %d: [%s]", len(matched), strings.Join(names, ", ")),
+		)
+		return
+	}
+
+	hostOnlyIf := matched[0]
+	dhcpEnabled := false
+	for _, server := range servers {
+		if server.NetworkName == hostOnlyIf.NetworkName {
+			dhcpEnabled = server.Enabled
+		}
+	}
+	data.Id = types.StringValue(hostOnlyIf.GUID)
+	data.InterfaceName = types.StringValue(hostOnlyIf.Name)
+	data.Name = types.StringValue(hostOnlyIf.NetworkName)
+	data.CIDR = types.StringValue(hostOnlyNetwork(hostOnlyIf).String())
+	data.DHCPEnabled = types.BoolValue(dhcpEnabled)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// hostOnlyNetwork returns network of host-only interface address
+func hostOnlyNetwork(hostOnlyIf virtualboxapi.HostOnlyInterface) *net.IPNet {
+	ip := net.ParseIP(hostOnlyIf.IPAddress).To4()
+	mask := net.IPMask(net.ParseIP(hostOnlyIf.NetworkMask).To4())
+	if ip == nil || mask == nil {
+		return &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+}
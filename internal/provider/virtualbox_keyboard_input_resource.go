@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxKeyboardInputResource{}
+
+func NewVirtualboxKeyboardInputResource() resource.Resource {
+	return &VirtualboxKeyboardInputResource{}
+}
+
+// VirtualboxKeyboardInputResource defines the resource implementation.
+type VirtualboxKeyboardInputResource struct {
+	client *http.Client
+}
+
+// VirtualboxKeyboardInputResourceModel describes the resource data model.
+type VirtualboxKeyboardInputResourceModel struct {
+	Id      types.String `tfsdk:"id"`
+	VMId    types.String `tfsdk:"vm_id"`
+	Text    types.String `tfsdk:"text"`
+	DelayMs types.Int64  `tfsdk:"delay_ms"`
+	Applied types.Bool   `tfsdk:"applied"`
+}
+
+func (r *VirtualboxKeyboardInputResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_keyboard_input"
+}
+
+func (r *VirtualboxKeyboardInputResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Types text on keyboard of running vm once, when resource is created. " +
+			"Changing any attribute types text again, destroying resource does nothing.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Input identifier",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"vm_id": schema.StringAttribute{
+				MarkdownDescription: "Id of running vm",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"text": schema.StringAttribute{
+				MarkdownDescription: "Text to type",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"delay_ms": schema.Int64Attribute{
+				MarkdownDescription: "Delay between characters in milliseconds. With non-zero delay characters are typed one by one " +
+					"as US keyboard scancodes, so only ASCII characters, tab and newline are supported. `0` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  int64default.StaticInt64(0),
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+				Validators: []validator.Int64{
+					int64Between(0, 60000),
+				},
+			},
+			"applied": schema.BoolAttribute{
+				MarkdownDescription: "Always `true`, text is typed on create",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *VirtualboxKeyboardInputResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxKeyboardInputResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_keyboard_input Create")
+
+	var data *VirtualboxKeyboardInputResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.KeyboardPutString(
+		ctx,
+		data.VMId.ValueString(),
+		data.Text.ValueString(),
+		time.Duration(data.DelayMs.ValueInt64())*time.Millisecond,
+	)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error typing text", err)
+		return
+	}
+
+	// the same text may be typed into the same vm several times
+	data.Id = types.StringValue(fmt.Sprintf("%s-%d", data.VMId.ValueString(), time.Now().UnixNano()))
+	data.Applied = types.BoolValue(true)
+
+	tflog.Trace(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxKeyboardInputResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Typed text can't be read back, state is kept as is
+}
+
+func (r *VirtualboxKeyboardInputResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// All attributes require replacement, so Update is never called
+}
+
+func (r *VirtualboxKeyboardInputResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Typed text can't be undone
+}
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxMediumDataSource{}
+
+func NewVirtualboxMediumDataSource() datasource.DataSource {
+	return &VirtualboxMediumDataSource{}
+}
+
+// VirtualboxMediumDataSource defines the data source implementation.
+type VirtualboxMediumDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxMediumDataSourceModel describes the data source data model.
+type VirtualboxMediumDataSourceModel struct {
+	Id              types.String            `tfsdk:"id"`
+	Type            types.String            `tfsdk:"type"`
+	Format          types.String            `tfsdk:"format"`
+	LocationPattern types.String            `tfsdk:"location_pattern"`
+	Mediums         []VirtualboxMediumModel `tfsdk:"mediums"`
+}
+
+// VirtualboxMediumModel describes registered medium.
+type VirtualboxMediumModel struct {
+	UUID       types.String `tfsdk:"uuid"`
+	ParentUUID types.String `tfsdk:"parent_uuid"`
+	State      types.String `tfsdk:"state"`
+	Type       types.String `tfsdk:"type"`
+	Location   types.String `tfsdk:"location"`
+	Format     types.String `tfsdk:"format"`
+	CapacityMB types.Int64  `tfsdk:"capacity_mb"`
+}
+
+func (d *VirtualboxMediumDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_medium"
+}
+
+func (d *VirtualboxMediumDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Lists media registered in VirtualBox media registry, e.g. to find existing disk image by location.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier, same as type",
+				Computed:            true,
+			},
+			"type": schema.StringAttribute{
+				MarkdownDescription: "Medium type, one of `hdd`, `dvd` or `floppy`",
+				Required:            true,
+				Validators: []validator.String{
+					stringOneOf(virtualboxapi.MediumTypeHDD, virtualboxapi.MediumTypeDVD, virtualboxapi.MediumTypeFloppy),
+				},
+			},
+			"format": schema.StringAttribute{
+				MarkdownDescription: "Return only media of given storage format, e.g. `VDI` or `VMDK`",
+				Optional:            true,
+			},
+			"location_pattern": schema.StringAttribute{
+				MarkdownDescription: "Return only media which location matches given glob pattern, e.g. `/data/ubuntu-*.vdi`. " +
+					"`*` doesn't match path separator",
+				Optional: true,
+				Validators: []validator.String{
+					stringIsGlob(),
+				},
+			},
+			"mediums": schema.ListNestedAttribute{
+				MarkdownDescription: "Matching media, in order of VirtualBox media registry",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"uuid": schema.StringAttribute{
+							MarkdownDescription: "Medium UUID",
+							Computed:            true,
+						},
+						"parent_uuid": schema.StringAttribute{
+							MarkdownDescription: "UUID of parent medium, empty for base medium",
+							Computed:            true,
+						},
+						"state": schema.StringAttribute{
+							MarkdownDescription: "Medium state, e.g. `created` or `inaccessible`",
+							Computed:            true,
+						},
+						"type": schema.StringAttribute{
+							MarkdownDescription: "Medium type as reported by VirtualBox, e.g. `normal (base)` or `readonly`",
+							Computed:            true,
+						},
+						"location": schema.StringAttribute{
+							MarkdownDescription: "Path to medium file",
+							Computed:            true,
+						},
+						"format": schema.StringAttribute{
+							MarkdownDescription: "Medium storage format",
+							Computed:            true,
+						},
+						"capacity_mb": schema.Int64Attribute{
+							MarkdownDescription: "Medium capacity in megabytes",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *VirtualboxMediumDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxMediumDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_medium Read")
+
+	var data VirtualboxMediumDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	mediums, err := virtualboxapi.ListMediums(ctx, data.Type.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing mediums", err)
+		return
+	}
+
+	data.Id = data.Type
+	data.Mediums = []VirtualboxMediumModel{}
+	for _, medium := range mediums {
+		if !data.Format.IsNull() && medium.Format != data.Format.ValueString() {
+			continue
+		}
+		if !data.LocationPattern.IsNull() {
+			// pattern is checked by validator
+			matched, _ := filepath.Match(data.LocationPattern.ValueString(), medium.Location)
+			if !matched {
+				continue
+			}
+		}
+		data.Mediums = append(data.Mediums, VirtualboxMediumModel{
+			UUID:       types.StringValue(medium.UUID),
+			ParentUUID: types.StringValue(medium.ParentUUID),
+			State:      types.StringValue(medium.State),
+			Type:       types.StringValue(medium.Type),
+			Location:   types.StringValue(medium.Location),
+			Format:     types.StringValue(medium.Format),
+			CapacityMB: types.Int64Value(medium.CapacityMB),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxNetworksDataSource{}
+
+func NewVirtualboxNetworksDataSource() datasource.DataSource {
+	return &VirtualboxNetworksDataSource{}
+}
+
+// VirtualboxNetworksDataSource defines the data source implementation.
+type VirtualboxNetworksDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxNetworksDataSourceModel describes the data source data model.
+type VirtualboxNetworksDataSourceModel struct {
+	Id                types.String                     `tfsdk:"id"`
+	HostOnlyNetworks  []VirtualboxHostOnlyNetworkModel `tfsdk:"hostonly_networks"`
+	NATNetworks       []VirtualboxNATNetworkModel      `tfsdk:"nat_networks"`
+	BridgedInterfaces []VirtualboxBridgedIfModel       `tfsdk:"bridged_interfaces"`
+}
+
+// VirtualboxHostOnlyNetworkModel describes host-only interface or network.
+type VirtualboxHostOnlyNetworkModel struct {
+	Name        types.String `tfsdk:"name"`
+	NetworkName types.String `tfsdk:"network_name"`
+	CIDR        types.String `tfsdk:"cidr"`
+}
+
+// VirtualboxNATNetworkModel describes NAT network.
+type VirtualboxNATNetworkModel struct {
+	Name        types.String `tfsdk:"name"`
+	CIDR        types.String `tfsdk:"cidr"`
+	Gateway     types.String `tfsdk:"gateway"`
+	DHCPEnabled types.Bool   `tfsdk:"dhcp_enabled"`
+	Enabled     types.Bool   `tfsdk:"enabled"`
+}
+
+// VirtualboxBridgedIfModel describes host interface available for bridged networking.
+type VirtualboxBridgedIfModel struct {
+	Name        types.String `tfsdk:"name"`
+	IPAddress   types.String `tfsdk:"ip_address"`
+	NetworkMask types.String `tfsdk:"network_mask"`
+	Status      types.String `tfsdk:"status"`
+}
+
+func (d *VirtualboxNetworksDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_networks"
+}
+
+func (d *VirtualboxNetworksDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Lists all VirtualBox host-only networks, NAT networks and bridged interfaces",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier",
+				Computed:            true,
+			},
+			"hostonly_networks": schema.ListNestedAttribute{
+				MarkdownDescription: "Host-only interfaces and host-only networks (VirtualBox 7.0+)",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Interface or network name, e.g. `vboxnet0`",
+							Computed:            true,
+						},
+						"network_name": schema.StringAttribute{
+							MarkdownDescription: "VirtualBox network name",
+							Computed:            true,
+						},
+						"cidr": schema.StringAttribute{
+							MarkdownDescription: "Network address in CIDR notation",
+							Computed:            true,
+						},
+					},
+				},
+			},
+			"nat_networks": schema.ListNestedAttribute{
+				MarkdownDescription: "NAT networks",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Network name",
+							Computed:            true,
+						},
+						"cidr": schema.StringAttribute{
+							MarkdownDescription: "Network address in CIDR notation",
+							Computed:            true,
+						},
+						"gateway": schema.StringAttribute{
+							MarkdownDescription: "Gateway address",
+							Computed:            true,
+						},
+						"dhcp_enabled": schema.BoolAttribute{
+							MarkdownDescription: "Whether dhcp server is enabled for network",
+							Computed:            true,
+						},
+						"enabled": schema.BoolAttribute{
+							MarkdownDescription: "Whether network is enabled",
+							Computed:            true,
+						},
+					},
+				},
+			},
+			"bridged_interfaces": schema.ListNestedAttribute{
+				MarkdownDescription: "Host interfaces available for bridged networking",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Interface name",
+							Computed:            true,
+						},
+						"ip_address": schema.StringAttribute{
+							MarkdownDescription: "Interface ipv4 address",
+							Computed:            true,
+						},
+						"network_mask": schema.StringAttribute{
+							MarkdownDescription: "Interface network mask",
+							Computed:            true,
+						},
+						"status": schema.StringAttribute{
+							MarkdownDescription: "Interface status, e.g. `Up`",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *VirtualboxNetworksDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxNetworksDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_networks Read")
+
+	var data VirtualboxNetworksDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	interfaces, err := virtualboxapi.ListHostOnlyInterfaces(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing host-only interfaces", err)
+		return
+	}
+	hostOnlyNetworks, err := virtualboxapi.ListHostOnlyNetworks(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing host-only networks", err)
+		return
+	}
+	natNetworks, err := virtualboxapi.ListNATNetworks(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing NAT networks", err)
+		return
+	}
+	bridgedInterfaces, err := virtualboxapi.ListBridgedInterfaces(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing bridged interfaces", err)
+		return
+	}
+
+	data.Id = types.StringValue("networks")
+	data.HostOnlyNetworks = []VirtualboxHostOnlyNetworkModel{}
+	for _, hostOnlyIf := range interfaces {
+		data.HostOnlyNetworks = append(data.HostOnlyNetworks, VirtualboxHostOnlyNetworkModel{
+			Name:        types.StringValue(hostOnlyIf.Name),
+			NetworkName: types.StringValue(hostOnlyIf.NetworkName),
+			CIDR:        types.StringValue(hostOnlyNetwork(hostOnlyIf).String()),
+		})
+	}
+	for _, network := range hostOnlyNetworks {
+		// host-only network has no host address, dhcp range defines the network
+		cidr := hostOnlyNetwork(virtualboxapi.HostOnlyInterface{IPAddress: network.LowerIP, NetworkMask: network.NetworkMask})
+		data.HostOnlyNetworks = append(data.HostOnlyNetworks, VirtualboxHostOnlyNetworkModel{
+			Name:        types.StringValue(network.Name),
+			NetworkName: types.StringValue(network.NetworkName),
+			CIDR:        types.StringValue(cidr.String()),
+		})
+	}
+	data.NATNetworks = []VirtualboxNATNetworkModel{}
+	for _, network := range natNetworks {
+		data.NATNetworks = append(data.NATNetworks, VirtualboxNATNetworkModel{
+			Name:        types.StringValue(network.Name),
+			CIDR:        types.StringValue(network.Network),
+			Gateway:     types.StringValue(network.Gateway),
+			DHCPEnabled: types.BoolValue(network.DHCPEnabled),
+			Enabled:     types.BoolValue(network.Enabled),
+		})
+	}
+	data.BridgedInterfaces = []VirtualboxBridgedIfModel{}
+	for _, bridgedIf := range bridgedInterfaces {
+		data.BridgedInterfaces = append(data.BridgedInterfaces, VirtualboxBridgedIfModel{
+			Name:        types.StringValue(bridgedIf.Name),
+			IPAddress:   types.StringValue(bridgedIf.IPAddress),
+			NetworkMask: types.StringValue(bridgedIf.NetworkMask),
+			Status:      types.StringValue(bridgedIf.Status),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,350 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxPortForwardingRuleResource{}
+var _ resource.ResourceWithImportState = &VirtualboxPortForwardingRuleResource{}
+var _ resource.ResourceWithModifyPlan = &VirtualboxPortForwardingRuleResource{}
+
+func NewVirtualboxPortForwardingRuleResource() resource.Resource {
+	return &VirtualboxPortForwardingRuleResource{}
+}
+
+// VirtualboxPortForwardingRuleResource defines the resource implementation.
+type VirtualboxPortForwardingRuleResource struct {
+	client *http.Client
+}
+
+// VirtualboxPortForwardingRuleResourceModel describes the resource data model.
+type VirtualboxPortForwardingRuleResourceModel struct {
+	Id        types.String `tfsdk:"id"`
+	VMId      types.String `tfsdk:"vm_id"`
+	Name      types.String `tfsdk:"name"`
+	Protocol  types.String `tfsdk:"protocol"`
+	HostIP    types.String `tfsdk:"host_ip"`
+	HostPort  types.Int64  `tfsdk:"host_port"`
+	GuestIP   types.String `tfsdk:"guest_ip"`
+	GuestPort types.Int64  `tfsdk:"guest_port"`
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_port_forwarding_rule"
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "NAT port forwarding rule of vm primary network adapter",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Rule identifier in `<vm_id>/<name>` format",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"vm_id": schema.StringAttribute{
+				MarkdownDescription: "Virtualbox vm id or name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Rule name, must be unique per vm",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					// natpf rule syntax uses commas as separators and colons for rule deletion
+					stringNoneOfChars(",:"),
+				},
+			},
+			"protocol": schema.StringAttribute{
+				MarkdownDescription: "Forwarded protocol, `tcp` or `udp`. `tcp` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("tcp"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf("tcp", "udp"),
+				},
+			},
+			"host_ip": schema.StringAttribute{
+				MarkdownDescription: "Host address to listen on, all addresses by default",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(""),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"host_port": schema.Int64Attribute{
+				MarkdownDescription: "Host port, must be unique among rules of all vms. Ports below 1024 require VirtualBox running as root.",
+				Required:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"guest_ip": schema.StringAttribute{
+				MarkdownDescription: "Guest address to forward to, guest address assigned by NAT DHCP by default",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(""),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"guest_port": schema.Int64Attribute{
+				MarkdownDescription: "Guest port",
+				Required:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+		},
+	}
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxPortForwardingRuleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan *VirtualboxPortForwardingRuleResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// ports computed from other resources are known only during apply
+	if plan.HostPort.IsUnknown() || plan.HostIP.IsUnknown() || plan.Protocol.IsUnknown() {
+		return
+	}
+
+	owner := ""
+	if !plan.VMId.IsUnknown() && !plan.Name.IsUnknown() {
+		owner = plan.VMId.ValueString() + "/" + plan.Name.ValueString()
+	}
+	conflicting, conflict := plannedHostPorts.claim(
+		plan.Protocol.ValueString(),
+		plan.HostIP.ValueString(),
+		plan.HostPort.ValueInt64(),
+		owner,
+	)
+	if conflict {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("host_port"),
+			"Host port is already in use",
+			fmt.Sprintf("%s port %d is already forwarded by %s", plan.Protocol.ValueString(), plan.HostPort.ValueInt64(), conflicting),
+		)
+		return
+	}
+
+	// rules are added to the first adapter, NAT forwarding does nothing for other network types.
+	// Vm created in the same apply isn't registered yet and is checked by VBoxManage
+	if !plan.VMId.IsUnknown() {
+		vminfo, err := virtualboxapi.GetVMInfo(ctx, plan.VMId.ValueString())
+		if err == nil {
+			if nic := vminfo.NetworkAdapter(1); nic == nil || nic.Attachment != "nat" {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("vm_id"),
+					"Port forwarding requires NAT adapter",
+					fmt.Sprintf("First network adapter of vm %s must be attached to NAT, port forwarding rules are added to it", plan.VMId.ValueString()),
+				)
+				return
+			}
+		}
+	}
+
+	if plan.HostPort.ValueInt64() < 1024 && !virtualboxapi.CanBindPrivilegedPorts() {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("host_port"),
+			"Privileged host port",
+			fmt.Sprintf("VirtualBox doesn't run as root and most likely won't be able to listen on port %d", plan.HostPort.ValueInt64()),
+		)
+	}
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_port_forwarding_rule Create")
+
+	var data *VirtualboxPortForwardingRuleResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := virtualboxapi.AddForwardingRule(ctx, data.VMId.ValueString(), virtualboxapi.PortForwardingRule{
+		Name:      data.Name.ValueString(),
+		Protocol:  data.Protocol.ValueString(),
+		HostIP:    data.HostIP.ValueString(),
+		HostPort:  strconv.FormatInt(data.HostPort.ValueInt64(), 10),
+		GuestIP:   data.GuestIP.ValueString(),
+		GuestPort: strconv.FormatInt(data.GuestPort.ValueInt64(), 10),
+	})
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating port forwarding rule", err)
+		return
+	}
+
+	// save into the Terraform state.
+	data.Id = types.StringValue(data.VMId.ValueString() + "/" + data.Name.ValueString())
+
+	tflog.Trace(ctx, "created a port forwarding rule")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_port_forwarding_rule Read")
+
+	var data *VirtualboxPortForwardingRuleResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vminfo, err := virtualboxapi.GetVMInfo(ctx, data.VMId.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting vm info", err)
+		return
+	}
+	rule := vminfo.Rule(data.Name.ValueString())
+	if rule == nil {
+		// rule was removed outside of Terraform
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	hostPort, err := strconv.ParseInt(rule.HostPort, 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError("Error parsing host port", err.Error())
+		return
+	}
+	guestPort, err := strconv.ParseInt(rule.GuestPort, 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError("Error parsing guest port", err.Error())
+		return
+	}
+	data.Protocol = types.StringValue(rule.Protocol)
+	data.HostIP = types.StringValue(rule.HostIP)
+	data.HostPort = types.Int64Value(hostPort)
+	data.GuestIP = types.StringValue(rule.GuestIP)
+	data.GuestPort = types.Int64Value(guestPort)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	defer logStats(ctx, "virtualbox_port_forwarding_rule Update")
+
+	var data *VirtualboxPortForwardingRuleResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// All attributes require replacement, nothing to do here
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxPortForwardingRuleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_port_forwarding_rule Delete")
+
+	var data *VirtualboxPortForwardingRuleResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := virtualboxapi.DeleteForwardingRule(ctx, data.VMId.ValueString(), data.Name.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		// vm or rule is already destroyed outside of Terraform
+		return
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error deleting port forwarding rule", err)
+		return
+	}
+}
+
+func (r *VirtualboxPortForwardingRuleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	vmID, name, found := strings.Cut(req.ID, "/")
+	if !found || vmID == "" || name == "" {
+		resp.Diagnostics.AddError(
+			"Unexpected Import Identifier",
+			fmt.Sprintf("Expected import identifier with format: <vm_id>/<name>. Got: %q", req.ID),
+		)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("vm_id"), vmID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+}
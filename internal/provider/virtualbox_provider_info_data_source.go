@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxProviderInfoDataSource{}
+
+func NewVirtualboxProviderInfoDataSource() datasource.DataSource {
+	return &VirtualboxProviderInfoDataSource{}
+}
+
+// VirtualboxProviderInfoDataSource defines the data source implementation.
+type VirtualboxProviderInfoDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxProviderInfoDataSourceModel describes the data source data model.
+type VirtualboxProviderInfoDataSourceModel struct {
+	Id                types.String `tfsdk:"id"`
+	VMCount           types.Int64  `tfsdk:"vm_count"`
+	VirtualboxVersion types.String `tfsdk:"virtualbox_version"`
+	APIVersion        types.String `tfsdk:"api_version"`
+}
+
+func (d *VirtualboxProviderInfoDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_provider_info"
+}
+
+func (d *VirtualboxProviderInfoDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Information about VirtualBox installation on provider host",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier, same as virtualbox_version",
+				Computed:            true,
+			},
+			"vm_count": schema.Int64Attribute{
+				MarkdownDescription: "Number of vms registered in VirtualBox",
+				Computed:            true,
+			},
+			"virtualbox_version": schema.StringAttribute{
+				MarkdownDescription: "VirtualBox version as reported by `VBoxManage --version`",
+				Computed:            true,
+			},
+			"api_version": schema.StringAttribute{
+				MarkdownDescription: "VirtualBox API version, e.g. `7_0`",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *VirtualboxProviderInfoDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxProviderInfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_provider_info Read")
+
+	var data VirtualboxProviderInfoDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vmCount, err := virtualboxapi.GetVMCount(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error counting vms", err)
+		return
+	}
+	version, err := virtualboxapi.GetVBoxVersion(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting VirtualBox version", err)
+		return
+	}
+	properties, err := virtualboxapi.GetSystemProperties(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error getting VirtualBox system properties", err)
+		return
+	}
+
+	data.Id = types.StringValue(version)
+	data.VMCount = types.Int64Value(int64(vmCount))
+	data.VirtualboxVersion = types.StringValue(version)
+	data.APIVersion = types.StringValue(properties["API version"])
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxSnapshotsDataSource{}
+
+func NewVirtualboxSnapshotsDataSource() datasource.DataSource {
+	return &VirtualboxSnapshotsDataSource{}
+}
+
+// VirtualboxSnapshotsDataSource defines the data source implementation.
+type VirtualboxSnapshotsDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxSnapshotsDataSourceModel describes the data source data model.
+type VirtualboxSnapshotsDataSourceModel struct {
+	Id        types.String              `tfsdk:"id"`
+	VMId      types.String              `tfsdk:"vm_id"`
+	Snapshots []VirtualboxSnapshotModel `tfsdk:"snapshots"`
+}
+
+// VirtualboxSnapshotModel describes vm snapshot.
+type VirtualboxSnapshotModel struct {
+	UUID        types.String `tfsdk:"uuid"`
+	Name        types.String `tfsdk:"name"`
+	Description types.String `tfsdk:"description"`
+	ParentUUID  types.String `tfsdk:"parent_uuid"`
+	IsCurrent   types.Bool   `tfsdk:"is_current"`
+}
+
+func (d *VirtualboxSnapshotsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_snapshots"
+}
+
+func (d *VirtualboxSnapshotsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Lists snapshots of vm. Snapshot tree is flattened, parents come before their children.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier, same as vm_id",
+				Computed:            true,
+			},
+			"vm_id": schema.StringAttribute{
+				MarkdownDescription: "Virtualbox vm id or name",
+				Required:            true,
+			},
+			"snapshots": schema.ListNestedAttribute{
+				MarkdownDescription: "Vm snapshots, empty when vm has none",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"uuid": schema.StringAttribute{
+							MarkdownDescription: "Snapshot UUID",
+							Computed:            true,
+						},
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Snapshot name",
+							Computed:            true,
+						},
+						"description": schema.StringAttribute{
+							MarkdownDescription: "Snapshot description",
+							Computed:            true,
+						},
+						"parent_uuid": schema.StringAttribute{
+							MarkdownDescription: "UUID of parent snapshot, empty for the root snapshot",
+							Computed:            true,
+						},
+						"is_current": schema.BoolAttribute{
+							MarkdownDescription: "Whether vm state is based on this snapshot",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *VirtualboxSnapshotsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxSnapshotsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_snapshots Read")
+
+	var data VirtualboxSnapshotsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	snapshots, err := virtualboxapi.ListSnapshots(ctx, data.VMId.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing vm snapshots", err)
+		return
+	}
+
+	data.Id = data.VMId
+	data.Snapshots = []VirtualboxSnapshotModel{}
+	for _, snapshot := range snapshots {
+		data.Snapshots = append(data.Snapshots, VirtualboxSnapshotModel{
+			UUID:        types.StringValue(snapshot.UUID),
+			Name:        types.StringValue(snapshot.Name),
+			Description: types.StringValue(snapshot.Description),
+			ParentUUID:  types.StringValue(snapshot.ParentUUID),
+			IsCurrent:   types.BoolValue(snapshot.IsCurrent),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &VirtualboxVMGroupResource{}
+var _ resource.ResourceWithImportState = &VirtualboxVMGroupResource{}
+
+func NewVirtualboxVMGroupResource() resource.Resource {
+	return &VirtualboxVMGroupResource{}
+}
+
+// VirtualboxVMGroupResource defines the resource implementation.
+type VirtualboxVMGroupResource struct {
+	client *http.Client
+}
+
+// VirtualboxVMGroupResourceModel describes the resource data model.
+type VirtualboxVMGroupResourceModel struct {
+	Id    types.String `tfsdk:"id"`
+	Path  types.String `tfsdk:"path"`
+	VMIds []string     `tfsdk:"vm_ids"`
+}
+
+func (r *VirtualboxVMGroupResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vm_group"
+}
+
+func (r *VirtualboxVMGroupResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "VirtualBox vm group, which organizes vms in VirtualBox Manager. Group exists while vms are assigned to it, " +
+			"other groups of these vms are kept. VirtualBox changes groups of powered off vms only.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Group identifier, same as path",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Group path, e.g. `/production/web`",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringIsGroupPath(),
+				},
+			},
+			"vm_ids": schema.ListAttribute{
+				MarkdownDescription: "Ids or names of vms assigned to group. Vms assigned to group outside of Terraform are added on refresh.",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+		},
+	}
+}
+
+func (r *VirtualboxVMGroupResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxVMGroupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_vm_group Create")
+
+	var data *VirtualboxVMGroupResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.CreateGroup(data.Path.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating vm group", err)
+		return
+	}
+	for _, vmID := range data.VMIds {
+		_, err = virtualboxapi.AddVMToGroup(ctx, vmID, data.Path.ValueString())
+		if err != nil {
+			addError(&resp.Diagnostics, "Error adding vm to group", err)
+			// vms which were already added are removed from group
+			_ = virtualboxapi.DeleteGroup(ctx, data.Path.ValueString())
+			return
+		}
+	}
+	data.Id = data.Path
+
+	tflog.Trace(ctx, "created a vm group")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxVMGroupResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_vm_group Read")
+
+	var data *VirtualboxVMGroupResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vms, err := virtualboxapi.GetGroupVMs(ctx, data.Path.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing vm group", err)
+		return
+	}
+	if len(vms) == 0 {
+		// group without vms doesn't exist in VirtualBox
+		tflog.Warn(ctx, "vm group has no vms, removing from state", map[string]interface{}{"path": data.Path.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	data.VMIds = groupVMIds(data.VMIds, vms)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxVMGroupResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	defer logStats(ctx, "virtualbox_vm_group Update")
+
+	var data, state *VirtualboxVMGroupResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planned := map[string]bool{}
+	for _, vmID := range data.VMIds {
+		planned[vmID] = true
+	}
+	for _, vmID := range state.VMIds {
+		if planned[vmID] {
+			continue
+		}
+		_, err := virtualboxapi.RemoveVMFromGroup(ctx, vmID, data.Path.ValueString())
+		if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+			addError(&resp.Diagnostics, "Error removing vm from group", err)
+			return
+		}
+	}
+	for _, vmID := range data.VMIds {
+		_, err := virtualboxapi.AddVMToGroup(ctx, vmID, data.Path.ValueString())
+		if err != nil {
+			addError(&resp.Diagnostics, "Error adding vm to group", err)
+			return
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxVMGroupResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_vm_group Delete")
+
+	var data *VirtualboxVMGroupResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := virtualboxapi.DeleteGroup(ctx, data.Path.ValueString())
+	if err != nil {
+		addError(&resp.Diagnostics, "Error deleting vm group", err)
+		return
+	}
+}
+
+func (r *VirtualboxVMGroupResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("path"), req.ID)...)
+}
+
+// groupVMIds keeps configured vm identifiers of group members, either ids or names,
+// members assigned outside of Terraform are appended by id
+func groupVMIds(configured []string, members []virtualboxapi.RegisteredVM) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, vmID := range configured {
+		for _, vm := range members {
+			if vmID == vm.ID || vmID == vm.Name {
+				result = append(result, vmID)
+				seen[vm.ID] = true
+				break
+			}
+		}
+	}
+	for _, vm := range members {
+		if !seen[vm.ID] {
+			result = append(result, vm.ID)
+		}
+	}
+	return result
+}
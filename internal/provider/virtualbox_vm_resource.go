@@ -6,21 +6,107 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
 	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
 )
 
+// maxNetworkAdapters is adapter count supported by every chipset and VirtualBox version
+const maxNetworkAdapters = 8
+
+// networkAdapterAttrTypes are attributes of network_adapter elements
+var networkAdapterAttrTypes = map[string]attr.Type{
+	"type":             types.StringType,
+	"network":          types.StringType,
+	"promiscuous_mode": types.StringType,
+	"cable_connected":  types.BoolType,
+	"ip_config":        types.ObjectType{AttrTypes: ipConfigAttrTypes},
+	"ip_address":       types.StringType,
+}
+
+// ipConfigAttrTypes are attributes of network_adapter ip_config
+var ipConfigAttrTypes = map[string]attr.Type{
+	"address": types.StringType,
+	"netmask": types.StringType,
+	"gateway": types.StringType,
+	"dns":     types.ListType{ElemType: types.StringType},
+}
+
+// Values of delete_behavior attribute
+const (
+	deleteBehaviorDelete       = "delete"
+	deleteBehaviorUnregister   = "unregister"
+	deleteBehaviorPoweroffOnly = "poweroff_only"
+)
+
+// Values of start_mode attribute
+const (
+	startModeStartVM = "startvm"
+	startModeDirect  = "direct"
+)
+
+// defaultSSHUser is user for which ssh key is injected by default
+const defaultSSHUser = "root"
+
+// Values of state attribute
+const (
+	vmStateRunning  = string(virtualboxapi.Running)
+	vmStatePoweroff = string(virtualboxapi.Poweroff)
+)
+
+// Values of disk_cache_mode attribute, VirtualBox only switches host I/O cache
+// of storage controller, so modes are mapped to it
+const (
+	diskCacheDefault      = "default"
+	diskCacheNone         = "none"
+	diskCacheWriteback    = "writeback"
+	diskCacheWritethrough = "writethrough"
+	diskCacheDirectSync   = "directsync"
+	diskCacheUnsafe       = "unsafe"
+)
+
+// vramPerMonitor is video memory in MB VirtualBox needs for each monitor
+const vramPerMonitor = 16
+
+// VirtualBox hard limits of vm settings, VBoxManage rejects values beyond them only on apply
+const (
+	minMemoryMB  = 4
+	maxMemoryMB  = 2097152
+	maxCPUs      = 64
+	maxPIIX3CPUs = 32
+)
+
+// lowMemoryRatio is fraction of appliance declared memory below which
+// configured memory is reported as too low
+const lowMemoryRatio = 0.5
+
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &VirtualboxVMResource{}
 var _ resource.ResourceWithImportState = &VirtualboxVMResource{}
+var _ resource.ResourceWithModifyPlan = &VirtualboxVMResource{}
+var _ resource.ResourceWithUpgradeState = &VirtualboxVMResource{}
+var _ resource.ResourceWithConfigValidators = &VirtualboxVMResource{}
 
 func NewVirtualboxVMResource() resource.Resource {
 	return &VirtualboxVMResource{}
@@ -33,14 +119,126 @@ type VirtualboxVMResource struct {
 
 // VirtualboxVMResourceModel describes the resource data model.
 type VirtualboxVMResourceModel struct {
-	Id      types.String `tfsdk:"id"`
-	Name    types.String `tfsdk:"name"`
-	Image   types.String `tfsdk:"image"`
-	SSHUser types.String `tfsdk:"ssh_user"`
-	SSHKey  types.String `tfsdk:"ssh_key"`
-	Cpu     types.Int64  `tfsdk:"cpu"`
-	Memory  types.Int64  `tfsdk:"memory"`
-	SSHPort types.String `tfsdk:"ssh_port"`
+	Id             types.String `tfsdk:"id"`
+	Name           types.String `tfsdk:"name"`
+	NamePrefix     types.String `tfsdk:"name_prefix"`
+	Image          types.String `tfsdk:"image"`
+	BaseDiskUUID   types.String `tfsdk:"base_disk_uuid"`
+	DiskController types.String `tfsdk:"disk_controller"`
+	SSHUser        types.String `tfsdk:"ssh_user"`
+	SSHKey         types.String `tfsdk:"ssh_key"`
+	SSHKeyRehash   types.Bool   `tfsdk:"ssh_key_rehash"`
+	OSDisk         types.String `tfsdk:"os_disk"`
+
+	GuestNetworkManager types.String `tfsdk:"guest_network_manager"`
+
+	Cpu          types.Int64  `tfsdk:"cpu"`
+	HotCPUs      types.Int64  `tfsdk:"hot_cpus"`
+	CPUProfile   types.String `tfsdk:"cpu_profile"`
+	Memory       types.Int64  `tfsdk:"memory"`
+	SSHPort      types.String `tfsdk:"ssh_port"`
+	FixedSSHPort types.Int64  `tfsdk:"fixed_ssh_port"`
+
+	State      types.String `tfsdk:"state"`
+	PowerState types.String `tfsdk:"power_state"`
+
+	SSHRuleName       types.String `tfsdk:"ssh_rule_name"`
+	GuestAdditionsISO types.String `tfsdk:"guest_additions_iso"`
+	UserData          types.String `tfsdk:"user_data"`
+	UserDataBase64    types.String `tfsdk:"user_data_base64"`
+	ImportExtraArgs   types.List   `tfsdk:"import_extra_args"`
+	AcceptOVAEULA     types.Bool   `tfsdk:"accept_ova_eula"`
+	DiskIDs           types.List   `tfsdk:"disk_ids"`
+	MachineFolder     types.String `tfsdk:"machine_folder"`
+	RestoreSnapshot   types.String `tfsdk:"restore_from_snapshot_on_start"`
+	DiskFormat        types.String `tfsdk:"disk_format"`
+	DiskCacheMode     types.String `tfsdk:"disk_cache_mode"`
+	ConfigFile        types.String `tfsdk:"config_file"`
+	MachineInfoRaw    types.String `tfsdk:"machine_readable_info_raw"`
+	DiskUsageMB       types.Int64  `tfsdk:"disk_usage_mb"`
+	DiskMissing       types.Bool   `tfsdk:"disk_missing"`
+
+	DetectedOSType types.String `tfsdk:"detected_os_type"`
+	DeclaredMemory types.Int64  `tfsdk:"declared_memory"`
+	DeclaredCPUs   types.Int64  `tfsdk:"declared_cpus"`
+
+	NetworkAdapters types.List `tfsdk:"network_adapter"`
+
+	VMStartTimeout types.Int64  `tfsdk:"vm_start_timeout"`
+	VMStopTimeout  types.Int64  `tfsdk:"vm_stop_timeout"`
+	DeleteBehavior types.String `tfsdk:"delete_behavior"`
+	StartMode      types.String `tfsdk:"start_mode"`
+
+	CompactDiskOnDestroy types.Bool `tfsdk:"compact_disk_on_destroy"`
+	CompactDiskOnStop    types.Bool `tfsdk:"compact_disk_on_stop"`
+
+	WaitForGuestAdditions types.Bool  `tfsdk:"wait_for_guest_additions"`
+	GuestAdditionsTimeout types.Int64 `tfsdk:"guest_additions_timeout"`
+
+	Chipset   types.String `tfsdk:"chipset"`
+	Firmware  types.String `tfsdk:"firmware"`
+	TPM       types.String `tfsdk:"tpm"`
+	RTCUseUTC types.Bool   `tfsdk:"rtc_use_utc"`
+	HPET      types.Bool   `tfsdk:"hpet"`
+	IOAPIC    types.Bool   `tfsdk:"ioapic"`
+
+	CPUHotplugEnabled types.Bool `tfsdk:"cpu_hotplug_enabled"`
+
+	MonitorCount types.Int64 `tfsdk:"monitor_count"`
+	VRAM         types.Int64 `tfsdk:"vram"`
+
+	ReadinessProbe *VirtualboxVMReadinessProbeModel `tfsdk:"readiness_probe"`
+	Teleport       *VirtualboxVMTeleportModel       `tfsdk:"teleport"`
+	VRDE           *VirtualboxVMVRDEModel           `tfsdk:"vrde"`
+	InstallFromISO *VirtualboxVMInstallFromISOModel `tfsdk:"install_from_iso"`
+}
+
+// VirtualboxVMReadinessProbeModel describes readiness probe data model.
+type VirtualboxVMReadinessProbeModel struct {
+	Type               types.String `tfsdk:"type"`
+	Port               types.Int64  `tfsdk:"port"`
+	Path               types.String `tfsdk:"path"`
+	ExpectedStatus     types.Int64  `tfsdk:"expected_status"`
+	TLS                types.Bool   `tfsdk:"tls"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+	Timeout            types.String `tfsdk:"timeout"`
+	Interval           types.String `tfsdk:"interval"`
+}
+
+// VirtualboxVMTeleportModel describes teleport data model.
+type VirtualboxVMTeleportModel struct {
+	Listen     types.Bool   `tfsdk:"listen"`
+	Port       types.Int64  `tfsdk:"port"`
+	TargetHost types.String `tfsdk:"target_host"`
+	TargetPort types.Int64  `tfsdk:"target_port"`
+	Password   types.String `tfsdk:"password"`
+}
+
+// VirtualboxVMVRDEModel describes remote display server data model.
+type VirtualboxVMVRDEModel struct {
+	Port            types.String `tfsdk:"port"`
+	MultiConnection types.Bool   `tfsdk:"multi_connection"`
+}
+
+// VirtualboxVMIPConfigModel describes static IPv4 configuration of guest interface of network adapter.
+type VirtualboxVMIPConfigModel struct {
+	Address types.String `tfsdk:"address"`
+	Netmask types.String `tfsdk:"netmask"`
+	Gateway types.String `tfsdk:"gateway"`
+	DNS     types.List   `tfsdk:"dns"`
+}
+
+// VirtualboxVMInstallFromISOModel describes unattended installation data model.
+type VirtualboxVMInstallFromISOModel struct {
+	ISOPath      types.String `tfsdk:"iso_path"`
+	Username     types.String `tfsdk:"username"`
+	Password     types.String `tfsdk:"password"`
+	Hostname     types.String `tfsdk:"hostname"`
+	Country      types.String `tfsdk:"country"`
+	Locale       types.String `tfsdk:"locale"`
+	TimeZone     types.String `tfsdk:"time_zone"`
+	Proxy        types.String `tfsdk:"proxy"`
+	AdditionsISO types.String `tfsdk:"additions_iso"`
 }
 
 func (r *VirtualboxVMResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -51,6 +249,7 @@ func (r *VirtualboxVMResource) Schema(ctx context.Context, req resource.SchemaRe
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "Virtualbox VM resource",
+		Version:             vmResourceSchemaVersion,
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
@@ -61,132 +260,1306 @@ func (r *VirtualboxVMResource) Schema(ctx context.Context, req resource.SchemaRe
 				},
 			},
 			"name": schema.StringAttribute{
-				MarkdownDescription: "Virtualbox vm name",
-				Optional:            false,
-				Required:            true,
+				MarkdownDescription: "Virtualbox vm name. Either `name` or `name_prefix` is required, generated name is set here when `name_prefix` is used",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name_prefix": schema.StringAttribute{
+				MarkdownDescription: "Prefix of generated vm name, random suffix is appended to it on create so that name isn't used by any registered vm. " +
+					"Changing it recreates vm",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"image": schema.StringAttribute{
-				MarkdownDescription: "Path or URL to virtualbox vm image",
-				Optional:            false,
-				Required:            true,
+				MarkdownDescription: "Path or URL to virtualbox vm image. Leading `~` is expanded, relative path is resolved against Terraform working directory. " +
+					"Either `image` or `base_disk_uuid` is required. `.vbox` settings file of existing vm is registered instead of imported, vm files are used in place " +
+					"and `name` must match vm name in the file. Set `delete_behavior = \"unregister\"` to keep its files on destroy. " +
+					"Local `.ova` and `.ovf` images are checked with import dry run during validation.",
+				Optional: true,
+			},
+			"base_disk_uuid": schema.StringAttribute{
+				MarkdownDescription: "UUID of disk registered in VirtualBox, vm is created from scratch with copy of this disk instead of importing `image`. " +
+					"Base disk itself is not modified.",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"disk_controller": schema.StringAttribute{
+				MarkdownDescription: "Type of storage controller disk copy of `base_disk_uuid` is attached to, `sata` or `nvme`. " +
+					"`nvme` requires VirtualBox 6.1 or later. Changing it recreates vm. `sata` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(string(virtualboxapi.DiskControllerSATA)),
+				Validators: []validator.String{
+					stringOneOf(string(virtualboxapi.DiskControllerSATA), string(virtualboxapi.DiskControllerNVMe)),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"ssh_user": schema.StringAttribute{
-				MarkdownDescription: "User for which ssh key will be injected. Root by default.",
+				MarkdownDescription: "User for which ssh key will be injected. `root` by default.",
 				Optional:            true,
-				Required:            false,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultSSHUser),
 			},
 			"ssh_key": schema.StringAttribute{
-				MarkdownDescription: "Path to public ssh key, will be inserted into authorized_keys of guest vm",
-				Optional:            true,
-				Required:            false,
+				MarkdownDescription: "Path to public ssh key, will be inserted into authorized_keys of guest vm. Resolved the same way as `image`. " +
+					"Changing it or `ssh_user` injects the key into existing vm, running vm is restarted.",
+				Optional: true,
+				Required: false,
+			},
+			"ssh_key_rehash": schema.BoolAttribute{
+				MarkdownDescription: "Whether ssh key file of existing vm is read on every plan. Missing file is an error when `true`, " +
+					"when `false` it's a warning and the key injected into vm is kept. Replacing vm always requires the file. `true` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(true),
+			},
+			"os_disk": schema.StringAttribute{
+				MarkdownDescription: "Path or file name of disk with guest operating system, ssh key is injected into it. " +
+					"Detected automatically if not set: the only disk, or the only one with operating system found by libguestfs inspection.",
+				Optional: true,
+			},
+			"guest_network_manager": schema.StringAttribute{
+				MarkdownDescription: "Format of `network_adapter` `ip_config` files written into guest: `netplan` (Ubuntu), `networkd` (systemd-networkd) " +
+					"or `ifcfg` (RHEL family network-scripts). `netplan` by default. Changing it recreates vm.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(string(virtualboxapi.Netplan)),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf(string(virtualboxapi.Netplan), string(virtualboxapi.Networkd), string(virtualboxapi.Ifcfg)),
+				},
 			},
 			"cpu": schema.Int64Attribute{
-				MarkdownDescription: "Virtualbox vm cpu count",
+				MarkdownDescription: fmt.Sprintf("Virtualbox vm cpu count, from 1 to %d. More than %d cpus require `chipset = \"ich9\"`.", maxCPUs, maxPIIX3CPUs),
 				Optional:            false,
 				Required:            true,
+				Validators: []validator.Int64{
+					int64Between(1, maxCPUs),
+				},
+			},
+			"cpu_hotplug_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether cpus could be plugged and unplugged on running vm, guest has to support it " +
+					"(e.g. Linux with `CONFIG_HOTPLUG_CPU`). `cpu` is maximum cpu count then. Changing it requires vm restart. `false` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"hot_cpus": schema.Int64Attribute{
+				MarkdownDescription: "Number of plugged cpus, up to `cpu`. Requires `cpu_hotplug_enabled`, " +
+					"changing it plugs or unplugs cpus without vm restart. All `cpu` cpus are plugged if not set.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64Between(1, maxCPUs),
+				},
+			},
+			"cpu_profile": schema.StringAttribute{
+				MarkdownDescription: "Cpu profile presented to guest: `host` or one of profiles listed by `VBoxManage list cpu-profiles`, " +
+					"e.g. to keep guest cpu features when vm moves between hosts. Changing it requires vm restart.",
+				Optional: true,
 			},
 			"memory": schema.Int64Attribute{
-				MarkdownDescription: "Virtualbox vm memory count (MB)",
+				MarkdownDescription: fmt.Sprintf("Virtualbox vm memory count (MB), from %d to %d", minMemoryMB, maxMemoryMB),
 				Optional:            false,
 				Required:            true,
+				Validators: []validator.Int64{
+					int64Between(minMemoryMB, maxMemoryMB),
+				},
 			},
 			"ssh_port": schema.StringAttribute{
 				MarkdownDescription: "Forwarded local port to guest ssh(22)",
 				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					// ModifyPlan marks it unknown when forwarding is going to change
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"fixed_ssh_port": schema.Int64Attribute{
+				MarkdownDescription: "Host port forwarded to guest ssh, so `ssh_port` stays the same when vm is recreated. " +
+					"Port must be free, vm creation fails otherwise. Free port of provider `ssh_port_range_start`-`ssh_port_range_end` range is used by default.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64Between(1, 65535),
+				},
+			},
+			"state": schema.StringAttribute{
+				MarkdownDescription: "Desired vm state, `running` or `poweroff`. Vm created with `poweroff` isn't started, " +
+					"ssh port is forwarded when vm is switched to `running`. State changed outside of Terraform is not reverted. `running` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(vmStateRunning),
+				Validators: []validator.String{
+					stringOneOf(vmStateRunning, vmStatePoweroff),
+				},
+			},
+			"power_state": schema.StringAttribute{
+				MarkdownDescription: "Vm state reported by VirtualBox, e.g. `running` or `poweroff`",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"ssh_rule_name": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Name of NAT rule used for ssh port forwarding. `%s` by default.", virtualboxapi.SshPortRuleName),
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(virtualboxapi.SshPortRuleName),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					// natpf rule syntax uses commas as separators and colons for rule deletion
+					stringNoneOfChars(",:"),
+				},
+			},
+			"import_extra_args": schema.ListAttribute{
+				MarkdownDescription: "Additional arguments passed to `VBoxManage import` as is, e.g. `[\"--vsys=0\", \"--eula=accept\"]`. " +
+					"This is an escape hatch for appliances which need special import options, `--vmname`, `--memory`, `--cpus` and `--basefolder` are managed by provider.",
+				ElementType: types.StringType,
+				Optional:    true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.List{
+					listNoneOfFlags("--vmname", "--memory", "--cpus", "--basefolder"),
+				},
+			},
+			"accept_ova_eula": schema.BoolAttribute{
+				MarkdownDescription: "Accept end-user license agreement of `image`, some vendor appliances can't be imported without it. " +
+					"Read the license with `VBoxManage import <image> --vsys 0 --eula show`. Used on vm creation only. `false` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"machine_folder": schema.StringAttribute{
+				MarkdownDescription: "Folder where vm directory is created, VirtualBox default machine folder is used if not set. " +
+					"Folder must exist and be writable. Changing it recreates vm.",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringIsWritableDir(),
+				},
+			},
+			"restore_from_snapshot_on_start": schema.StringAttribute{
+				MarkdownDescription: "Name of snapshot taken right after vm is created and configured. When set, vm is restored from it " +
+					"every time provider (re)starts vm, e.g. on `cpu` or `memory` change. Changed settings are applied on top of restored vm " +
+					"and saved into the snapshot. Changing it recreates vm.",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"disk_ids": schema.ListAttribute{
+				MarkdownDescription: "UUIDs of `virtualbox_disk` disks attached to vm. Vm manages only attachment, disks are detached " +
+					"before vm is destroyed and are kept. Changing it requires vm restart.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"disk_format": schema.StringAttribute{
+				MarkdownDescription: "Format of vm disk, `VDI`, `VMDK` or `VHD`. Imported disk is converted when its format differs, " +
+					"format embedded in image is kept if not set. Changing it recreates vm.",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf("VDI", "VMDK", "VHD"),
+				},
+			},
+			"disk_cache_mode": schema.StringAttribute{
+				MarkdownDescription: "Host caching of vm disk I/O. VirtualBox only switches host I/O cache of storage controller: " +
+					"`none` and `directsync` disable it, `writeback`, `writethrough` and `unsafe` enable it, " +
+					"`default` keeps controller setting as is. Changing it requires vm restart. `default` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(diskCacheDefault),
+				Validators: []validator.String{
+					stringOneOf(diskCacheDefault, diskCacheNone, diskCacheWriteback, diskCacheWritethrough, diskCacheDirectSync, diskCacheUnsafe),
+				},
+			},
+			"config_file": schema.StringAttribute{
+				MarkdownDescription: "Path to vm `.vbox` configuration file",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"disk_usage_mb": schema.Int64Attribute{
+				MarkdownDescription: "Total size of attached disk image files (MB), refreshed on every read. " +
+					"Null when image files aren't accessible to provider, e.g. with `run_as_user`.",
+				Computed: true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"disk_missing": schema.BoolAttribute{
+				MarkdownDescription: "Whether file of some attached disk image doesn't exist, e.g. it was deleted outside of VirtualBox. " +
+					"Refreshed on every read, which warns about missing files. Null when vm runs on `remote_host`.",
+				Computed: true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine_readable_info_raw": schema.StringAttribute{
+				MarkdownDescription: "Raw `VBoxManage showvminfo --machinereadable` output, escape hatch for settings not exposed by provider. " +
+					"Format is not stable and differs between VirtualBox versions.",
+				Computed:  true,
+				Sensitive: false,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"detected_os_type": schema.StringAttribute{
+				MarkdownDescription: "OS type suggested by appliance, read with `VBoxManage import -n` before import",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"declared_memory": schema.Int64Attribute{
+				MarkdownDescription: "Memory (MB) declared by appliance, `memory` overrides it",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"declared_cpus": schema.Int64Attribute{
+				MarkdownDescription: "Cpu count declared by appliance, `cpu` overrides it",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"network_adapter": schema.ListNestedAttribute{
+				MarkdownDescription: fmt.Sprintf("Additional network adapters, attached as adapters 2 to %d in list order. "+
+					"The first adapter is NAT used for ssh port forwarding. Network of existing adapter is changed on running vm, "+
+					"adding or removing adapter requires vm restart. Appliance adapters are kept if not set, set to `[]` to remove them.", maxNetworkAdapters),
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+				Validators: []validator.List{
+					listSizeAtMost(maxNetworkAdapters - 1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"type": schema.StringAttribute{
+							MarkdownDescription: "Attachment type, `hostonly`, `bridged`, `intnet`, `natnetwork`, `nat` or `none`. " +
+								"`none` leaves adapter slot empty, so following adapters keep their numbers.",
+							Required: true,
+							Validators: []validator.String{
+								stringOneOf("hostonly", "bridged", "intnet", "natnetwork", "nat", "none"),
+							},
+						},
+						"network": schema.StringAttribute{
+							MarkdownDescription: "Host-only or bridged host interface, e.g. `vboxnet0`, or name of internal or NAT network. " +
+								"Not used by `nat` and `none`.",
+							Optional: true,
+						},
+						"promiscuous_mode": schema.StringAttribute{
+							MarkdownDescription: "Promiscuous mode, `deny`, `allow-vms` or `allow-all`. " +
+								"Could be changed on running vm. Adapter setting is kept if not set.",
+							Optional: true,
+							Computed: true,
+							PlanModifiers: []planmodifier.String{
+								stringplanmodifier.UseStateForUnknown(),
+							},
+							Validators: []validator.String{
+								stringOneOf(virtualboxapi.PromiscDeny, virtualboxapi.PromiscAllowVMs, virtualboxapi.PromiscAllowAll),
+							},
+						},
+						"cable_connected": schema.BoolAttribute{
+							MarkdownDescription: "Whether network cable is connected, could be changed on running vm. " +
+								"Disconnected cable requires VirtualBox 6.0 or later. `true` by default.",
+							Optional: true,
+							Computed: true,
+							Default:  booldefault.StaticBool(true),
+						},
+						"ip_config": schema.SingleNestedAttribute{
+							MarkdownDescription: "Static IPv4 configuration of guest interface, written into guest disk together with `ssh_key` before the first boot, " +
+								"so guest on host-only network gets known address without cloud-init or Guest Additions. " +
+								"Guest interface is matched by adapter MAC address. Requires `ssh_key`. Changing it recreates vm.",
+							Optional: true,
+							PlanModifiers: []planmodifier.Object{
+								objectplanmodifier.RequiresReplace(),
+							},
+							Attributes: map[string]schema.Attribute{
+								"address": schema.StringAttribute{
+									MarkdownDescription: "IPv4 address, e.g. `192.168.56.10`",
+									Required:            true,
+								},
+								"netmask": schema.StringAttribute{
+									MarkdownDescription: "Dotted netmask, e.g. `255.255.255.0`",
+									Required:            true,
+								},
+								"gateway": schema.StringAttribute{
+									MarkdownDescription: "Default gateway, not set by default",
+									Optional:            true,
+								},
+								"dns": schema.ListAttribute{
+									MarkdownDescription: "DNS servers, not set by default",
+									ElementType:         types.StringType,
+									Optional:            true,
+								},
+							},
+						},
+						"ip_address": schema.StringAttribute{
+							MarkdownDescription: "IPv4 address of running vm reported by Guest Additions, guest interface is matched to adapter by MAC address. " +
+								"Null when vm is stopped or Guest Additions aren't running.",
+							Computed: true,
+							PlanModifiers: []planmodifier.String{
+								stringplanmodifier.UseStateForUnknown(),
+							},
+						},
+					},
+				},
+			},
+			"vm_start_timeout": schema.Int64Attribute{
+				MarkdownDescription: "How long to wait for vm to start, in seconds. `120` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(int64(virtualboxapi.DefaultStartTimeout / time.Second)),
+				Validators: []validator.Int64{
+					int64Between(1, 3599),
+				},
+			},
+			"vm_stop_timeout": schema.Int64Attribute{
+				MarkdownDescription: "How long to wait for vm to power off, in seconds. `60` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(int64(virtualboxapi.DefaultStopTimeout / time.Second)),
+				Validators: []validator.Int64{
+					int64Between(1, 3599),
+				},
+			},
+			"wait_for_guest_additions": schema.BoolAttribute{
+				MarkdownDescription: "Wait until Guest Additions are running in guest when vm is created, e.g. before using `guestcontrol`. " +
+					"Guest Additions have to be installed in guest. `false` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"guest_additions_timeout": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("How long to wait for Guest Additions, in seconds. `%d` by default.", int64(virtualboxapi.DefaultGuestAdditionsTimeout/time.Second)),
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(int64(virtualboxapi.DefaultGuestAdditionsTimeout / time.Second)),
+				Validators: []validator.Int64{
+					int64Between(1, 3599),
+				},
+			},
+			"delete_behavior": schema.StringAttribute{
+				MarkdownDescription: "What happens with vm on destroy: `delete` unregisters vm and deletes its files, " +
+					"`unregister` unregisters vm leaving files on disk, `poweroff_only` powers vm off and keeps it registered. " +
+					"Vm is removed from Terraform state in all cases. `delete` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(deleteBehaviorDelete),
+				Validators: []validator.String{
+					stringOneOf(deleteBehaviorDelete, deleteBehaviorUnregister, deleteBehaviorPoweroffOnly),
+				},
+			},
+			"compact_disk_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Compact vm disks before vm is destroyed, so that files kept by `delete_behavior = \"unregister\"` or `\"poweroff_only\"` " +
+					"don't hold space freed inside guest. Guest has to zero free space for it to be reclaimed. `false` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"compact_disk_on_stop": schema.BoolAttribute{
+				MarkdownDescription: "Compact vm disks after vm is stopped by changing `state` to `poweroff`. `false` by default.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"start_mode": schema.StringAttribute{
+				MarkdownDescription: "How vm is started: `startvm` uses `VBoxManage startvm --type=headless`, " +
+					"`direct` launches detached `VBoxHeadless` process, which is an escape hatch for hosts where startvm fails because of desktop session issues. " +
+					"`startvm` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(startModeStartVM),
+				Validators: []validator.String{
+					stringOneOf(startModeStartVM, startModeDirect),
+				},
+			},
+			"chipset": schema.StringAttribute{
+				MarkdownDescription: "Emulated chipset, `piix3` or `ich9`. Kept as declared by appliance when not set. " +
+					"`ich9` is required for more than 32 PCI slots and is recommended for Windows 8 and newer guests. " +
+					"Changing it recreates vm, as guest installed for one chipset usually doesn't boot on another.",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf("piix3", "ich9"),
+				},
+			},
+			"firmware": schema.StringAttribute{
+				MarkdownDescription: "Vm firmware, `bios` or `efi`. Kept as declared by appliance when not set. " +
+					"Changing it recreates vm, as guest installed for one firmware usually doesn't boot with another.",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOf("bios", "efi"),
+				},
+			},
+			"tpm": schema.StringAttribute{
+				MarkdownDescription: "Emulated TPM version, `none`, `1.2` or `2.0`. Windows 11 guests require `2.0`. " +
+					"Requires VirtualBox 7.0 or later and `firmware = \"efi\"`. Applied before first boot, changing it requires vm restart. `none` by default.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString("none"),
+				Validators: []validator.String{
+					stringOneOf("none", "1.2", "2.0"),
+				},
+			},
+			"rtc_use_utc": schema.BoolAttribute{
+				MarkdownDescription: "Whether real-time clock is in UTC, most of non-Windows guests expect it. Changing it requires vm restart.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"hpet": schema.BoolAttribute{
+				MarkdownDescription: "Whether High Precision Event Timer is enabled. Changing it requires vm restart.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"ioapic": schema.BoolAttribute{
+				MarkdownDescription: "Whether I/O APIC is enabled. Guests use only one cpu without it, 64-bit Windows guests don't boot without it. " +
+					"Kept as declared by appliance when not set. Changing it requires vm restart.",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"monitor_count": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Number of virtual monitors, from 1 to 8. Each monitor needs %d MB of `vram`. Changing it requires vm restart. `1` by default.", vramPerMonitor),
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(1),
+				Validators: []validator.Int64{
+					int64Between(1, 8),
+				},
+			},
+			"vram": schema.Int64Attribute{
+				MarkdownDescription: "Video memory (MB), from 0 to 256. `0` leaves vm without video memory, e.g. for headless servers. Changing it requires vm restart.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+				Validators: []validator.Int64{
+					int64Between(0, 256),
+				},
+			},
+			"readiness_probe": schema.SingleNestedAttribute{
+				MarkdownDescription: "Probe which has to succeed before vm creation is considered complete. " +
+					"Probe is executed against forwarded host port, temporary NAT rule is created if guest port isn't forwarded.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"type": schema.StringAttribute{
+						MarkdownDescription: "Probe type, `tcp` or `http`",
+						Required:            true,
+						Validators: []validator.String{
+							stringOneOf(string(virtualboxapi.TCPProbe), string(virtualboxapi.HTTPProbe)),
+						},
+					},
+					"port": schema.Int64Attribute{
+						MarkdownDescription: "Guest port to probe",
+						Required:            true,
+					},
+					"path": schema.StringAttribute{
+						MarkdownDescription: "Path requested by http probe. `/` by default.",
+						Optional:            true,
+						Computed:            true,
+						Default:             stringdefault.StaticString("/"),
+					},
+					"expected_status": schema.Int64Attribute{
+						MarkdownDescription: "Status code expected from http probe. `200` by default.",
+						Optional:            true,
+						Computed:            true,
+						Default:             int64default.StaticInt64(200),
+					},
+					"tls": schema.BoolAttribute{
+						MarkdownDescription: "Use https for http probe",
+						Optional:            true,
+						Computed:            true,
+						Default:             booldefault.StaticBool(false),
+					},
+					"insecure_skip_verify": schema.BoolAttribute{
+						MarkdownDescription: "Skip certificate verification for https probe, useful for self-signed appliance certificates",
+						Optional:            true,
+						Computed:            true,
+						Default:             booldefault.StaticBool(false),
+					},
+					"timeout": schema.StringAttribute{
+						MarkdownDescription: "How long to wait for probe to succeed. `5m` by default.",
+						Optional:            true,
+						Computed:            true,
+						Default:             stringdefault.StaticString("5m"),
+						Validators: []validator.String{
+							stringIsDuration(),
+						},
+					},
+					"interval": schema.StringAttribute{
+						MarkdownDescription: "Interval between probe attempts. `5s` by default.",
+						Optional:            true,
+						Computed:            true,
+						Default:             stringdefault.StaticString("5s"),
+						Validators: []validator.String{
+							stringIsDuration(),
+						},
+					},
+				},
+			},
+			"teleport": schema.SingleNestedAttribute{
+				MarkdownDescription: "Experimental, requires `enable_experimental` provider attribute. Moves running vm between hosts: " +
+					"vm with `listen` waits for incoming teleport when started, setting `target_host` teleports running vm " +
+					"to such vm once. Teleported vm is powered off, its `power_state` becomes `poweroff` and it is kept " +
+					"in state, set `state = \"poweroff\"` to keep it stopped.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"listen": schema.BoolAttribute{
+						MarkdownDescription: "Wait for incoming teleport on `port` when vm is started. Changing it requires vm restart.",
+						Optional:            true,
+					},
+					"port": schema.Int64Attribute{
+						MarkdownDescription: "Port to listen on for incoming teleport",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64Between(1, 65535),
+						},
+					},
+					"target_host": schema.StringAttribute{
+						MarkdownDescription: "Host to teleport running vm to, teleport is started when it or `target_port` changes",
+						Optional:            true,
+					},
+					"target_port": schema.Int64Attribute{
+						MarkdownDescription: "Port teleport target listens on",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64Between(1, 65535),
+						},
+					},
+					"password": schema.StringAttribute{
+						MarkdownDescription: "Teleport password, used both for listening and for outgoing teleport",
+						Optional:            true,
+						Sensitive:           true,
+					},
+				},
+			},
+			"vrde": schema.SingleNestedAttribute{
+				MarkdownDescription: "Enables VirtualBox Remote Desktop server, removing it disables the server. " +
+					"Changing it requires vm restart.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"port": schema.StringAttribute{
+						MarkdownDescription: "Port or port range server listens on, e.g. `5000-5050`. Kept as configured in vm when not set.",
+						Optional:            true,
+					},
+					"multi_connection": schema.BoolAttribute{
+						MarkdownDescription: "Whether several clients can connect at once, needed for multi-screen RDP with `monitor_count` above 1. " +
+							"Kept as configured in vm when not set.",
+						Optional: true,
+					},
+				},
+			},
+			"install_from_iso": schema.SingleNestedAttribute{
+				MarkdownDescription: "Installs guest OS from ISO with `VBoxManage unattended install` on the first start, requires VirtualBox 6.1 or later. " +
+					"Use with `base_disk_uuid` of empty disk, e.g. `virtualbox_disk`, guest is installed on its copy. `ssh_key` can't be injected into empty disk. " +
+					"Changing it recreates vm.",
+				Optional: true,
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.RequiresReplace(),
+				},
+				Attributes: map[string]schema.Attribute{
+					"iso_path": schema.StringAttribute{
+						MarkdownDescription: "Path to installation ISO, resolved the same way as `image`",
+						Required:            true,
+					},
+					"username": schema.StringAttribute{
+						MarkdownDescription: "Name of user created by installation, `vboxuser` by default",
+						Optional:            true,
+					},
+					"password": schema.StringAttribute{
+						MarkdownDescription: "Password of created user and root, `changeme` by default",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"hostname": schema.StringAttribute{
+						MarkdownDescription: "Fully qualified guest host name, e.g. `vm1.example.com`",
+						Optional:            true,
+					},
+					"country": schema.StringAttribute{
+						MarkdownDescription: "Two-letter country code, e.g. `US`",
+						Optional:            true,
+					},
+					"locale": schema.StringAttribute{
+						MarkdownDescription: "Guest locale, e.g. `en_US`",
+						Optional:            true,
+					},
+					"time_zone": schema.StringAttribute{
+						MarkdownDescription: "Guest time zone, e.g. `UTC` or `Europe/Berlin`",
+						Optional:            true,
+					},
+					"proxy": schema.StringAttribute{
+						MarkdownDescription: "HTTP proxy used by installer, e.g. `http://proxy.example.com:3128`",
+						Optional:            true,
+					},
+					"additions_iso": schema.StringAttribute{
+						MarkdownDescription: "Path to Guest Additions ISO, Guest Additions are installed with guest OS when it is set",
+						Optional:            true,
+					},
+				},
+			},
+			"guest_additions_iso": schema.StringAttribute{
+				MarkdownDescription: "Path to Guest Additions ISO which will be attached to vm optical drive, resolved the same way as `image`. Use `auto` to detect ISO shipped with VirtualBox: path reported by `VBoxManage list systemproperties`, then standard install locations on Linux, macOS and Windows.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"user_data": schema.StringAttribute{
+				MarkdownDescription: "User data readable by guest from `/terraform/user-data` guest property, e.g. for cloud-init. " +
+					"Guest OS needs Guest Additions and an agent or cloud-init datasource configured to read it from VirtualBox guest properties, " +
+					"VirtualBox doesn't pass it to guest by itself. Value is passed on VBoxManage command line and is stored in vm settings unencrypted. " +
+					"Changing it updates property without vm restart, guest sees new value on next boot.",
+				Optional:  true,
+				Sensitive: true,
+			},
+			"user_data_base64": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded binary user data, conflicts with `user_data`. It's stored in `/terraform/user-data` guest property as is, " +
+					"`/terraform/user-data-encoding` property is set to `base64` so that guest knows to decode it.",
+				Optional:  true,
+				Sensitive: true,
+				Validators: []validator.String{
+					stringIsBase64(),
+				},
 			},
 		},
 	}
 }
 
-func (r *VirtualboxVMResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
+func (r *VirtualboxVMResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		sshUserValidator{},
+		exactlyOneOf("image", "base_disk_uuid"),
+		exactlyOneOf("name", "name_prefix"),
+		atMostOneOf("user_data", "user_data_base64"),
+		installFromISOValidator{},
+		cpuChipsetValidator{},
+		cpuIOAPICValidator{},
+		tpmFirmwareValidator{},
+		applianceValidator{},
+		ipConfigValidator{},
+		networkAdapterValidator{},
 	}
+}
 
-	client, ok := req.ProviderData.(*http.Client)
-
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
-
+func (r *VirtualboxVMResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
 		return
 	}
 
-	r.client = client
-}
-
-func (r *VirtualboxVMResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *VirtualboxVMResourceModel
+	var plan *VirtualboxVMResourceModel
 
-	// Read Terraform plan data into the model
-	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	vmInfo, err := virtualboxapi.CreateVM(
-		data.Image.ValueString(),
-		data.Name.ValueString(),
-		data.Memory.ValueInt64(),
-		data.Cpu.ValueInt64(),
-	)
-	if err != nil {
-		resp.Diagnostics.AddError("Error creating new vm", err.Error())
-		err = virtualboxapi.DestroyVM(data.Name.ValueString())
-		resp.Diagnostics.AddError("Error destroying vm", err.Error())
-		return
-	}
-
-	if !data.SSHKey.IsNull() {
-		vmInfo, err = virtualboxapi.ForwardLocalPort(vmInfo.ID, 22)
-		if err != nil {
-			resp.Diagnostics.AddError("Error forwarding local port", err.Error())
-			err = virtualboxapi.DestroyVM(data.Name.ValueString())
-			resp.Diagnostics.AddError("Error destroying vm", err.Error())
+	if !plan.HotCPUs.IsNull() && !plan.HotCPUs.IsUnknown() {
+		if !plan.CPUHotplugEnabled.IsUnknown() && !plan.CPUHotplugEnabled.ValueBool() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("hot_cpus"),
+				"Cpu hotplug is disabled",
+				"hot_cpus requires cpu_hotplug_enabled = true",
+			)
 			return
 		}
-		sshUser := "root"
-		if !data.SSHUser.IsNull() {
-			sshUser = data.SSHUser.ValueString()
-		}
-		err = virtualboxapi.InjectSSHKey(vmInfo.ID, sshUser, data.SSHKey.ValueString())
-		if err != nil {
-			resp.Diagnostics.AddError("Error injecting ssh key", err.Error())
-			err = virtualboxapi.DestroyVM(data.Name.ValueString())
-			resp.Diagnostics.AddError("Error destroying vm", err.Error())
+		if !plan.Cpu.IsUnknown() && plan.HotCPUs.ValueInt64() > plan.Cpu.ValueInt64() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("hot_cpus"),
+				"Too many hot cpus",
+				fmt.Sprintf("hot_cpus can't exceed cpu (%d), got: %d", plan.Cpu.ValueInt64(), plan.HotCPUs.ValueInt64()),
+			)
 			return
 		}
 	}
 
-	vmInfo, err = virtualboxapi.StartVM(
-		vmInfo.ID,
-		virtualboxapi.Headless, // TODO: add to schema, with default = headless
-	)
-	if err != nil {
-		resp.Diagnostics.AddError("Error starting new vm", err.Error())
-		err = virtualboxapi.DestroyVM(data.Name.ValueString())
-		resp.Diagnostics.AddError("Error destroying vm", err.Error())
-		return
+	// vram is either configured or known from state, it's unknown only on create without vram.
+	// Vm without video memory has no display, so monitor_count doesn't apply to it
+	if !plan.VRAM.IsUnknown() && !plan.VRAM.IsNull() && plan.VRAM.ValueInt64() > 0 && !plan.MonitorCount.IsUnknown() {
+		required := plan.MonitorCount.ValueInt64() * vramPerMonitor
+		if plan.VRAM.ValueInt64() < required {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("vram"),
+				"Insufficient video memory",
+				fmt.Sprintf("%d monitors require at least %d MB of video memory, got: %d MB", plan.MonitorCount.ValueInt64(), required, plan.VRAM.ValueInt64()),
+			)
+			return
+		}
 	}
 
-	// save into the Terraform state.
-	data.Id = types.StringValue(vmInfo.ID)
-	data.SSHPort = types.StringValue(vmInfo.SSHPort)
+	if plan.Teleport != nil {
+		validateTeleport(plan.Teleport, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
 
-	// Write logs using the tflog package
-	// Documentation: https://terraform.io/plugin/log
-	tflog.Trace(ctx, "created a resource")
+	var state *VirtualboxVMResourceModel
 
-	// Save data into Terraform state
-	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
-}
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 
-func (r *VirtualboxVMResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	var data *VirtualboxVMResourceModel
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var prior VirtualboxVMResourceModel
+	if state != nil {
+		prior = *state
+	}
+	if state == nil && virtualboxapi.IsVBoxFile(plan.Image.ValueString()) && plan.DeleteBehavior.ValueString() == deleteBehaviorDelete {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("delete_behavior"),
+			"Registered vm files are deleted on destroy",
+			"Vm registered from .vbox file uses its files in place, they are deleted when vm is destroyed. "+
+				"Set delete_behavior = \"unregister\" to keep them.",
+		)
+	}
+	if state == nil && virtualboxapi.IsVBoxFile(plan.Image.ValueString()) && !plan.NamePrefix.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name_prefix"),
+			"Name prefix can't be used with .vbox image",
+			"Vm registered from .vbox file keeps its name from the file, set `name` to that name instead.",
+		)
+	}
+	if virtualboxapi.IsRemote() {
+		// key is injected by local virt-sysprep, probe connects to local forwarded port
+		if !plan.SSHKey.IsNull() {
+			addRemoteUnsupportedError(&resp.Diagnostics, "ssh_key")
+		}
+		if plan.ReadinessProbe != nil {
+			addRemoteUnsupportedError(&resp.Diagnostics, "readiness_probe")
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	checkLocalFile(ctx, path.Root("image"), plan.Image, prior.Image, &resp.Diagnostics)
+	checkCPUProfile(ctx, plan.CPUProfile, prior.CPUProfile, &resp.Diagnostics)
+	if tpmRequested(plan.TPM) && !plan.TPM.Equal(prior.TPM) {
+		requireVersion(ctx, &resp.Diagnostics, "tpm", virtualboxapi.RequiredVersion(virtualboxapi.OptionTPMType))
+	}
+	if nvmeRequested(plan.DiskController) && !plan.DiskController.Equal(prior.DiskController) {
+		requireVersion(ctx, &resp.Diagnostics, "disk_controller", virtualboxapi.NVMeVersion)
+	}
+	checkLocalFile(ctx, path.Root("ssh_key"), plan.SSHKey, prior.SSHKey, &resp.Diagnostics)
+	if state != nil && !virtualboxapi.IsRemote() {
+		checkSSHKeyFile(ctx, plan, state, req.Private, len(resp.RequiresReplace) > 0, &resp.Diagnostics)
+	}
+	if plan.GuestAdditionsISO.ValueString() != "auto" {
+		checkLocalFile(ctx, path.Root("guest_additions_iso"), plan.GuestAdditionsISO, prior.GuestAdditionsISO, &resp.Diagnostics)
+	}
+	if plan.InstallFromISO != nil {
+		priorInstall := VirtualboxVMInstallFromISOModel{}
+		if prior.InstallFromISO != nil {
+			priorInstall = *prior.InstallFromISO
+		}
+		checkLocalFile(ctx, path.Root("install_from_iso").AtName("iso_path"), plan.InstallFromISO.ISOPath, priorInstall.ISOPath, &resp.Diagnostics)
+		checkLocalFile(ctx, path.Root("install_from_iso").AtName("additions_iso"), plan.InstallFromISO.AdditionsISO, priorInstall.AdditionsISO, &resp.Diagnostics)
+	}
+
+	// Nothing else to do on create
+	if state == nil || resp.Diagnostics.HasError() {
+		return
+	}
+
+	if sshPortReplanned(plan, state) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ssh_port"), types.StringUnknown())...)
+	}
+	if !plan.State.Equal(state.State) || teleportRequested(plan, state) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("power_state"), types.StringUnknown())...)
+	}
+	// Values read from running vm are kept from state only while nothing changes,
+	// Update reads them again
+	if !req.Plan.Raw.Equal(req.State.Raw) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("disk_usage_mb"), types.Int64Unknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("disk_missing"), types.BoolUnknown())...)
+		if !plan.NetworkAdapters.IsUnknown() {
+			for i := range plan.NetworkAdapters.Elements() {
+				resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("network_adapter").AtListIndex(i).AtName("ip_address"), types.StringUnknown())...)
+			}
+		}
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("machine_readable_info_raw"), types.StringUnknown())...)
+	}
+}
+
+func (r *VirtualboxVMResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *VirtualboxVMResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	defer logStats(ctx, "virtualbox_vm Create")
+
+	var data *VirtualboxVMResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// version specific settings are checked before anything is created
+	for _, adapter := range networkAdapters(data.NetworkAdapters) {
+		if adapter.Type != "none" && !adapter.CableConnected {
+			requireVersion(ctx, &resp.Diagnostics, "network_adapter", virtualboxapi.RequiredVersion(virtualboxapi.OptionCableConnected))
+			break
+		}
+	}
+	if tpmRequested(data.TPM) {
+		requireVersion(ctx, &resp.Diagnostics, "tpm", virtualboxapi.RequiredVersion(virtualboxapi.OptionTPMType))
+	}
+	if nvmeRequested(data.DiskController) {
+		requireVersion(ctx, &resp.Diagnostics, "disk_controller", virtualboxapi.NVMeVersion)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var importExtraArgs []string
+	resp.Diagnostics.Append(data.ImportExtraArgs.ElementsAs(ctx, &importExtraArgs, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	imagePath := ""
+	if !data.Image.IsNull() {
+		imagePath = resolveAttributePath(ctx, path.Root("image"), data.Image, &resp.Diagnostics)
+	}
+	sshKeyPath := ""
+	if !data.SSHKey.IsNull() {
+		sshKeyPath = resolveAttributePath(ctx, path.Root("ssh_key"), data.SSHKey, &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// vm created from base disk or registered from settings file has no appliance metadata
+	registering := virtualboxapi.IsVBoxFile(imagePath)
+	applianceInfo := &virtualboxapi.ApplianceInfo{}
+	if imagePath != "" && !registering {
+		var err error
+		applianceInfo, err = virtualboxapi.GetApplianceInfo(ctx, imagePath)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error reading appliance", err)
+			return
+		}
+	}
+	if applianceInfo.Platform() == virtualboxapi.PlatformX86 {
+		hostPlatform, err := virtualboxapi.GetHostPlatform(ctx)
+		if err != nil {
+			tflog.Warn(ctx, "unable to detect host platform", map[string]interface{}{"error": err.Error()})
+		} else if err = virtualboxapi.CheckPlatformSupport(hostPlatform, virtualboxapi.OptionX86Guests); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("image"),
+				"Appliance can't run on this host",
+				fmt.Sprintf("Image %s declares x86 guest OS type %q, but VirtualBox on this host runs %s guests only: %s. "+
+					"Use image built for %s guests.", imagePath, applianceInfo.OSType, hostPlatform, err, hostPlatform),
+			)
+			return
+		}
+	}
+	if applianceInfo.EULARequired && !eulaAccepted(data, importExtraArgs) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("accept_ova_eula"),
+			"Appliance requires license agreement",
+			fmt.Sprintf("Image %s can't be imported until its end-user license agreement is accepted. "+
+				"Read it with `VBoxManage import %s --vsys 0 --eula show` and set accept_ova_eula = true to accept it.", imagePath, imagePath),
+		)
+		return
+	}
+	updateModelFromApplianceInfo(data, applianceInfo)
+	if float64(data.Memory.ValueInt64()) < float64(applianceInfo.Memory)*lowMemoryRatio {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("memory"),
+			"Memory is far below appliance declared memory",
+			fmt.Sprintf("Appliance declares %d MB of memory, but only %d MB is configured, guest may fail to boot.",
+				applianceInfo.Memory, data.Memory.ValueInt64()),
+		)
+	}
+
+	// name is generated as late as possible, so that vms created in parallel see each other
+	if !data.NamePrefix.IsNull() {
+		name, err := virtualboxapi.UniqueVMName(ctx, data.NamePrefix.ValueString())
+		if err != nil {
+			addError(&resp.Diagnostics, "Error generating vm name", err)
+			return
+		}
+		data.Name = types.StringValue(name)
+	}
+	if !registering {
+		resp.Diagnostics.Append(cleanupOrphanedArtifacts(ctx, data)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var vmInfo *virtualboxapi.VirtualboxVMInfo
+	var err error
+	if registering {
+		vmInfo, err = registerVMFromFile(ctx, imagePath, data)
+	} else if !data.BaseDiskUUID.IsNull() {
+		vmInfo, err = virtualboxapi.CreateVMFromDisk(
+			ctx,
+			data.BaseDiskUUID.ValueString(),
+			data.Name.ValueString(),
+			data.MachineFolder.ValueString(),
+			data.Memory.ValueInt64(),
+			data.Cpu.ValueInt64(),
+			virtualboxapi.DiskController(data.DiskController.ValueString()),
+		)
+	} else {
+		vmInfo, err = virtualboxapi.CreateVM(
+			ctx,
+			imagePath,
+			data.Name.ValueString(),
+			data.MachineFolder.ValueString(),
+			data.Memory.ValueInt64(),
+			data.Cpu.ValueInt64(),
+			data.AcceptOVAEULA.ValueBool(),
+			importExtraArgs,
+		)
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error creating new vm", err)
+		// registerVMFromFile cleans up after itself
+		if !registering {
+			destroyFailedVM(ctx, data.Name.ValueString(), vmStateTimeouts(data).Stop, &resp.Diagnostics)
+		}
+		return
+	}
+	// vm may be renamed outside of Terraform while it is being created,
+	// so the rest of Create and cleanup address it by UUID
+	vmID := vmInfo.ID
+	// files of registered vm are used in place, failed Create only unregisters it
+	destroyFailed, destroyFailedKeepingDisks := destroyFailedVM, destroyFailedVMKeepingDisks
+	if registering {
+		destroyFailed = unregisterFailedVM
+		destroyFailedKeepingDisks = func(ctx context.Context, vmName string, diskIDs []string, stopTimeout time.Duration, diags *diag.Diagnostics) {
+			unregisterFailedVM(ctx, vmName, stopTimeout, diags)
+		}
+	}
+
+	err = virtualboxapi.MarkManaged(ctx, vmID)
+	if err == nil && !registering {
+		// vm left by interrupted create is cleaned up by the next one, see cleanupOrphanedArtifacts
+		err = virtualboxapi.SetExtraData(ctx, vmID, virtualboxapi.CreatingMarkerKey, "true")
+	}
+	if err != nil {
+		addError(&resp.Diagnostics, "Error marking vm as managed by Terraform", err)
+		destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+		return
+	}
+
+	if !data.DiskFormat.IsNull() {
+		vmInfo, err = virtualboxapi.ConvertVMDisk(ctx, vmID, data.DiskFormat.ValueString())
+		if err != nil {
+			addError(&resp.Diagnostics, "Error converting vm disk", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if enabled, ok := hostIOCache(data.DiskCacheMode.ValueString()); ok {
+		vmInfo, err = virtualboxapi.SetHostIOCache(ctx, vmID, enabled, vmBootType(data), vmStateTimeouts(data))
+		if err != nil {
+			addError(&resp.Diagnostics, "Error changing disk cache mode", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if !data.GuestAdditionsISO.IsNull() {
+		isoPath := data.GuestAdditionsISO.ValueString()
+		if isoPath == "auto" {
+			isoPath, err = virtualboxapi.GetGuestAdditionsISOPath(ctx)
+			if err != nil {
+				addError(&resp.Diagnostics, "Error detecting guest additions iso", err)
+				destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+				return
+			}
+		} else {
+			isoPath = resolveAttributePath(ctx, path.Root("guest_additions_iso"), data.GuestAdditionsISO, &resp.Diagnostics)
+			if resp.Diagnostics.HasError() {
+				destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+				return
+			}
+		}
+		vmInfo, err = virtualboxapi.AttachDVD(ctx, vmID, isoPath)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error attaching guest additions iso", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if data.InstallFromISO != nil {
+		if !requireVersion(ctx, &resp.Diagnostics, "install_from_iso", virtualboxapi.Version{Major: 6, Minor: 1}) {
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+		opts := unattendedOptions(ctx, data.InstallFromISO, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+		err = virtualboxapi.UnattendedInstall(ctx, vmID, opts)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error preparing unattended installation", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if userData, encoding := vmUserData(data); userData != "" {
+		err = virtualboxapi.SetUserData(ctx, vmID, userData, encoding)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error setting user data", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	// adapters are added before ssh key injection, ip_config matches guest interfaces by their MAC addresses
+	if args := offlineModifyArgs(ctx, data, nil); len(args) > 0 {
+		vmInfo, err = virtualboxapi.ModifyVM(ctx, vmID, args...)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error modifying vm", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	startVM := data.State.ValueString() != vmStatePoweroff
+
+	if !data.SSHKey.IsNull() {
+		// stopped vm gets ssh port on first start, see startStoppedVM
+		if startVM {
+			vmInfo, err = virtualboxapi.ForwardLocalPort(ctx, vmID, data.SSHRuleName.ValueString(), int(data.FixedSSHPort.ValueInt64()), 22)
+			if err != nil {
+				addError(&resp.Diagnostics, "Error forwarding local port", err)
+				destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+				return
+			}
+		}
+		var networkArgs []string
+		networkArgs, err = guestNetworkArgs(ctx, data, vmInfo)
+		if err == nil {
+			err = virtualboxapi.InjectSSHKey(ctx, vmID, data.OSDisk.ValueString(), data.SSHUser.ValueString(), sshKeyPath, networkArgs)
+		}
+		if err != nil {
+			addError(&resp.Diagnostics, "Error injecting ssh key", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+		resp.Diagnostics.Append(storeSSHKeyHash(ctx, resp.Private, sshKeyPath)...)
+	}
+
+	if !data.HotCPUs.IsNull() {
+		vmInfo, err = virtualboxapi.SetPluggedCPUs(ctx, vmID, int(data.Cpu.ValueInt64()), int(data.HotCPUs.ValueInt64()))
+		if err != nil {
+			addError(&resp.Diagnostics, "Error unplugging cpus", err)
+			destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	nicInfo, err := setNetworkAdapterOptions(ctx, vmID, networkAdapterChanges(data, nil))
+	if err != nil {
+		addError(&resp.Diagnostics, "Error changing network adapter", err)
+		destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+		return
+	}
+	if nicInfo != nil {
+		vmInfo = nicInfo
+	}
+
+	var diskIDs []string
+	resp.Diagnostics.Append(data.DiskIDs.ElementsAs(ctx, &diskIDs, false)...)
+	if resp.Diagnostics.HasError() {
+		destroyFailed(ctx, vmID, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+		return
+	}
+	for _, diskID := range diskIDs {
+		vmInfo, err = virtualboxapi.AttachDisk(ctx, vmID, diskID)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error attaching disk", err)
+			destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if !data.RestoreSnapshot.IsNull() {
+		err = virtualboxapi.TakeSnapshot(ctx, vmID, data.RestoreSnapshot.ValueString())
+		if err != nil {
+			addError(&resp.Diagnostics, "Error taking vm snapshot", err)
+			destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if !startVM {
+		err = virtualboxapi.SetExtraData(ctx, vmID, virtualboxapi.CreatingMarkerKey, "")
+		if err != nil {
+			addError(&resp.Diagnostics, "Error marking vm as created", err)
+			destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+		data.Id = types.StringValue(vmID)
+		updateModelFromVMInfo(ctx, data, vmInfo)
+		tflog.Trace(ctx, "created a stopped resource")
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	probeRuleName := ""
+	if data.ReadinessProbe != nil {
+		guestPort := data.ReadinessProbe.Port.ValueInt64()
+		if vmInfo.RuleForGuestPort(strconv.FormatInt(guestPort, 10)) == nil {
+			// NAT rules of running vm are managed through controlvm,
+			// so temporary rule is created before start
+			probeRuleName = virtualboxapi.ProbePortRuleName
+			vmInfo, err = virtualboxapi.ForwardLocalPort(ctx, vmID, probeRuleName, 0, int(guestPort))
+			if err != nil {
+				addError(&resp.Diagnostics, "Error forwarding readiness probe port", err)
+				destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+				return
+			}
+		}
+	}
+
+	vmInfo, err = virtualboxapi.StartVM(
+		ctx,
+		vmID,
+		vmBootType(data),
+		vmStateTimeouts(data).Start,
+	)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error starting new vm", err)
+		destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+		return
+	}
+
+	// ports forwarded before start may have been taken by another process meanwhile
+	for _, ruleName := range []string{data.SSHRuleName.ValueString(), probeRuleName} {
+		if ruleName == "" || vmInfo.Rule(ruleName) == nil {
+			continue
+		}
+		movable := ruleName != data.SSHRuleName.ValueString() || data.FixedSSHPort.IsNull()
+		vmInfo, err = virtualboxapi.EnsureForwardedPort(ctx, vmID, ruleName, movable)
+		if err != nil {
+			addError(&resp.Diagnostics, "Error forwarding local port", err)
+			destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if data.ReadinessProbe != nil {
+		err = virtualboxapi.WaitForReadiness(ctx, readinessProbe(vmInfo, data.ReadinessProbe))
+		if probeRuleName != "" {
+			_, deleteErr := virtualboxapi.DeleteForwardingRule(ctx, vmID, probeRuleName)
+			if deleteErr != nil {
+				tflog.Warn(ctx, "failed to delete readiness probe NAT rule", map[string]interface{}{"error": deleteErr.Error()})
+			}
+		}
+		if err != nil {
+			addError(&resp.Diagnostics, "VM is not ready", err)
+			destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	if data.WaitForGuestAdditions.ValueBool() {
+		err = virtualboxapi.WaitForGuestAdditions(ctx, vmID, time.Duration(data.GuestAdditionsTimeout.ValueInt64())*time.Second)
+		if err != nil {
+			addError(&resp.Diagnostics, "Guest Additions are not running", err)
+			destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+			return
+		}
+	}
+
+	err = virtualboxapi.SetExtraData(ctx, vmID, virtualboxapi.CreatingMarkerKey, "")
+	if err != nil {
+		addError(&resp.Diagnostics, "Error marking vm as created", err)
+		destroyFailedKeepingDisks(ctx, vmID, diskIDs, vmStateTimeouts(data).Stop, &resp.Diagnostics)
+		return
+	}
+
+	// save into the Terraform state.
+	data.Id = types.StringValue(vmID)
+	updateModelFromVMInfo(ctx, data, vmInfo)
+
+	// Write logs using the tflog package
+	// Documentation: https://terraform.io/plugin/log
+	tflog.Trace(ctx, "created a resource")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *VirtualboxVMResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_vm Read")
+
+	var data *VirtualboxVMResourceModel
 
 	// Read Terraform prior state data into the model
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -195,32 +1568,85 @@ func (r *VirtualboxVMResource) Read(ctx context.Context, req resource.ReadReques
 		return
 	}
 
-	vminfo, err := virtualboxapi.GetVMInfo(data.Id.ValueString())
+	vminfo, err := virtualboxapi.GetVMInfo(ctx, data.Id.ValueString())
+	if virtualboxapi.IsObjectNotFound(err) {
+		// vm was removed outside of Terraform, it will be recreated on next apply
+		tflog.Warn(ctx, "vm not found, removing from state", map[string]interface{}{"id": data.Id.ValueString(), "name": data.Name.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Error getting vm info", err.Error())
+		addVMError(&resp.Diagnostics, "Error getting vm info", data, err)
 		return
 	}
-	data.SSHPort = types.StringValue(vminfo.SSHPort)
+	// vm imported by name gets UUID as id, so renaming vm outside of Terraform doesn't lose it
+	data.Id = types.StringValue(vminfo.ID)
+	if data.Name.IsNull() {
+		data.Name = types.StringValue(vminfo.Name)
+	}
+	if data.SSHRuleName.IsNull() {
+		// Imported resources have no rule name in state yet
+		data.SSHRuleName = types.StringValue(virtualboxapi.SshPortRuleName)
+	}
+	if data.SSHUser.IsNull() {
+		data.SSHUser = types.StringValue(defaultSSHUser)
+	}
+	if data.State.IsNull() {
+		// Imported resources keep vm in its current state
+		data.State = types.StringValue(vmStatePoweroff)
+		if vminfo.State == virtualboxapi.Running {
+			data.State = types.StringValue(vmStateRunning)
+		}
+	}
+	updateModelFromVMInfo(ctx, data, vminfo)
+	if missing := vminfo.MissingDisks(); len(missing) > 0 {
+		resp.Diagnostics.AddWarning(
+			"Vm disk image is missing",
+			fmt.Sprintf("Vm %s is registered, but file of its disk image doesn't exist: %s. Vm won't start until disk is restored from backup or replaced.",
+				vmDescription(data), strings.Join(missing, ", ")),
+		)
+	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *VirtualboxVMResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data *VirtualboxVMResourceModel
+	defer logStats(ctx, "virtualbox_vm Update")
 
-	// Read Terraform plan data into the model
+	var data, state *VirtualboxVMResourceModel
+
+	// Read Terraform plan and prior state data into the models
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
+	resp.Diagnostics.Append(applyVMDiff(ctx, state, data)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	if sshKeyChanged(data, state) {
+		sshKeyPath := resolveAttributePath(ctx, path.Root("ssh_key"), data.SSHKey, &resp.Diagnostics)
+		resp.Diagnostics.Append(storeSSHKeyHash(ctx, resp.Private, sshKeyPath)...)
+	}
+
+	vminfo, err := virtualboxapi.GetVMInfo(ctx, data.Id.ValueString())
+	if err != nil {
+		addVMError(&resp.Diagnostics, "Error getting vm info", data, err)
+		return
+	}
+	updateModelFromVMInfo(ctx, data, vminfo)
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *VirtualboxVMResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	defer logStats(ctx, "virtualbox_vm Delete")
+
 	var data *VirtualboxVMResourceModel
 
 	// Read Terraform prior state data into the model
@@ -230,14 +1656,1121 @@ func (r *VirtualboxVMResource) Delete(ctx context.Context, req resource.DeleteRe
 		return
 	}
 
-	err := virtualboxapi.DestroyVM(
-		data.Id.ValueString(),
-	)
-	if err != nil {
-		tflog.Error(ctx, err.Error())
-		resp.Diagnostics.AddError("Error destroying vm", err.Error())
-		return
+	// provider rules are removed while vm runs, so that vm re-created right away could bind the same fixed host ports
+	var releasedRules []virtualboxapi.PortForwardingRule
+	if data.DeleteBehavior.ValueString() != deleteBehaviorPoweroffOnly {
+		var err error
+		releasedRules, err = virtualboxapi.DeleteProviderForwardingRules(ctx, data.Id.ValueString(), data.SSHRuleName.ValueString())
+		if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+			tflog.Warn(ctx, "unable to remove forwarding rules before destroy", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
+	if data.CompactDiskOnDestroy.ValueBool() {
+		// vm is destroyed even if its disks couldn't be compacted
+		_, err := virtualboxapi.PowerOffVM(ctx, data.Id.ValueString(), vmStateTimeouts(data).Stop)
+		if err == nil {
+			err = virtualboxapi.CompactVMDisks(ctx, data.Id.ValueString())
+		}
+		if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+			resp.Diagnostics.AddWarning(
+				"Error compacting vm disks",
+				fmt.Sprintf("Disks of vm %s were not compacted before destroy: %s", vmDescription(data), err),
+			)
+		}
+	}
+
+	var err error
+	switch data.DeleteBehavior.ValueString() {
+	case deleteBehaviorUnregister:
+		_, err = virtualboxapi.PowerOffVM(ctx, data.Id.ValueString(), vmStateTimeouts(data).Stop)
+		if err == nil {
+			err = virtualboxapi.UnregisterVM(ctx, data.Id.ValueString())
+		}
+		if err == nil {
+			resp.Diagnostics.AddWarning(
+				"Vm files are kept",
+				fmt.Sprintf("Vm %s is unregistered from VirtualBox, its files are left on disk: %s", vmDescription(data), data.ConfigFile.ValueString()),
+			)
+		}
+	case deleteBehaviorPoweroffOnly:
+		_, err = virtualboxapi.PowerOffVM(ctx, data.Id.ValueString(), vmStateTimeouts(data).Stop)
+		if err == nil {
+			resp.Diagnostics.AddWarning(
+				"Vm is kept",
+				fmt.Sprintf("Vm %s is powered off and removed from Terraform state, it is still registered in VirtualBox", vmDescription(data)),
+			)
+		}
+	default:
+		var diskIDs []string
+		resp.Diagnostics.Append(data.DiskIDs.ElementsAs(ctx, &diskIDs, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// standalone disks would be deleted together with vm files
+		err = detachDisks(ctx, data.Id.ValueString(), diskIDs, vmStateTimeouts(data).Stop)
+		if err == nil {
+			err = virtualboxapi.DestroyVM(
+				ctx,
+				data.Id.ValueString(),
+				vmStateTimeouts(data).Stop,
+			)
+		}
+	}
+	if virtualboxapi.IsObjectNotFound(err) {
+		// Already destroyed outside of Terraform
+		return
+	}
+	if err != nil {
+		tflog.Error(ctx, err.Error())
+		addVMError(&resp.Diagnostics, "Error destroying vm", data, err)
+		return
+	}
+	err = virtualboxapi.WaitForHostPortsReleased(ctx, releasedRules, virtualboxapi.DefaultPortReleaseTimeout)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Host port is still in use",
+			fmt.Sprintf("Vm %s is destroyed, but its forwarded host port isn't released yet, vm re-created with the same fixed_ssh_port may fail to bind it: %s",
+				vmDescription(data), err),
+		)
+	}
+}
+
+// applyVMDiff applies differences between plan and state to existing vm. Settings which need powered off vm
+// are passed to a single modifyvm call, so vm is stopped and started at most once for all of them
+func applyVMDiff(ctx context.Context, state, data *VirtualboxVMResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !data.FixedSSHPort.IsNull() && !data.FixedSSHPort.Equal(state.FixedSSHPort) {
+		// stopped vm without rule gets fixed port on start
+		vminfo, err := virtualboxapi.GetVMInfo(ctx, data.Id.ValueString())
+		if err != nil {
+			addVMError(&diags, "Error getting vm info", data, err)
+			return diags
+		}
+		rule := vminfo.Rule(data.SSHRuleName.ValueString())
+		if rule != nil && rule.HostPort != strconv.FormatInt(data.FixedSSHPort.ValueInt64(), 10) {
+			_, err = virtualboxapi.SetForwardedHostPort(ctx, vminfo.ID, rule.Name, int(data.FixedSSHPort.ValueInt64()))
+			if err != nil {
+				addVMError(&diags, "Error changing ssh port", data, err)
+				return diags
+			}
+		}
+	}
+
+	if !data.UserData.Equal(state.UserData) || !data.UserDataBase64.Equal(state.UserDataBase64) {
+		userData, encoding := vmUserData(data)
+		err := virtualboxapi.SetUserData(ctx, data.Id.ValueString(), userData, encoding)
+		if err != nil {
+			addVMError(&diags, "Error setting user data", data, err)
+			return diags
+		}
+	}
+
+	for _, change := range networkAdapterChanges(data, state) {
+		if !change.replug && (change.Type != change.prior.Type || change.Network != change.prior.Network) {
+			_, err := virtualboxapi.SetNICAttachment(ctx, data.Id.ValueString(), change.nic, change.Type, change.Network)
+			if err != nil {
+				addVMError(&diags, "Error changing network adapter", data, err)
+				return diags
+			}
+		}
+	}
+
+	if !data.DiskIDs.Equal(state.DiskIDs) {
+		err := updateAttachedDisks(ctx, data, state)
+		if err != nil {
+			addVMError(&diags, "Error changing attached disks", data, err)
+			return diags
+		}
+	}
+
+	stateChanged := !data.State.Equal(state.State)
+
+	if stateChanged && data.State.ValueString() == vmStatePoweroff {
+		// stopping first spares restart by ModifyVMOffline
+		_, err := virtualboxapi.PowerOffVM(ctx, data.Id.ValueString(), vmStateTimeouts(data).Stop)
+		if err != nil {
+			addVMError(&diags, "Error stopping vm", data, err)
+			return diags
+		}
+		if data.CompactDiskOnStop.ValueBool() {
+			err = virtualboxapi.CompactVMDisks(ctx, data.Id.ValueString())
+			if err != nil {
+				addVMError(&diags, "Error compacting vm disks", data, err)
+				return diags
+			}
+		}
+	}
+
+	if sshKeyChanged(data, state) {
+		// stopped vm is started below when it's meant to run, so it isn't restarted twice
+		sshKeyPath := resolveAttributePath(ctx, path.Root("ssh_key"), data.SSHKey, &diags)
+		if diags.HasError() {
+			return diags
+		}
+		var networkArgs []string
+		vminfo, err := virtualboxapi.GetVMInfo(ctx, data.Id.ValueString())
+		if err == nil {
+			networkArgs, err = guestNetworkArgs(ctx, data, vminfo)
+		}
+		if err == nil {
+			err = virtualboxapi.InjectSSHKeyOffline(ctx, data.Id.ValueString(), data.OSDisk.ValueString(), data.SSHUser.ValueString(),
+				sshKeyPath, networkArgs, vmBootType(data), vmStateTimeouts(data))
+		}
+		if err != nil {
+			addVMError(&diags, "Error injecting ssh key", data, err)
+			return diags
+		}
+	}
+
+	args := offlineModifyArgs(ctx, data, state)
+	starting := stateChanged && data.State.ValueString() == vmStateRunning
+
+	if !data.RestoreSnapshot.IsNull() && (len(args) > 0 || starting) {
+		err := resetToSnapshot(ctx, data, args)
+		if err != nil {
+			addVMError(&diags, "Error restoring vm snapshot", data, err)
+			return diags
+		}
+		// vm is powered off by reset, start it again unless it's meant to be stopped
+		starting = data.State.ValueString() == vmStateRunning
+	} else {
+		if len(args) > 0 {
+			_, err := virtualboxapi.ModifyVMOffline(ctx, data.Id.ValueString(), vmBootType(data), vmStateTimeouts(data), args...)
+			if err != nil {
+				addVMError(&diags, "Error modifying vm", data, err)
+				return diags
+			}
+		}
+		// replugged adapters are added by offline args, so their options are set afterwards
+		_, err := setNetworkAdapterOptions(ctx, data.Id.ValueString(), networkAdapterChanges(data, state))
+		if err != nil {
+			addVMError(&diags, "Error changing network adapter", data, err)
+			return diags
+		}
+	}
+
+	if enabled, ok := hostIOCache(data.DiskCacheMode.ValueString()); ok && !data.DiskCacheMode.Equal(state.DiskCacheMode) {
+		_, err := virtualboxapi.SetHostIOCache(ctx, data.Id.ValueString(), enabled, vmBootType(data), vmStateTimeouts(data))
+		if err != nil {
+			addVMError(&diags, "Error changing disk cache mode", data, err)
+			return diags
+		}
+	}
+
+	if !data.HotCPUs.Equal(state.HotCPUs) && data.CPUHotplugEnabled.ValueBool() {
+		_, err := virtualboxapi.SetPluggedCPUs(ctx, data.Id.ValueString(), pluggedCPUs(state), pluggedCPUs(data))
+		if err != nil {
+			addVMError(&diags, "Error changing plugged cpus", data, err)
+			return diags
+		}
+	}
+
+	if starting {
+		err := startStoppedVM(ctx, data)
+		if err != nil {
+			addVMError(&diags, "Error starting vm", data, err)
+			return diags
+		}
+	}
+
+	if teleportRequested(data, state) {
+		_, err := virtualboxapi.Teleport(
+			ctx,
+			data.Id.ValueString(),
+			data.Teleport.TargetHost.ValueString(),
+			data.Teleport.TargetPort.ValueInt64(),
+			data.Teleport.Password.ValueString(),
+		)
+		if err != nil {
+			addVMError(&diags, "Error teleporting vm", data, err)
+			return diags
+		}
+	}
+
+	return diags
+}
+
+// networkAdapter is element of network_adapter, empty network is not set,
+// empty promiscuous mode keeps adapter setting
+type networkAdapter struct {
+	Type            string
+	Network         string
+	PromiscuousMode string
+	CableConnected  bool
+}
+
+// networkAdapterChange is planned adapter with its number and prior state, replug means that adapter
+// is added or removed, which needs powered off vm. Prior type is empty when adapter state isn't known
+type networkAdapterChange struct {
+	networkAdapter
+	prior  networkAdapter
+	nic    int
+	replug bool
+}
+
+// networkAdapters returns network_adapter elements, nil when list is null or unknown
+func networkAdapters(list types.List) []networkAdapter {
+	if list.IsNull() || list.IsUnknown() {
+		return nil
+	}
+	result := []networkAdapter{}
+	for _, element := range list.Elements() {
+		object, ok := element.(types.Object)
+		if !ok {
+			continue
+		}
+		// cable is connected by default
+		adapter := networkAdapter{CableConnected: true}
+		if value, ok := object.Attributes()["type"].(types.String); ok {
+			adapter.Type = value.ValueString()
+		}
+		if value, ok := object.Attributes()["network"].(types.String); ok {
+			adapter.Network = value.ValueString()
+		}
+		if value, ok := object.Attributes()["promiscuous_mode"].(types.String); ok {
+			adapter.PromiscuousMode = value.ValueString()
+		}
+		if value, ok := object.Attributes()["cable_connected"].(types.Bool); ok && !value.IsNull() && !value.IsUnknown() {
+			adapter.CableConnected = value.ValueBool()
+		}
+		result = append(result, adapter)
+	}
+	return result
+}
+
+// networkAdapterChanges returns adapters which differ between plan and state. State is nil when vm is created,
+// all adapter slots are set then, so that appliance adapters are replaced. Unknown plan keeps adapters as they are
+func networkAdapterChanges(plan, state *VirtualboxVMResourceModel) []networkAdapterChange {
+	if plan.NetworkAdapters.IsNull() || plan.NetworkAdapters.IsUnknown() {
+		return nil
+	}
+	planned := networkAdapters(plan.NetworkAdapters)
+	slots := len(planned)
+	var prior []networkAdapter
+	if state != nil {
+		prior = networkAdapters(state.NetworkAdapters)
+		if len(prior) > slots {
+			slots = len(prior)
+		}
+	} else {
+		slots = maxNetworkAdapters - 1
+	}
+	at := func(adapters []networkAdapter, i int) networkAdapter {
+		if i < len(adapters) {
+			return adapters[i]
+		}
+		return networkAdapter{Type: "none", CableConnected: true}
+	}
+	changes := []networkAdapterChange{}
+	for i := 0; i < slots; i++ {
+		want, current := at(planned, i), at(prior, i)
+		if want.PromiscuousMode == "" {
+			want.PromiscuousMode = current.PromiscuousMode
+		}
+		if state == nil {
+			current = networkAdapter{}
+		} else if want == current {
+			continue
+		}
+		changes = append(changes, networkAdapterChange{
+			networkAdapter: want,
+			prior:          current,
+			// adapter 1 is the ssh NAT adapter
+			nic:    i + 2,
+			replug: state == nil || want.Type == "none" || current.Type == "none",
+		})
+	}
+	return changes
+}
+
+// setNetworkAdapterOptions sets promiscuous mode and cable state of changed adapters, adapters have to exist already.
+// Options of adapters with unknown or empty prior state are set whenever they are configured.
+// Returns vm info after the last change, nil when nothing is changed
+func setNetworkAdapterOptions(ctx context.Context, vmName string, changes []networkAdapterChange) (*virtualboxapi.VirtualboxVMInfo, error) {
+	var vminfo *virtualboxapi.VirtualboxVMInfo
+	var err error
+	for _, change := range changes {
+		if change.Type == "none" {
+			continue
+		}
+		known := change.prior.Type != "" && change.prior.Type != "none"
+		if change.PromiscuousMode != "" && (!known || change.PromiscuousMode != change.prior.PromiscuousMode) {
+			vminfo, err = virtualboxapi.SetPromiscuousMode(ctx, vmName, change.nic, change.PromiscuousMode)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !known || change.CableConnected != change.prior.CableConnected {
+			info, err := virtualboxapi.SetCableConnected(ctx, vmName, change.nic, change.CableConnected)
+			// cable is always connected with VirtualBox which can't disconnect it
+			if err != nil && !(change.CableConnected && virtualboxapi.IsUnsupportedOption(err)) {
+				return nil, err
+			}
+			if err == nil {
+				vminfo = info
+			}
+		}
+	}
+	return vminfo, nil
+}
+
+// networkAdapterType maps showvminfo attachment to network_adapter type
+func networkAdapterType(nic *virtualboxapi.NetworkAdapter) string {
+	if nic == nil {
+		return "none"
+	}
+	return nic.Attachment
+}
+
+// networkAdaptersValue returns network_adapter of vm. Empty adapter slots after the last enabled adapter
+// are omitted unless they are within prior adapters. ip_config isn't readable from vm, it's kept from prior adapters.
+// ips are guest addresses by adapter number
+func networkAdaptersValue(vminfo *virtualboxapi.VirtualboxVMInfo, ips map[int]string, prior types.List) types.List {
+	var priorElements []attr.Value
+	if !prior.IsNull() && !prior.IsUnknown() {
+		priorElements = prior.Elements()
+	}
+	last := len(priorElements) + 1
+	for _, nic := range vminfo.NetworkAdapters {
+		if nic.Index > last {
+			last = nic.Index
+		}
+	}
+	elements := []attr.Value{}
+	for index := 2; index <= last; index++ {
+		nic := vminfo.NetworkAdapter(index)
+		network, promiscuousMode := types.StringNull(), types.StringNull()
+		// empty slot keeps default, so it doesn't show up as diff
+		cableConnected := true
+		if nic != nil && nic.Network != "" {
+			network = types.StringValue(nic.Network)
+		}
+		if nic != nil && nic.PromiscuousMode != "" {
+			promiscuousMode = types.StringValue(nic.PromiscuousMode)
+		}
+		if nic != nil {
+			cableConnected = nic.CableConnected
+		}
+		ipConfig := types.ObjectNull(ipConfigAttrTypes)
+		if i := index - 2; i < len(priorElements) {
+			if object, ok := priorElements[i].(types.Object); ok {
+				if value, ok := object.Attributes()["ip_config"].(types.Object); ok && !value.IsUnknown() {
+					ipConfig = value
+				}
+			}
+		}
+		ipAddress := types.StringNull()
+		if ip, ok := ips[index]; ok {
+			ipAddress = types.StringValue(ip)
+		}
+		elements = append(elements, types.ObjectValueMust(networkAdapterAttrTypes, map[string]attr.Value{
+			"type":             types.StringValue(networkAdapterType(nic)),
+			"network":          network,
+			"promiscuous_mode": promiscuousMode,
+			"cable_connected":  types.BoolValue(cableConnected),
+			"ip_config":        ipConfig,
+			"ip_address":       ipAddress,
+		}))
+	}
+	return types.ListValueMust(types.ObjectType{AttrTypes: networkAdapterAttrTypes}, elements)
+}
+
+// sshPortReplanned reports whether ssh_port of existing vm isn't known until apply: ssh forwarding
+// rule is recreated, so previous host port is meaningless. Stopped vm gets ssh port on start,
+// empty port is written by older versions
+func sshPortReplanned(plan, state *VirtualboxVMResourceModel) bool {
+	return !plan.SSHKey.Equal(state.SSHKey) || !plan.SSHRuleName.Equal(state.SSHRuleName) || !plan.FixedSSHPort.Equal(state.FixedSSHPort) ||
+		!plan.State.Equal(state.State) || (!state.SSHPort.IsNull() && state.SSHPort.ValueString() == "")
+}
+
+// sshKeyChanged reports whether ssh key has to be injected into existing vm
+func sshKeyChanged(plan, state *VirtualboxVMResourceModel) bool {
+	return !plan.SSHKey.IsNull() && (!plan.SSHKey.Equal(state.SSHKey) || !plan.SSHUser.Equal(state.SSHUser))
+}
+
+// offlineModifyArgs returns modifyvm arguments for settings which could be changed
+// only on powered off vm, state is nil when vm is being created
+func offlineModifyArgs(ctx context.Context, plan, state *VirtualboxVMResourceModel) []string {
+	args := []string{}
+	changed := func(planValue, stateValue attr.Value) bool {
+		return !planValue.IsNull() && !planValue.IsUnknown() && (state == nil || !planValue.Equal(stateValue))
+	}
+	var prior VirtualboxVMResourceModel
+	if state != nil {
+		prior = *state
+	}
+	if changed(plan.Chipset, prior.Chipset) {
+		args = append(args, "--chipset", plan.Chipset.ValueString())
+	}
+	if changed(plan.Firmware, prior.Firmware) {
+		args = append(args, "--firmware", plan.Firmware.ValueString())
+	}
+	// vm without TPM is left as is on create, VirtualBox before 7.0 doesn't know the flag
+	if changed(plan.TPM, prior.TPM) && (state != nil || tpmRequested(plan.TPM)) {
+		// version is checked at plan time, undetected version is left to VBoxManage to report
+		flag, err := virtualboxapi.ModifyVMFlag(ctx, virtualboxapi.OptionTPMType, 0)
+		if err != nil {
+			flag = "--tpm-type"
+		}
+		args = append(args, flag, plan.TPM.ValueString())
+	}
+	for _, change := range networkAdapterChanges(plan, state) {
+		if change.replug {
+			args = append(args, virtualboxapi.NICArgs(change.nic, change.Type, change.Network)...)
+		}
+	}
+	if changed(plan.RTCUseUTC, prior.RTCUseUTC) {
+		args = append(args, "--rtcuseutc", virtualboxapi.OnOff(plan.RTCUseUTC.ValueBool()))
+	}
+	if changed(plan.HPET, prior.HPET) {
+		args = append(args, "--hpet", virtualboxapi.OnOff(plan.HPET.ValueBool()))
+	}
+	if changed(plan.IOAPIC, prior.IOAPIC) {
+		args = append(args, "--ioapic", virtualboxapi.OnOff(plan.IOAPIC.ValueBool()))
+	}
+	if changed(plan.CPUHotplugEnabled, prior.CPUHotplugEnabled) {
+		args = append(args, "--cpuhotplug", virtualboxapi.OnOff(plan.CPUHotplugEnabled.ValueBool()))
+	}
+	if changed(plan.Cpu, prior.Cpu) && state != nil {
+		args = append(args, "--cpus", strconv.FormatInt(plan.Cpu.ValueInt64(), 10))
+	}
+	if changed(plan.Memory, prior.Memory) && state != nil {
+		args = append(args, "--memory", strconv.FormatInt(plan.Memory.ValueInt64(), 10))
+	}
+	if changed(plan.CPUProfile, prior.CPUProfile) {
+		args = append(args, "--cpu-profile", plan.CPUProfile.ValueString())
+	}
+	if changed(plan.VRAM, prior.VRAM) {
+		args = append(args, "--vram", strconv.FormatInt(plan.VRAM.ValueInt64(), 10))
+	}
+	if changed(plan.MonitorCount, prior.MonitorCount) {
+		args = append(args, "--monitorcount", strconv.FormatInt(plan.MonitorCount.ValueInt64(), 10))
+	}
+	if listen, priorListen := teleportListen(plan.Teleport), teleportListen(prior.Teleport); listen != priorListen ||
+		(listen && (!plan.Teleport.Port.Equal(prior.Teleport.Port) || !plan.Teleport.Password.Equal(prior.Teleport.Password))) {
+		if listen || state != nil {
+			args = append(args, teleporterArgs(plan.Teleport)...)
+		}
+	}
+	if vrdeChanged(plan.VRDE, prior.VRDE) && (plan.VRDE != nil || state != nil) {
+		args = append(args, vrdeArgs(plan.VRDE)...)
+	}
+	return args
+}
+
+// networkAdapterIPConfigs returns ip_config of network_adapter elements by list index,
+// elements without ip_config are missing
+func networkAdapterIPConfigs(ctx context.Context, list types.List) (map[int]VirtualboxVMIPConfigModel, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	result := map[int]VirtualboxVMIPConfigModel{}
+	if list.IsNull() || list.IsUnknown() {
+		return result, diags
+	}
+	for i, element := range list.Elements() {
+		object, ok := element.(types.Object)
+		if !ok || object.IsUnknown() {
+			continue
+		}
+		value, ok := object.Attributes()["ip_config"].(types.Object)
+		if !ok || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		var ipConfig VirtualboxVMIPConfigModel
+		diags.Append(value.As(ctx, &ipConfig, basetypes.ObjectAsOptions{})...)
+		result[i] = ipConfig
+	}
+	return result, diags
+}
+
+// guestIPConfig converts ip_config of adapter into API config, unknown values are empty
+func guestIPConfig(ctx context.Context, nic int, ipConfig VirtualboxVMIPConfigModel) (virtualboxapi.GuestIPConfig, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	config := virtualboxapi.GuestIPConfig{
+		NIC:     nic,
+		Address: ipConfig.Address.ValueString(),
+		Netmask: ipConfig.Netmask.ValueString(),
+		Gateway: ipConfig.Gateway.ValueString(),
+	}
+	if !ipConfig.DNS.IsNull() && !ipConfig.DNS.IsUnknown() {
+		diags.Append(ipConfig.DNS.ElementsAs(ctx, &config.DNS, false)...)
+	}
+	return config, diags
+}
+
+// guestNetworkArgs returns virt-sysprep arguments writing ip_config of network adapters into guest disk,
+// guest interfaces are matched by MAC addresses of vm adapters. Empty without ip_config
+func guestNetworkArgs(ctx context.Context, data *VirtualboxVMResourceModel, vminfo *virtualboxapi.VirtualboxVMInfo) ([]string, error) {
+	ipConfigs, diags := networkAdapterIPConfigs(ctx, data.NetworkAdapters)
+	if len(ipConfigs) == 0 && !diags.HasError() {
+		return nil, nil
+	}
+	configs := []virtualboxapi.GuestIPConfig{}
+	for i := 0; i < len(data.NetworkAdapters.Elements()); i++ {
+		ipConfig, ok := ipConfigs[i]
+		if !ok {
+			continue
+		}
+		// adapter 1 is the ssh NAT adapter
+		config, configDiags := guestIPConfig(ctx, i+2, ipConfig)
+		diags.Append(configDiags...)
+		if nic := vminfo.NetworkAdapter(config.NIC); nic != nil {
+			config.MACAddress = nic.MACAddress
+		}
+		configs = append(configs, config)
+	}
+	if diags.HasError() {
+		return nil, fmt.Errorf("reading ip_config failed: %v", diags)
+	}
+	return virtualboxapi.GuestNetworkArgs(virtualboxapi.GuestNetworkManager(data.GuestNetworkManager.ValueString()), configs)
+}
+
+// tpmRequested reports whether tpm is known and asks for emulated TPM
+func tpmRequested(tpm types.String) bool {
+	return !tpm.IsNull() && !tpm.IsUnknown() && tpm.ValueString() != "none"
+}
+
+// nvmeRequested reports whether disk_controller is known and asks for NVMe controller
+func nvmeRequested(controller types.String) bool {
+	return !controller.IsUnknown() && controller.ValueString() == string(virtualboxapi.DiskControllerNVMe)
+}
+
+// hostIOCache maps disk_cache_mode to host I/O cache switch, ok is false when setting is kept as is
+func hostIOCache(mode string) (enabled bool, ok bool) {
+	switch mode {
+	case diskCacheNone, diskCacheDirectSync:
+		return false, true
+	case diskCacheWriteback, diskCacheWritethrough, diskCacheUnsafe:
+		return true, true
+	}
+	return false, false
+}
+
+// teleportListen reports whether vm is configured to wait for incoming teleport
+func teleportListen(teleport *VirtualboxVMTeleportModel) bool {
+	return teleport != nil && teleport.Listen.ValueBool()
+}
+
+// teleporterArgs returns modifyvm arguments applying teleport listen settings
+func teleporterArgs(teleport *VirtualboxVMTeleportModel) []string {
+	if !teleportListen(teleport) {
+		return virtualboxapi.TeleporterArgs(false, 0, "")
+	}
+	return virtualboxapi.TeleporterArgs(true, teleport.Port.ValueInt64(), teleport.Password.ValueString())
+}
+
+// vrdeChanged reports whether remote display server settings differ
+func vrdeChanged(plan, prior *VirtualboxVMVRDEModel) bool {
+	if plan == nil || prior == nil {
+		return plan != prior
+	}
+	return !plan.Port.Equal(prior.Port) || !plan.MultiConnection.Equal(prior.MultiConnection)
+}
+
+// vrdeArgs returns modifyvm arguments applying vrde, nil vrde disables the server
+func vrdeArgs(vrde *VirtualboxVMVRDEModel) []string {
+	if vrde == nil {
+		return virtualboxapi.VRDEArgs(false, "", nil)
+	}
+	var multiConnection *bool
+	if !vrde.MultiConnection.IsNull() && !vrde.MultiConnection.IsUnknown() {
+		value := vrde.MultiConnection.ValueBool()
+		multiConnection = &value
+	}
+	return virtualboxapi.VRDEArgs(true, vrde.Port.ValueString(), multiConnection)
+}
+
+// teleportRequested reports whether plan changes teleport target of existing vm, which starts teleport
+func teleportRequested(plan, state *VirtualboxVMResourceModel) bool {
+	if plan.Teleport == nil || plan.Teleport.TargetHost.IsNull() {
+		return false
+	}
+	if state.Teleport == nil {
+		return true
+	}
+	return !plan.Teleport.TargetHost.Equal(state.Teleport.TargetHost) || !plan.Teleport.TargetPort.Equal(state.Teleport.TargetPort)
+}
+
+// vmUserData returns user data stored in guest properties and its encoding, empty encoding means plain text
+func vmUserData(data *VirtualboxVMResourceModel) (string, string) {
+	if !data.UserDataBase64.IsNull() {
+		return data.UserDataBase64.ValueString(), "base64"
+	}
+	return data.UserData.ValueString(), ""
+}
+
+// checkCPUProfile reports cpu profile unknown to installed VirtualBox at plan time, profiles differ between
+// VirtualBox versions and host platforms. Only new or changed values are checked
+func checkCPUProfile(ctx context.Context, planned, prior types.String, diags *diag.Diagnostics) {
+	if planned.IsNull() || planned.IsUnknown() || planned.Equal(prior) || planned.ValueString() == "host" {
+		return
+	}
+	profiles, err := virtualboxapi.ListCPUProfiles(ctx)
+	if err != nil {
+		addError(diags, "Error listing cpu profiles", err)
+		return
+	}
+	for _, profile := range profiles {
+		if profile == planned.ValueString() {
+			return
+		}
+	}
+	diags.AddAttributeError(
+		path.Root("cpu_profile"),
+		"Unknown cpu profile",
+		fmt.Sprintf("VirtualBox doesn't have cpu profile %q, use `host` or one of: %s", planned.ValueString(), strings.Join(profiles, ", ")),
+	)
+}
+
+// validateTeleport checks teleport settings, they're guarded by enable_experimental provider attribute
+func validateTeleport(teleport *VirtualboxVMTeleportModel, diags *diag.Diagnostics) {
+	if !experimentalEnabled {
+		diags.AddAttributeError(
+			path.Root("teleport"),
+			"Experimental feature is disabled",
+			"teleport requires enable_experimental = true in provider configuration",
+		)
+		return
+	}
+	if teleport.Listen.ValueBool() && teleport.Port.IsNull() {
+		diags.AddAttributeError(
+			path.Root("teleport").AtName("port"),
+			"Missing teleport port",
+			"listen requires port to listen on",
+		)
+	}
+	if !teleport.TargetHost.IsNull() && teleport.TargetPort.IsNull() {
+		diags.AddAttributeError(
+			path.Root("teleport").AtName("target_port"),
+			"Missing teleport target port",
+			"target_host requires target_port",
+		)
+	}
+	if teleport.Listen.ValueBool() && !teleport.TargetHost.IsNull() {
+		diags.AddAttributeError(
+			path.Root("teleport").AtName("target_host"),
+			"Conflicting teleport settings",
+			"vm either waits for incoming teleport or is teleported to target_host, not both",
+		)
+	}
+}
+
+// updateModelFromVMInfo refreshes computed and drift-detected attributes from actual vm info,
+// so that refresh-only plans show changes made outside of Terraform
+func updateModelFromVMInfo(ctx context.Context, data *VirtualboxVMResourceModel, vminfo *virtualboxapi.VirtualboxVMInfo) {
+	data.PowerState = types.StringValue(string(vminfo.State))
+	if vminfo.State == virtualboxapi.Teleported {
+		// vm is running on teleport target now, local copy is just stopped
+		data.PowerState = types.StringValue(string(virtualboxapi.Poweroff))
+	}
+	if vminfo.CPUs > 0 {
+		data.Cpu = types.Int64Value(int64(vminfo.CPUs))
+	}
+	if vminfo.Memory > 0 {
+		data.Memory = types.Int64Value(int64(vminfo.Memory))
+	}
+	data.SSHPort = types.StringNull()
+	if port := vminfo.HostPort(data.SSHRuleName.ValueString()); port != "" {
+		data.SSHPort = types.StringValue(port)
+	}
+	var ips map[int]string
+	if vminfo.State == virtualboxapi.Running {
+		// addresses are informational, vm without Guest Additions simply reports none
+		ips, _ = virtualboxapi.GetAdapterIPs(ctx, vminfo)
+	}
+	data.NetworkAdapters = networkAdaptersValue(vminfo, ips, data.NetworkAdapters)
+	data.Chipset = types.StringValue(vminfo.Chipset)
+	if vminfo.Firmware != "" {
+		data.Firmware = types.StringValue(vminfo.Firmware)
+	}
+	data.TPM = types.StringValue(vminfo.TPMType)
+	data.RTCUseUTC = types.BoolValue(vminfo.RTCUseUTC)
+	data.HPET = types.BoolValue(vminfo.HPET)
+	data.IOAPIC = types.BoolValue(vminfo.IOAPIC)
+	data.MonitorCount = types.Int64Value(int64(vminfo.MonitorCount))
+	data.VRAM = types.Int64Value(int64(vminfo.VRAM))
+	// imported vm has no disk_controller, it's taken from vm so that import isn't followed by replacement
+	if data.DiskController.IsNull() {
+		data.DiskController = types.StringValue(string(virtualboxapi.DiskControllerSATA))
+		for _, controller := range vminfo.StorageControllers {
+			if controller.Type == "NVMe" {
+				data.DiskController = types.StringValue(string(virtualboxapi.DiskControllerNVMe))
+			}
+		}
+	}
+	// vrde of config without vrde block isn't tracked, so that appliance settings don't show up as diff
+	if data.VRDE != nil {
+		if !vminfo.VRDEEnabled {
+			data.VRDE = nil
+		} else if !data.VRDE.MultiConnection.IsNull() {
+			data.VRDE.MultiConnection = types.BoolValue(vminfo.VRDEMultiConnection)
+		}
+	}
+	data.ConfigFile = types.StringValue(vminfo.ConfigFile)
+	data.MachineInfoRaw = types.StringValue(vminfo.Raw)
+	data.DiskUsageMB = types.Int64Null()
+	if usage, err := vminfo.DiskUsage(); err == nil {
+		data.DiskUsageMB = types.Int64Value(usage / (1024 * 1024))
+	}
+	data.DiskMissing = types.BoolNull()
+	if !virtualboxapi.IsRemote() {
+		data.DiskMissing = types.BoolValue(len(vminfo.MissingDisks()) > 0)
+	}
+}
+
+// updateModelFromApplianceInfo sets appliance metadata, settings not declared by appliance are null
+func updateModelFromApplianceInfo(data *VirtualboxVMResourceModel, info *virtualboxapi.ApplianceInfo) {
+	data.DetectedOSType = types.StringNull()
+	if info.OSType != "" {
+		data.DetectedOSType = types.StringValue(info.OSType)
+	}
+	data.DeclaredMemory = types.Int64Null()
+	if info.Memory > 0 {
+		data.DeclaredMemory = types.Int64Value(info.Memory)
+	}
+	data.DeclaredCPUs = types.Int64Null()
+	if info.CPUs > 0 {
+		data.DeclaredCPUs = types.Int64Value(info.CPUs)
+	}
+}
+
+// unattendedOptions converts install_from_iso into unattended installation options, ISO paths are resolved
+func unattendedOptions(ctx context.Context, model *VirtualboxVMInstallFromISOModel, diags *diag.Diagnostics) virtualboxapi.UnattendedOptions {
+	opts := virtualboxapi.UnattendedOptions{
+		ISOPath:  resolveAttributePath(ctx, path.Root("install_from_iso").AtName("iso_path"), model.ISOPath, diags),
+		Username: model.Username.ValueString(),
+		Password: model.Password.ValueString(),
+		Hostname: model.Hostname.ValueString(),
+		Country:  model.Country.ValueString(),
+		Locale:   model.Locale.ValueString(),
+		TimeZone: model.TimeZone.ValueString(),
+		Proxy:    model.Proxy.ValueString(),
+	}
+	if !model.AdditionsISO.IsNull() {
+		opts.AdditionsISO = resolveAttributePath(ctx, path.Root("install_from_iso").AtName("additions_iso"), model.AdditionsISO, diags)
+	}
+	return opts
+}
+
+// eulaAccepted reports whether appliance license is accepted either by accept_ova_eula
+// or by --eula=accept in import_extra_args
+func eulaAccepted(data *VirtualboxVMResourceModel, importExtraArgs []string) bool {
+	if data.AcceptOVAEULA.ValueBool() {
+		return true
+	}
+	for i, arg := range importExtraArgs {
+		if arg == "--eula=accept" || (arg == "--eula" && i+1 < len(importExtraArgs) && importExtraArgs[i+1] == "accept") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupOrphanedArtifacts removes leftovers of interrupted create of vm with the same name, e.g. half imported
+// appliance, so that apply could be re-run. Leftovers which can't be proven to be left by provider are reported
+// with instructions instead
+func cleanupOrphanedArtifacts(ctx context.Context, data *VirtualboxVMResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	artifacts, err := virtualboxapi.ListOrphanedArtifacts(ctx, data.Name.ValueString(), data.MachineFolder.ValueString())
+	if err != nil {
+		addError(&diags, "Error checking leftovers of previous vm creation", err)
+		return diags
+	}
+	removable := []string{}
+	for _, artifact := range artifacts {
+		if artifact.Removable {
+			removable = append(removable, "- "+artifact.String())
+		}
+	}
+	if !keptArtifactsError(data, artifacts, &diags) || len(removable) == 0 {
+		return diags
+	}
+	err = virtualboxapi.CleanupArtifacts(ctx, artifacts, vmStateTimeouts(data).Stop)
+	if err != nil {
+		addError(&diags, "Error removing leftovers of previous vm creation", err)
+		return diags
+	}
+	diags.AddWarning(
+		"Leftovers of interrupted vm creation removed",
+		fmt.Sprintf("Before creating vm %q the following leftovers of interrupted apply were removed:\n%s", data.Name.ValueString(), strings.Join(removable, "\n")),
+	)
+	// files of unregistered inaccessible vm stay in place and have to be removed manually
+	artifacts, err = virtualboxapi.ListOrphanedArtifacts(ctx, data.Name.ValueString(), data.MachineFolder.ValueString())
+	if err != nil {
+		addError(&diags, "Error checking leftovers of previous vm creation", err)
+		return diags
+	}
+	keptArtifactsError(data, artifacts, &diags)
+	return diags
+}
+
+// keptArtifactsError adds error listing artifacts which can't be removed automatically
+// with commands removing them, returns false when there are such artifacts
+func keptArtifactsError(data *VirtualboxVMResourceModel, artifacts []virtualboxapi.Artifact, diags *diag.Diagnostics) bool {
+	kept := []string{}
+	for _, artifact := range artifacts {
+		if !artifact.Removable {
+			kept = append(kept, fmt.Sprintf("- %s, remove it with: %s", artifact, artifact.CleanupHint()))
+		}
+	}
+	if len(kept) == 0 {
+		return true
+	}
+	diags.AddAttributeError(
+		path.Root("name"),
+		"Vm name is already in use",
+		fmt.Sprintf("Vm %q can't be created, as the following objects aren't proven leftovers of interrupted apply and are kept:\n%s\n"+
+			"Remove them, choose another name, or import existing vm with terraform import.", data.Name.ValueString(), strings.Join(kept, "\n")),
+	)
+	return false
+}
+
+// registerVMFromFile registers vm from .vbox settings file and applies memory and cpu count,
+// which import sets for appliances. Vm is unregistered again on failure, its files are kept
+func registerVMFromFile(ctx context.Context, vboxFilePath string, data *VirtualboxVMResourceModel) (*virtualboxapi.VirtualboxVMInfo, error) {
+	vminfo, err := virtualboxapi.RegisterVM(ctx, vboxFilePath)
+	if err != nil {
+		return nil, err
+	}
+	vmID := vminfo.ID
+	if vminfo.Name != data.Name.ValueString() {
+		err = fmt.Errorf("name %q doesn't match vm name %q in %s, vm is not renamed as that would move its files", data.Name.ValueString(), vminfo.Name, vboxFilePath)
+	} else {
+		vminfo, err = virtualboxapi.ModifyVM(
+			ctx,
+			vmID,
+			"--memory", strconv.FormatInt(data.Memory.ValueInt64(), 10),
+			"--cpus", strconv.FormatInt(data.Cpu.ValueInt64(), 10),
+		)
+	}
+	if err != nil {
+		unregisterErr := virtualboxapi.UnregisterVM(ctx, vmID)
+		if unregisterErr != nil {
+			return nil, fmt.Errorf("%w, unregistering vm failed: %v", err, unregisterErr)
+		}
+		return nil, err
+	}
+	return vminfo, nil
+}
+
+// startStoppedVM starts vm created with poweroff state, ssh port is forwarded
+// on first start. Key is injected on create, so it isn't injected again here
+func startStoppedVM(ctx context.Context, data *VirtualboxVMResourceModel) error {
+	vminfo, err := virtualboxapi.GetVMInfo(ctx, data.Id.ValueString())
+	if err != nil {
+		return err
+	}
+	if !data.SSHKey.IsNull() && vminfo.Rule(data.SSHRuleName.ValueString()) == nil {
+		vminfo, err = virtualboxapi.ForwardLocalPort(ctx, vminfo.ID, data.SSHRuleName.ValueString(), int(data.FixedSSHPort.ValueInt64()), 22)
+		if err != nil {
+			return err
+		}
+	}
+	if vminfo.State == virtualboxapi.Running {
+		return nil
+	}
+	_, err = virtualboxapi.StartVM(ctx, vminfo.ID, vmBootType(data), vmStateTimeouts(data).Start)
+	if err != nil || vminfo.Rule(data.SSHRuleName.ValueString()) == nil {
+		return err
+	}
+	// ssh port may have been taken by another process since it was forwarded
+	_, err = virtualboxapi.EnsureForwardedPort(ctx, vminfo.ID, data.SSHRuleName.ValueString(), data.FixedSSHPort.IsNull())
+	return err
+}
+
+// resetToSnapshot powers vm off and restores it from restore_from_snapshot_on_start snapshot.
+// Offline args are applied and saved into the snapshot, online settings and ssh rule
+// are applied again as snapshot may predate them
+func resetToSnapshot(ctx context.Context, data *VirtualboxVMResourceModel, args []string) error {
+	vmName := data.Id.ValueString()
+	snapshot := data.RestoreSnapshot.ValueString()
+	vminfo, err := virtualboxapi.PowerOffVM(ctx, vmName, vmStateTimeouts(data).Stop)
+	if err != nil {
+		return err
+	}
+	sshRule := vminfo.Rule(data.SSHRuleName.ValueString())
+	err = virtualboxapi.ResetToSnapshot(ctx, vmName, snapshot)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		_, err = virtualboxapi.ModifyVM(ctx, vmName, args...)
+		if err != nil {
+			return err
+		}
+		err = virtualboxapi.DeleteSnapshot(ctx, vmName, snapshot)
+		if err != nil {
+			return err
+		}
+		err = virtualboxapi.TakeSnapshot(ctx, vmName, snapshot)
+		if err != nil {
+			return err
+		}
+	}
+	vminfo, err = virtualboxapi.GetVMInfo(ctx, vmName)
+	if err != nil {
+		return err
+	}
+	if sshRule != nil && vminfo.Rule(sshRule.Name) == nil {
+		// keep host port, so ssh_port doesn't change
+		_, err = virtualboxapi.AddForwardingRule(ctx, vmName, *sshRule)
+		if err != nil {
+			return err
+		}
+	}
+	// adapters changed on running vm aren't saved into the snapshot
+	nicArgs := []string{}
+	changes := networkAdapterChanges(data, nil)
+	for _, change := range changes {
+		nicArgs = append(nicArgs, virtualboxapi.NICArgs(change.nic, change.Type, change.Network)...)
+	}
+	if len(nicArgs) > 0 {
+		_, err = virtualboxapi.ModifyVM(ctx, vmName, nicArgs...)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = setNetworkAdapterOptions(ctx, vmName, changes)
+	return err
+}
+
+// updateAttachedDisks attaches and detaches disks according to changed disk_ids,
+// running vm is stopped for it and started again after it
+func updateAttachedDisks(ctx context.Context, data, state *VirtualboxVMResourceModel) error {
+	var planned, prior []string
+	diags := data.DiskIDs.ElementsAs(ctx, &planned, false)
+	diags.Append(state.DiskIDs.ElementsAs(ctx, &prior, false)...)
+	if diags.HasError() {
+		return fmt.Errorf("unable to read disk_ids: %v", diags)
+	}
+	contains := func(ids []string, id string) bool {
+		for _, other := range ids {
+			if other == id {
+				return true
+			}
+		}
+		return false
+	}
+	vmName := data.Id.ValueString()
+	vminfo, err := virtualboxapi.GetVMInfo(ctx, vmName)
+	if err != nil {
+		return err
+	}
+	wasRunning := vminfo.State == virtualboxapi.Running
+	_, err = virtualboxapi.PowerOffVM(ctx, vmName, vmStateTimeouts(data).Stop)
+	if err != nil {
+		return err
+	}
+	for _, diskID := range prior {
+		if contains(planned, diskID) {
+			continue
+		}
+		_, err = virtualboxapi.DetachDisk(ctx, vmName, diskID)
+		if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+			return err
+		}
+	}
+	for _, diskID := range planned {
+		if contains(prior, diskID) {
+			continue
+		}
+		_, err = virtualboxapi.AttachDisk(ctx, vmName, diskID)
+		if err != nil {
+			return err
+		}
+	}
+	if !data.RestoreSnapshot.IsNull() {
+		// restored snapshot has to keep new attachments
+		snapshot := data.RestoreSnapshot.ValueString()
+		err = virtualboxapi.DeleteSnapshot(ctx, vmName, snapshot)
+		if err != nil {
+			return err
+		}
+		err = virtualboxapi.TakeSnapshot(ctx, vmName, snapshot)
+		if err != nil {
+			return err
+		}
+	}
+	if wasRunning {
+		_, err = virtualboxapi.StartVM(ctx, vmName, vmBootType(data), vmStateTimeouts(data).Start)
+	}
+	return err
+}
+
+// detachDisks powers vm off and detaches standalone disks from it
+func detachDisks(ctx context.Context, vmName string, diskIDs []string, stopTimeout time.Duration) error {
+	if len(diskIDs) == 0 {
+		return nil
+	}
+	_, err := virtualboxapi.PowerOffVM(ctx, vmName, stopTimeout)
+	if err != nil {
+		return err
+	}
+	for _, diskID := range diskIDs {
+		_, err = virtualboxapi.DetachDisk(ctx, vmName, diskID)
+		if err != nil && !virtualboxapi.IsObjectNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// pluggedCPUs returns number of plugged cpus, all cpus are plugged unless hot_cpus is set
+func pluggedCPUs(data *VirtualboxVMResourceModel) int {
+	if data.CPUHotplugEnabled.ValueBool() && !data.HotCPUs.IsNull() {
+		return int(data.HotCPUs.ValueInt64())
+	}
+	return int(data.Cpu.ValueInt64())
+}
+
+// vmBootType returns how vm has to be started according to start_mode
+func vmBootType(data *VirtualboxVMResourceModel) virtualboxapi.VMBootType {
+	if data.StartMode.ValueString() == startModeDirect {
+		return virtualboxapi.DirectHeadless
+	}
+	return virtualboxapi.Headless
+}
+
+// vmStateTimeouts returns configured vm state timeouts,
+// defaults are used for state written before timeouts were added
+func vmStateTimeouts(data *VirtualboxVMResourceModel) virtualboxapi.StateTimeouts {
+	timeouts := virtualboxapi.StateTimeouts{
+		Start: virtualboxapi.DefaultStartTimeout,
+		Stop:  virtualboxapi.DefaultStopTimeout,
+	}
+	if data.VMStartTimeout.ValueInt64() > 0 {
+		timeouts.Start = time.Duration(data.VMStartTimeout.ValueInt64()) * time.Second
+	}
+	if data.VMStopTimeout.ValueInt64() > 0 {
+		timeouts.Stop = time.Duration(data.VMStopTimeout.ValueInt64()) * time.Second
+	}
+	return timeouts
+}
+
+// readinessProbe converts probe model into api probe targeting host side of guest port forwarding rule
+func readinessProbe(vmInfo *virtualboxapi.VirtualboxVMInfo, model *VirtualboxVMReadinessProbeModel) virtualboxapi.ReadinessProbe {
+	// durations are checked by schema validators
+	timeout, _ := time.ParseDuration(model.Timeout.ValueString())
+	interval, _ := time.ParseDuration(model.Interval.ValueString())
+	probe := virtualboxapi.ReadinessProbe{
+		Type:               virtualboxapi.ProbeType(model.Type.ValueString()),
+		Host:               "127.0.0.1",
+		Path:               model.Path.ValueString(),
+		ExpectedStatus:     int(model.ExpectedStatus.ValueInt64()),
+		TLS:                model.TLS.ValueBool(),
+		InsecureSkipVerify: model.InsecureSkipVerify.ValueBool(),
+		Timeout:            timeout,
+		Interval:           interval,
+	}
+	rule := vmInfo.RuleForGuestPort(strconv.FormatInt(model.Port.ValueInt64(), 10))
+	if rule != nil {
+		probe.Port = rule.HostPort
+		if rule.HostIP != "" {
+			probe.Host = rule.HostIP
+		}
 	}
+	return probe
 }
 
 func (r *VirtualboxVMResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
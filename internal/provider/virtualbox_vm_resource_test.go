@@ -0,0 +1,515 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+func TestOfflineModifyArgsChipset(t *testing.T) {
+	tests := []struct {
+		name  string
+		plan  types.String
+		state *types.String
+		want  []string
+	}{
+		{
+			// appliance chipset is kept, e.g. ich9 appliance isn't switched to piix3
+			name: "create without chipset in config",
+			plan: types.StringUnknown(),
+			want: []string{},
+		},
+		{
+			name: "create with chipset in config",
+			plan: types.StringValue("ich9"),
+			want: []string{"--chipset", "ich9"},
+		},
+		{
+			name:  "update keeps chipset read from vm",
+			plan:  types.StringValue("ich9"),
+			state: stringPtr(types.StringValue("ich9")),
+			want:  []string{},
+		},
+		{
+			name:  "update with changed chipset",
+			plan:  types.StringValue("ich9"),
+			state: stringPtr(types.StringValue("piix3")),
+			want:  []string{"--chipset", "ich9"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &VirtualboxVMResourceModel{Chipset: tt.plan}
+			var state *VirtualboxVMResourceModel
+			if tt.state != nil {
+				state = &VirtualboxVMResourceModel{Chipset: *tt.state}
+			}
+			got := offlineModifyArgs(context.Background(), plan, state)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func stringPtr(v types.String) *types.String {
+	return &v
+}
+
+// adapterList builds network_adapter value from adapters, empty strings are null
+func adapterList(adapters ...networkAdapter) types.List {
+	elements := []attr.Value{}
+	for _, adapter := range adapters {
+		network, promiscuousMode := types.StringNull(), types.StringNull()
+		if adapter.Network != "" {
+			network = types.StringValue(adapter.Network)
+		}
+		if adapter.PromiscuousMode != "" {
+			promiscuousMode = types.StringValue(adapter.PromiscuousMode)
+		}
+		elements = append(elements, types.ObjectValueMust(networkAdapterAttrTypes, map[string]attr.Value{
+			"type":             types.StringValue(adapter.Type),
+			"network":          network,
+			"promiscuous_mode": promiscuousMode,
+			"cable_connected":  types.BoolValue(adapter.CableConnected),
+			"ip_config":        types.ObjectNull(ipConfigAttrTypes),
+			"ip_address":       types.StringNull(),
+		}))
+	}
+	return types.ListValueMust(types.ObjectType{AttrTypes: networkAdapterAttrTypes}, elements)
+}
+
+// withIPConfig returns network_adapter value with ip_config of i-th adapter set to address
+func withIPConfig(list types.List, i int, address string) types.List {
+	elements := list.Elements()
+	attributes := elements[i].(types.Object).Attributes()
+	attributes["ip_config"] = types.ObjectValueMust(ipConfigAttrTypes, map[string]attr.Value{
+		"address": types.StringValue(address),
+		"netmask": types.StringValue("255.255.255.0"),
+		"gateway": types.StringNull(),
+		"dns":     types.ListNull(types.StringType),
+	})
+	elements[i] = types.ObjectValueMust(networkAdapterAttrTypes, attributes)
+	return types.ListValueMust(types.ObjectType{AttrTypes: networkAdapterAttrTypes}, elements)
+}
+
+// withIPAddress returns network_adapter value with ip_address of i-th adapter set to address
+func withIPAddress(list types.List, i int, address string) types.List {
+	elements := list.Elements()
+	attributes := elements[i].(types.Object).Attributes()
+	attributes["ip_address"] = types.StringValue(address)
+	elements[i] = types.ObjectValueMust(networkAdapterAttrTypes, attributes)
+	return types.ListValueMust(types.ObjectType{AttrTypes: networkAdapterAttrTypes}, elements)
+}
+
+func TestNetworkAdapterArgs(t *testing.T) {
+	hostonly := networkAdapter{Type: "hostonly", Network: "vboxnet0", CableConnected: true}
+	bridged := networkAdapter{Type: "bridged", Network: "eth0", CableConnected: true}
+	none := networkAdapter{Type: "none", CableConnected: true}
+	deny := networkAdapter{Type: "hostonly", Network: "vboxnet0", PromiscuousMode: "deny", CableConnected: true}
+	allowAll := networkAdapter{Type: "hostonly", Network: "vboxnet0", PromiscuousMode: "allow-all", CableConnected: true}
+	unplugged := networkAdapter{Type: "hostonly", Network: "vboxnet0"}
+	tests := []struct {
+		name    string
+		plan    types.List
+		state   *types.List
+		offline []string
+		hot     []networkAdapterChange
+	}{
+		{
+			name: "create keeps appliance adapters when not configured",
+			plan: types.ListUnknown(types.ObjectType{AttrTypes: networkAdapterAttrTypes}),
+		},
+		{
+			name: "create replaces appliance adapters",
+			plan: adapterList(hostonly, none, bridged),
+			offline: []string{
+				"--nic2", "hostonly", "--hostonlyadapter2", "vboxnet0",
+				"--nic3", "none",
+				"--nic4", "bridged", "--bridgeadapter4", "eth0",
+				"--nic5", "none", "--nic6", "none", "--nic7", "none", "--nic8", "none",
+			},
+		},
+		{
+			name:  "unchanged",
+			plan:  adapterList(hostonly),
+			state: listPtr(adapterList(hostonly)),
+		},
+		{
+			name:  "network of existing adapter is changed on running vm",
+			plan:  adapterList(bridged),
+			state: listPtr(adapterList(hostonly)),
+			hot:   []networkAdapterChange{{networkAdapter: bridged, prior: hostonly, nic: 2}},
+		},
+		{
+			name:  "promiscuous mode is changed on running vm",
+			plan:  adapterList(allowAll),
+			state: listPtr(adapterList(deny)),
+			hot:   []networkAdapterChange{{networkAdapter: allowAll, prior: deny, nic: 2}},
+		},
+		{
+			name:  "cable is disconnected on running vm",
+			plan:  adapterList(unplugged),
+			state: listPtr(adapterList(hostonly)),
+			hot:   []networkAdapterChange{{networkAdapter: unplugged, prior: hostonly, nic: 2}},
+		},
+		{
+			name:  "promiscuous mode is kept when not set",
+			plan:  adapterList(hostonly),
+			state: listPtr(adapterList(deny)),
+		},
+		{
+			name:    "added adapter needs restart",
+			plan:    adapterList(hostonly, bridged),
+			state:   listPtr(adapterList(hostonly)),
+			offline: []string{"--nic3", "bridged", "--bridgeadapter3", "eth0"},
+		},
+		{
+			name:    "removed adapter needs restart",
+			plan:    adapterList(),
+			state:   listPtr(adapterList(hostonly)),
+			offline: []string{"--nic2", "none"},
+		},
+		{
+			name:    "adapter replaced by empty slot",
+			plan:    adapterList(none, bridged),
+			state:   listPtr(adapterList(hostonly, hostonly)),
+			offline: []string{"--nic2", "none"},
+			hot:     []networkAdapterChange{{networkAdapter: bridged, prior: hostonly, nic: 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &VirtualboxVMResourceModel{NetworkAdapters: tt.plan}
+			var state *VirtualboxVMResourceModel
+			if tt.state != nil {
+				state = &VirtualboxVMResourceModel{NetworkAdapters: *tt.state}
+			}
+			offline := offlineModifyArgs(context.Background(), plan, state)
+			if len(offline) == 0 {
+				offline = nil
+			}
+			if !reflect.DeepEqual(offline, tt.offline) {
+				t.Errorf("offline args = %q, want %q", offline, tt.offline)
+			}
+			var hot []networkAdapterChange
+			for _, change := range networkAdapterChanges(plan, state) {
+				if !change.replug {
+					hot = append(hot, change)
+				}
+			}
+			if !reflect.DeepEqual(hot, tt.hot) {
+				t.Errorf("hot changes = %+v, want %+v", hot, tt.hot)
+			}
+		})
+	}
+}
+
+func TestNetworkAdaptersValue(t *testing.T) {
+	vminfo := &virtualboxapi.VirtualboxVMInfo{NetworkAdapters: []virtualboxapi.NetworkAdapter{
+		{Index: 1, Attachment: "nat"},
+		{Index: 3, Attachment: "hostonly", Network: "vboxnet0", PromiscuousMode: "deny"},
+		{Index: 4, Attachment: "intnet", Network: "lab", CableConnected: true},
+	}}
+	empty := networkAdapter{Type: "none", CableConnected: true}
+	disconnected := networkAdapter{Type: "hostonly", Network: "vboxnet0", PromiscuousMode: "deny"}
+	intnet := networkAdapter{Type: "intnet", Network: "lab", CableConnected: true}
+	tests := []struct {
+		name  string
+		ips   map[int]string
+		prior types.List
+		want  types.List
+	}{
+		{
+			name:  "gaps are empty slots",
+			prior: types.ListNull(types.ObjectType{AttrTypes: networkAdapterAttrTypes}),
+			want:  adapterList(empty, disconnected, intnet),
+		},
+		{
+			// configured trailing empty slots are kept, so they don't show up as diff
+			name:  "configured empty slots",
+			prior: adapterList(empty, disconnected, intnet, empty),
+			want:  adapterList(empty, disconnected, intnet, empty),
+		},
+		{
+			name:  "ip_config is kept from prior adapters",
+			prior: withIPConfig(adapterList(empty, disconnected), 1, "192.168.56.10"),
+			want:  withIPConfig(adapterList(empty, disconnected, intnet), 1, "192.168.56.10"),
+		},
+		{
+			name:  "ip_address is reported by adapter number",
+			ips:   map[int]string{1: "10.0.2.15", 4: "10.10.0.5"},
+			prior: types.ListNull(types.ObjectType{AttrTypes: networkAdapterAttrTypes}),
+			want:  withIPAddress(adapterList(empty, disconnected, intnet), 2, "10.10.0.5"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := networkAdaptersValue(vminfo, tt.ips, tt.prior); !got.Equal(tt.want) {
+				t.Errorf("network_adapter = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func listPtr(v types.List) *types.List {
+	return &v
+}
+
+func TestOfflineModifyArgsIOAPIC(t *testing.T) {
+	tests := []struct {
+		name  string
+		plan  types.Bool
+		state *types.Bool
+		want  []string
+	}{
+		{
+			name: "create without ioapic in config",
+			plan: types.BoolUnknown(),
+			want: []string{},
+		},
+		{
+			name: "create with ioapic in config",
+			plan: types.BoolValue(true),
+			want: []string{"--ioapic", "on"},
+		},
+		{
+			name:  "update keeps ioapic read from vm",
+			plan:  types.BoolValue(true),
+			state: boolPtr(types.BoolValue(true)),
+			want:  []string{},
+		},
+		{
+			name:  "update with changed ioapic",
+			plan:  types.BoolValue(false),
+			state: boolPtr(types.BoolValue(true)),
+			want:  []string{"--ioapic", "off"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &VirtualboxVMResourceModel{IOAPIC: tt.plan}
+			var state *VirtualboxVMResourceModel
+			if tt.state != nil {
+				state = &VirtualboxVMResourceModel{IOAPIC: *tt.state}
+			}
+			got := offlineModifyArgs(context.Background(), plan, state)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func boolPtr(v types.Bool) *types.Bool {
+	return &v
+}
+
+func TestOfflineModifyArgsVRDE(t *testing.T) {
+	vrde := func(port string, multiConnection types.Bool) *VirtualboxVMVRDEModel {
+		p := types.StringNull()
+		if port != "" {
+			p = types.StringValue(port)
+		}
+		return &VirtualboxVMVRDEModel{Port: p, MultiConnection: multiConnection}
+	}
+	tests := []struct {
+		name   string
+		plan   *VirtualboxVMVRDEModel
+		state  *VirtualboxVMVRDEModel
+		create bool
+		want   []string
+	}{
+		{
+			name:   "create without vrde",
+			create: true,
+			want:   []string{},
+		},
+		{
+			name:   "create with multi connection",
+			plan:   vrde("5000-5050", types.BoolValue(true)),
+			create: true,
+			want:   []string{"--vrde", "on", "--vrdeport", "5000-5050", "--vrdemulticon", "on"},
+		},
+		{
+			name:  "unchanged",
+			plan:  vrde("", types.BoolValue(true)),
+			state: vrde("", types.BoolValue(true)),
+			want:  []string{},
+		},
+		{
+			name:  "multi connection disabled",
+			plan:  vrde("", types.BoolValue(false)),
+			state: vrde("", types.BoolValue(true)),
+			want:  []string{"--vrde", "on", "--vrdemulticon", "off"},
+		},
+		{
+			name:  "vrde removed",
+			state: vrde("3389", types.BoolNull()),
+			want:  []string{"--vrde", "off"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &VirtualboxVMResourceModel{VRDE: tt.plan}
+			var state *VirtualboxVMResourceModel
+			if !tt.create {
+				state = &VirtualboxVMResourceModel{VRDE: tt.state}
+			}
+			got := offlineModifyArgs(context.Background(), plan, state)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateModelFromVMInfoDiskController(t *testing.T) {
+	tests := []struct {
+		name        string
+		state       types.String
+		controllers []virtualboxapi.StorageController
+		want        string
+	}{
+		{
+			name:        "imported sata vm",
+			state:       types.StringNull(),
+			controllers: []virtualboxapi.StorageController{{Name: "IDE", Type: "PIIX4"}, {Name: "SATA", Type: "IntelAhci"}},
+			want:        "sata",
+		},
+		{
+			name:        "imported nvme vm",
+			state:       types.StringNull(),
+			controllers: []virtualboxapi.StorageController{{Name: "IDE", Type: "PIIX4"}, {Name: "NVMe", Type: "NVMe"}},
+			want:        "nvme",
+		},
+		{
+			// disk_controller is applied only on create, controllers added later don't change it
+			name:        "configured value is kept",
+			state:       types.StringValue("sata"),
+			controllers: []virtualboxapi.StorageController{{Name: "SATA", Type: "IntelAhci"}, {Name: "NVMe", Type: "NVMe"}},
+			want:        "sata",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &VirtualboxVMResourceModel{DiskController: tt.state}
+			updateModelFromVMInfo(context.Background(), data, &virtualboxapi.VirtualboxVMInfo{StorageControllers: tt.controllers})
+			if got := data.DiskController.ValueString(); got != tt.want {
+				t.Errorf("disk_controller = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSHPortReplanned(t *testing.T) {
+	state := VirtualboxVMResourceModel{
+		SSHKey:       types.StringValue("~/.ssh/id_ed25519.pub"),
+		SSHRuleName:  types.StringValue("terraform_ssh_port_rule"),
+		FixedSSHPort: types.Int64Null(),
+		State:        types.StringValue("running"),
+		SSHPort:      types.StringValue("7001"),
+	}
+	tests := []struct {
+		name   string
+		modify func(plan, state *VirtualboxVMResourceModel)
+		want   bool
+	}{
+		{
+			name:   "unrelated change keeps port",
+			modify: func(plan, state *VirtualboxVMResourceModel) { plan.Memory = types.Int64Value(4096) },
+		},
+		{
+			name:   "ssh key changed",
+			modify: func(plan, state *VirtualboxVMResourceModel) { plan.SSHKey = types.StringValue("~/.ssh/id_rsa.pub") },
+			want:   true,
+		},
+		{
+			name:   "rule renamed",
+			modify: func(plan, state *VirtualboxVMResourceModel) { plan.SSHRuleName = types.StringValue("ssh") },
+			want:   true,
+		},
+		{
+			name:   "fixed port set",
+			modify: func(plan, state *VirtualboxVMResourceModel) { plan.FixedSSHPort = types.Int64Value(2222) },
+			want:   true,
+		},
+		{
+			name:   "stopped vm is started",
+			modify: func(plan, state *VirtualboxVMResourceModel) { state.State = types.StringValue("poweroff") },
+			want:   true,
+		},
+		{
+			name:   "empty port of older version",
+			modify: func(plan, state *VirtualboxVMResourceModel) { state.SSHPort = types.StringValue("") },
+			want:   true,
+		},
+		{
+			name:   "vm without ssh forwarding",
+			modify: func(plan, state *VirtualboxVMResourceModel) { state.SSHPort = types.StringNull() },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, prior := state, state
+			tt.modify(&plan, &prior)
+			if got := sshPortReplanned(&plan, &prior); got != tt.want {
+				t.Errorf("sshPortReplanned = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateModelFromVMInfoPowerState(t *testing.T) {
+	sshRule := virtualboxapi.PortForwardingRule{Name: "terraform_ssh_port_rule", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: "7001", GuestPort: "22"}
+	tests := []struct {
+		name       string
+		vminfo     virtualboxapi.VirtualboxVMInfo
+		powerState string
+		sshPort    types.String
+	}{
+		{
+			// vm created with state = "poweroff" gets ssh port on first start
+			name:       "stopped vm without ssh rule",
+			vminfo:     virtualboxapi.VirtualboxVMInfo{State: virtualboxapi.Poweroff},
+			powerState: "poweroff",
+			sshPort:    types.StringNull(),
+		},
+		{
+			name:       "running vm",
+			vminfo:     virtualboxapi.VirtualboxVMInfo{State: virtualboxapi.Running, ForwardingRules: []virtualboxapi.PortForwardingRule{sshRule}},
+			powerState: "running",
+			sshPort:    types.StringValue("7001"),
+		},
+		{
+			name:       "teleported vm",
+			vminfo:     virtualboxapi.VirtualboxVMInfo{State: virtualboxapi.Teleported, ForwardingRules: []virtualboxapi.PortForwardingRule{sshRule}},
+			powerState: "poweroff",
+			sshPort:    types.StringValue("7001"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &VirtualboxVMResourceModel{
+				SSHRuleName: types.StringValue("terraform_ssh_port_rule"),
+				State:       types.StringValue("running"),
+			}
+			updateModelFromVMInfo(context.Background(), data, &tt.vminfo)
+			if got := data.PowerState.ValueString(); got != tt.powerState {
+				t.Errorf("power_state = %q, want %q", got, tt.powerState)
+			}
+			if !data.SSHPort.Equal(tt.sshPort) {
+				t.Errorf("ssh_port = %s, want %s", data.SSHPort, tt.sshPort)
+			}
+			// state changed outside of Terraform is not reverted, so it's never refreshed
+			if got := data.State.ValueString(); got != "running" {
+				t.Errorf("state = %q, want running", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// vmResourceSchemaVersion is the current virtualbox_vm schema version
+const vmResourceSchemaVersion = 2
+
+// vmResourceV1Defaults holds values of attributes which didn't exist in version 0 schema
+// (id, name, image, ssh_user, ssh_key, cpu, memory, ssh_port). Computed attributes
+// without default are null and get filled by the following Read.
+var vmResourceV1Defaults = map[string]interface{}{
+	"ssh_rule_name":                  virtualboxapi.SshPortRuleName,
+	"name_prefix":                    nil,
+	"ssh_key_rehash":                 true,
+	"fixed_ssh_port":                 nil,
+	"base_disk_uuid":                 nil,
+	"disk_controller":                "sata",
+	"os_disk":                        nil,
+	"guest_network_manager":          "netplan",
+	"guest_additions_iso":            nil,
+	"user_data":                      nil,
+	"user_data_base64":               nil,
+	"import_extra_args":              nil,
+	"accept_ova_eula":                false,
+	"disk_ids":                       nil,
+	"machine_folder":                 nil,
+	"restore_from_snapshot_on_start": nil,
+	"disk_format":                    nil,
+	"disk_cache_mode":                diskCacheDefault,
+	"config_file":                    nil,
+	"machine_readable_info_raw":      nil,
+	"disk_usage_mb":                  nil,
+	"disk_missing":                   nil,
+	"detected_os_type":               nil,
+	"declared_memory":                nil,
+	"declared_cpus":                  nil,
+	"state":                          vmStateRunning,
+	"power_state":                    nil,
+	"compact_disk_on_destroy":        false,
+	"compact_disk_on_stop":           false,
+	"network_adapter":                nil,
+	"vm_start_timeout":               int64(virtualboxapi.DefaultStartTimeout / time.Second),
+	"vm_stop_timeout":                int64(virtualboxapi.DefaultStopTimeout / time.Second),
+	"wait_for_guest_additions":       false,
+	"guest_additions_timeout":        int64(virtualboxapi.DefaultGuestAdditionsTimeout / time.Second),
+	"delete_behavior":                deleteBehaviorDelete,
+	"start_mode":                     startModeStartVM,
+	"chipset":                        nil,
+	"firmware":                       nil,
+	"tpm":                            "none",
+	"rtc_use_utc":                    nil,
+	"hpet":                           nil,
+	"ioapic":                         nil,
+	"cpu_hotplug_enabled":            false,
+	"hot_cpus":                       nil,
+	"cpu_profile":                    nil,
+	"monitor_count":                  1,
+	"vram":                           nil,
+	"readiness_probe":                nil,
+	"teleport":                       nil,
+	"vrde":                           nil,
+	"install_from_iso":               nil,
+}
+
+// vmResourceV2Defaults holds schema defaults which were applied by code in version 1,
+// null values written by version 1 are replaced by them to avoid spurious diffs
+var vmResourceV2Defaults = map[string]interface{}{
+	"ssh_user": defaultSSHUser,
+	"state":    vmStateRunning,
+}
+
+func (r *VirtualboxVMResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// PriorSchema is omitted on purpose: some version 0 states were written
+		// by builds which already had part of new attributes, raw JSON handles both
+		0: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgraded, err := upgradeVMStateV0(req.RawState.JSON)
+				if err == nil {
+					upgraded, err = upgradeVMStateV1(upgraded)
+				}
+				if err != nil {
+					resp.Diagnostics.AddError("Unable to upgrade virtualbox_vm state", err.Error())
+					return
+				}
+				resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+			},
+		},
+		1: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				upgraded, err := upgradeVMStateV1(req.RawState.JSON)
+				if err != nil {
+					resp.Diagnostics.AddError("Unable to upgrade virtualbox_vm state", err.Error())
+					return
+				}
+				resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+			},
+		},
+	}
+}
+
+// decodeVMState decodes raw state JSON into map
+func decodeVMState(rawState []byte) (map[string]interface{}, error) {
+	state := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(rawState))
+	// keep numbers exactly as they are stored
+	decoder.UseNumber()
+	err := decoder.Decode(&state)
+	return state, err
+}
+
+// upgradeVMStateV0 adds attributes missing from version 0 state, attributes already present are kept as is
+func upgradeVMStateV0(rawState []byte) ([]byte, error) {
+	state, err := decodeVMState(rawState)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range vmResourceV1Defaults {
+		if _, ok := state[name]; !ok {
+			state[name] = value
+		}
+	}
+	return json.Marshal(state)
+}
+
+// upgradeVMStateV1 replaces null or missing values of attributes, which got schema defaults in version 2.
+// Version 1 states written by older builds also miss attributes added later, they are added as in version 0
+func upgradeVMStateV1(rawState []byte) ([]byte, error) {
+	state, err := decodeVMState(rawState)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range vmResourceV1Defaults {
+		if _, ok := state[name]; !ok {
+			state[name] = value
+		}
+	}
+	for name, value := range vmResourceV2Defaults {
+		if state[name] == nil {
+			state[name] = value
+		}
+	}
+	return json.Marshal(state)
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+// upgradeVMState runs state upgrader of given version on fixture from testdata
+func upgradeVMState(t *testing.T, version int64, fixture string) map[string]interface{} {
+	t.Helper()
+	ctx := context.Background()
+	raw, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &VirtualboxVMResource{}
+	upgrader, ok := r.UpgradeState(ctx)[version]
+	if !ok {
+		t.Fatalf("no state upgrader for version %d", version)
+	}
+	req := resource.UpgradeStateRequest{RawState: &tfprotov6.RawState{JSON: raw}}
+	resp := &resource.UpgradeStateResponse{}
+	upgrader.StateUpgrader(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("upgrade failed: %v", resp.Diagnostics)
+	}
+
+	// upgraded state has to be decodable with the current schema
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	if _, err := resp.DynamicValue.Unmarshal(schemaResp.Schema.Type().TerraformType(ctx)); err != nil {
+		t.Fatalf("upgraded state doesn't match schema: %v", err)
+	}
+	upgraded := map[string]interface{}{}
+	if err := json.Unmarshal(resp.DynamicValue.JSON, &upgraded); err != nil {
+		t.Fatal(err)
+	}
+	for name := range schemaResp.Schema.Attributes {
+		if _, ok := upgraded[name]; !ok {
+			t.Errorf("upgraded state misses attribute %s", name)
+		}
+	}
+	return upgraded
+}
+
+func TestUpgradeVMState(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int64
+		fixture string
+		want    map[string]interface{}
+	}{
+		{
+			name:    "v0",
+			version: 0,
+			fixture: "vm_state_v0.json",
+			want: map[string]interface{}{
+				"id":               "1b2f5d3e-0000-4000-8000-000000000001",
+				"ssh_user":         "ubuntu",
+				"ssh_key":          "~/.ssh/id_ed25519.pub",
+				"cpu":              float64(2),
+				"memory":           float64(2048),
+				"ssh_port":         "7001",
+				"ssh_rule_name":    "terraform_ssh_port_rule",
+				"ssh_key_rehash":   true,
+				"state":            "running",
+				"delete_behavior":  "delete",
+				"chipset":          nil,
+				"tpm":              "none",
+				"vm_start_timeout": float64(120),
+			},
+		},
+		{
+			// some version 0 states were written by builds which already had part of new attributes
+			name:    "v0 with newer attributes",
+			version: 0,
+			fixture: "vm_state_v0_partial.json",
+			want: map[string]interface{}{
+				"ssh_user":        "root",
+				"ssh_key":         nil,
+				"ssh_rule_name":   "custom_ssh_rule",
+				"delete_behavior": "unregister",
+				"state":           "running",
+			},
+		},
+		{
+			name:    "v1",
+			version: 1,
+			fixture: "vm_state_v1.json",
+			want: map[string]interface{}{
+				"ssh_user":         "root",
+				"ssh_key_rehash":   false,
+				"fixed_ssh_port":   float64(2222),
+				"state":            "running",
+				"power_state":      "running",
+				"chipset":          "ich9",
+				"tpm":              "none",
+				"install_from_iso": nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgraded := upgradeVMState(t, tt.version, tt.fixture)
+			for name, want := range tt.want {
+				if got := upgraded[name]; got != want {
+					t.Errorf("%s = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
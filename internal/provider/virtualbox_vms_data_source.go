@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &VirtualboxVMsDataSource{}
+
+func NewVirtualboxVMsDataSource() datasource.DataSource {
+	return &VirtualboxVMsDataSource{}
+}
+
+// VirtualboxVMsDataSource defines the data source implementation.
+type VirtualboxVMsDataSource struct {
+	client *http.Client
+}
+
+// VirtualboxVMsDataSourceModel describes the data source data model.
+type VirtualboxVMsDataSourceModel struct {
+	Id          types.String                  `tfsdk:"id"`
+	ManagedOnly types.Bool                    `tfsdk:"managed_only"`
+	VMs         []VirtualboxRegisteredVMModel `tfsdk:"vms"`
+}
+
+// VirtualboxRegisteredVMModel describes vm registered in VirtualBox.
+type VirtualboxRegisteredVMModel struct {
+	Name    types.String `tfsdk:"name"`
+	ID      types.String `tfsdk:"id"`
+	Managed types.Bool   `tfsdk:"managed"`
+}
+
+func (d *VirtualboxVMsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vms"
+}
+
+func (d *VirtualboxVMsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Lists vms registered in VirtualBox. Vms created by provider are marked with " +
+			fmt.Sprintf("`%s` extradata, which tells them apart from vms of other tools like Vagrant.", virtualboxapi.ManagedMarkerKey),
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Data source identifier",
+				Computed:            true,
+			},
+			"managed_only": schema.BoolAttribute{
+				MarkdownDescription: "List only vms created by provider. `false` by default.",
+				Optional:            true,
+			},
+			"vms": schema.ListNestedAttribute{
+				MarkdownDescription: "Registered vms",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Vm name",
+							Computed:            true,
+						},
+						"id": schema.StringAttribute{
+							MarkdownDescription: "Vm UUID",
+							Computed:            true,
+						},
+						"managed": schema.BoolAttribute{
+							MarkdownDescription: "Whether vm was created by provider",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *VirtualboxVMsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*http.Client)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *VirtualboxVMsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	defer logStats(ctx, "virtualbox_vms Read")
+
+	var data VirtualboxVMsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vms, err := virtualboxapi.ListVMs(ctx)
+	if err != nil {
+		addError(&resp.Diagnostics, "Error listing vms", err)
+		return
+	}
+
+	data.Id = types.StringValue("vms")
+	data.VMs = []VirtualboxRegisteredVMModel{}
+	for _, vm := range vms {
+		managed, err := virtualboxapi.IsManaged(ctx, vm.ID)
+		if virtualboxapi.IsObjectNotFound(err) {
+			// vm was unregistered after listing
+			continue
+		}
+		if err != nil {
+			addError(&resp.Diagnostics, "Error reading vm extradata", err)
+			return
+		}
+		if data.ManagedOnly.ValueBool() && !managed {
+			continue
+		}
+		data.VMs = append(data.VMs, VirtualboxRegisteredVMModel{
+			Name:    types.StringValue(vm.Name),
+			ID:      types.StringValue(vm.ID),
+			Managed: types.BoolValue(managed),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
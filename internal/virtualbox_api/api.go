@@ -5,15 +5,25 @@ package virtualboxapi
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/net"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 type VMBootType string
@@ -21,11 +31,68 @@ type VMStateType string
 type NetworkType string
 
 const (
+	// SshPortRuleName is the default name of NAT rule used for ssh forwarding
 	SshPortRuleName = "terraform_ssh_port_rule"
+	// ProbePortRuleName is the name of temporary NAT rule used by readiness probe
+	ProbePortRuleName = "terraform_probe_port_rule"
+	// ProviderRulePrefix is name prefix of NAT rules created by provider
+	ProviderRulePrefix = "terraform_"
+	// DefaultPortRangeStart and DefaultPortRangeEnd limit host ports used by ForwardLocalPort by default
+	DefaultPortRangeStart = 7000
+	DefaultPortRangeEnd   = 8000
+	// MinPortRangeSize is the smallest allowed host ports range
+	MinPortRangeSize = 100
+	// SSHKeyMarkerKey is vm extradata key holding hash of injected ssh key
+	SSHKeyMarkerKey = "terraform/ssh_key_sha256"
+	// ManagedMarkerKey is vm extradata key set on vms created by provider
+	ManagedMarkerKey = "terraform/managed"
+	// CreatingMarkerKey is vm extradata key set while provider creates vm, vm which still has it
+	// was left by interrupted create, see ListOrphanedArtifacts
+	CreatingMarkerKey = "terraform/creating"
 )
 
+// VirtualBox result codes, which could be found in VBoxManage stderr
+const (
+	ObjectNotFound = "VBOX_E_OBJECT_NOT_FOUND"
+	FileError      = "VBOX_E_FILE_ERROR"
+	InvalidVMState = "VBOX_E_INVALID_VM_STATE"
+	ObjectInUse    = "VBOX_E_OBJECT_IN_USE"
+	InvalidArg     = "E_INVALIDARG"
+	AccessDenied   = "E_ACCESSDENIED"
+)
+
+// VBoxManage messages printed together with usage text when command line is rejected
+const (
+	UnknownOption = "Unknown option"
+	SyntaxError   = "Syntax error"
+)
+
+// maxErrorOutputLines limits command output included in errors, rejected
+// command line makes VBoxManage print usage of whole subcommand
+const maxErrorOutputLines = 20
+
+const (
+	// DefaultStartTimeout is how long StartVM waits for vm to become running by default
+	DefaultStartTimeout = 120 * time.Second
+	// DefaultStopTimeout is how long StopVM waits for vm to power off by default
+	DefaultStopTimeout = 60 * time.Second
+	// DefaultCommandTimeout is how long single VBoxManage call may run by default
+	DefaultCommandTimeout = 120 * time.Second
+	statePollInterval     = 500 * time.Millisecond
+)
+
+// StateTimeouts configures how long vm state transitions are awaited
+type StateTimeouts struct {
+	Start time.Duration
+	Stop  time.Duration
+}
+
 const (
 	Poweroff VMStateType = "poweroff"
+	Running  VMStateType = "running"
+	Aborted  VMStateType = "aborted"
+	// Teleported is state of source vm after it was teleported to another host
+	Teleported VMStateType = "teleported"
 )
 
 const (
@@ -33,6 +100,8 @@ const (
 	Headless VMBootType = "headless"
 	Sdl      VMBootType = "sdl"
 	Separate VMBootType = "separate"
+	// DirectHeadless runs VBoxHeadless detached instead of `VBoxManage startvm`
+	DirectHeadless VMBootType = "direct"
 )
 
 const (
@@ -44,272 +113,2334 @@ const (
 	Natnetwork  NetworkType = "natnetwork"
 )
 
+type PortForwardingRule struct {
+	Name      string
+	Protocol  string
+	HostIP    string
+	HostPort  string
+	GuestIP   string
+	GuestPort string
+}
+
+// Promiscuous modes of network adapter
+const (
+	PromiscDeny     = "deny"
+	PromiscAllowVMs = "allow-vms"
+	PromiscAllowAll = "allow-all"
+)
+
+// NetworkAdapter describes enabled network adapter of vm
+type NetworkAdapter struct {
+	// Index is adapter number, starting from 1
+	Index int
+	// Attachment is network type, e.g. "nat" or "bridged"
+	Attachment string
+	// SpeedKbps is reported link speed, 0 means VirtualBox default
+	SpeedKbps       int
+	PromiscuousMode string
+	CableConnected  bool
+	// MACAddress is reported by showvminfo as 12 uppercase hex digits, e.g. "080027A1B2C3"
+	MACAddress string
+	// Network is host interface of hostonly and bridged adapters or network name of intnet
+	// and natnetwork ones, empty for other attachments
+	Network string
+	// TraceFile is network trace file, empty when tracing is off
+	TraceFile string
+}
+
+type StorageController struct {
+	Name      string
+	Type      string
+	PortCount int
+}
+
+// DevicesPerPort returns how many devices can be attached to single controller port
+func (ctl *StorageController) DevicesPerPort() int {
+	switch ctl.Type {
+	case "PIIX3", "PIIX4", "ICH6":
+		// IDE controllers have master and slave devices
+		return 2
+	}
+	return 1
+}
+
+// StorageAttachment describes medium attached to storage controller slot
+type StorageAttachment struct {
+	Controller string
+	Port       int
+	Device     int
+	// Medium is file path, "none" for empty slot or "emptydrive" for empty optical drive
+	Medium string
+}
+
+// IsDisk reports whether attached medium looks like hard disk image
+func (a StorageAttachment) IsDisk() bool {
+	if a.Medium == "" || a.Medium == "none" || a.Medium == "emptydrive" {
+		return false
+	}
+	switch strings.ToLower(path.Ext(a.Medium)) {
+	case ".iso", ".dmg", ".img":
+		return false
+	}
+	return true
+}
+
 type VirtualboxVMInfo struct {
-	ID       string
-	Name     string
-	State    VMStateType
-	VmdkPath string
-	SSHPort  string
+	ID              string
+	Name            string
+	State           VMStateType
+	ForwardingRules []PortForwardingRule
+	Chipset         string
+	RTCUseUTC       bool
+	HPET            bool
+	CPUs            int
+	CPUHotplug      bool
+	Memory          int
+	MonitorCount    int
+	VRAM            int
+	ConfigFile      string
+	ACPI            bool
+	IOAPIC          bool
+	HWVirtEx        bool
+	NestedPaging    bool
+	LargePages      bool
+	// Firmware is "bios" or "efi", EFI variants are reported as "efi"
+	Firmware string
+	// TPMType is "none" when vm has no TPM or VirtualBox is older than 7.0
+	TPMType string
+	// ParavirtProvider is configured paravirtualization interface, e.g. "default", "kvm" or "none"
+	ParavirtProvider string
+	// NetworkAdapters lists enabled network adapters ordered by index
+	NetworkAdapters []NetworkAdapter
+	// VmdkPath is the first attached disk image in boot order, see Disks
+	VmdkPath           string
+	StorageControllers []StorageController
+	// Attachments lists all storage attachments in showvminfo order
+	Attachments []StorageAttachment
+	// StorageAttachments maps "<controller>-<port>-<device>" to attached medium
+	StorageAttachments map[string]string
+	VRDEEnabled        bool
+	// VRDEPort is port VRDP server listens on, configured port range when server isn't listening
+	VRDEPort     string
+	VRDEAuthType string
+	// VRDEMultiConnection reports whether VRDP server accepts several connections at once
+	VRDEMultiConnection bool
+	// VRDEAddress is address VRDP server actually listens on, set only for running vm with VRDE enabled
+	VRDEAddress string
+	// Groups lists vm groups, e.g. "/production/web", vm outside of any group is in "/"
+	Groups []string
+	// Raw is unparsed `showvminfo --machinereadable` output
+	Raw string
+}
+
+// Rule returns forwarding rule with given name, or nil if there is no such rule
+func (info *VirtualboxVMInfo) Rule(ruleName string) *PortForwardingRule {
+	for i, rule := range info.ForwardingRules {
+		if rule.Name == ruleName {
+			return &info.ForwardingRules[i]
+		}
+	}
+	return nil
+}
+
+// RuleForGuestPort returns tcp forwarding rule for given guest port, or nil if port isn't forwarded
+func (info *VirtualboxVMInfo) RuleForGuestPort(guestPort string) *PortForwardingRule {
+	for i, rule := range info.ForwardingRules {
+		if rule.Protocol == "tcp" && rule.GuestPort == guestPort {
+			return &info.ForwardingRules[i]
+		}
+	}
+	return nil
+}
+
+// HostPort returns host port of forwarding rule with given name,
+// or empty string if there is no such rule
+func (info *VirtualboxVMInfo) HostPort(ruleName string) string {
+	if rule := info.Rule(ruleName); rule != nil {
+		return rule.HostPort
+	}
+	return ""
+}
+
+// VBoxManageError describes failed external command (usually VBoxManage)
+type VBoxManageError struct {
+	Command  []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *VBoxManageError) Error() string {
+	return fmt.Sprintf(
+		"command %q exited with code %d: %s",
+		commandLine(e.Command),
+		e.ExitCode,
+		strings.TrimSpace(e.Stderr),
+	)
+}
+
+// truncateOutput shortens command output to maxLines non-empty lines. Lines mentioning error are kept
+// wherever they are, older VBoxManage prints syntax error after usage text, the rest is filled by first lines
+func truncateOutput(output string, maxLines int) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) <= maxLines {
+		return output
+	}
+	keep := make([]bool, len(lines))
+	kept := 0
+	for i, line := range lines {
+		if kept < maxLines && strings.Contains(strings.ToLower(line), "error") {
+			keep[i] = true
+			kept++
+		}
+	}
+	for i := 0; i < len(lines) && kept < maxLines; i++ {
+		if !keep[i] {
+			keep[i] = true
+			kept++
+		}
+	}
+	result := []string{}
+	skipped := 0
+	for i, line := range lines {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		if skipped > 0 {
+			result = append(result, fmt.Sprintf("... %d lines skipped", skipped))
+			skipped = 0
+		}
+		result = append(result, line)
+	}
+	if skipped > 0 {
+		result = append(result, fmt.Sprintf("... %d lines skipped", skipped))
+	}
+	return strings.Join(result, "\n")
+}
+
+// HasCode reports whether command failed with given VirtualBox result code,
+// e.g. VBOX_E_OBJECT_NOT_FOUND
+func (e *VBoxManageError) HasCode(code string) bool {
+	return strings.Contains(e.Stderr, code)
+}
+
+// portRangeStart and portRangeEnd limit host ports used by ForwardLocalPort, see SetPortRange
+var (
+	portRangeStart = DefaultPortRangeStart
+	portRangeEnd   = DefaultPortRangeEnd
+)
+
+// commandTimeout is how long single VBoxManage call may run, see SetCommandTimeout
+var commandTimeout = DefaultCommandTimeout
+
+// tmpDir is directory for temporary copies of disk images, see SetTmpDir
+var tmpDir = os.TempDir()
+
+// tmpDirConfigured disables choosing temporary directory by disk location, see diskTmpDir
+var tmpDirConfigured bool
+
+// virtSysprepOperations and virtSysprepExtraArgs customize virt-sysprep run by InjectSSHKey,
+// see SetVirtSysprepOptions
+var (
+	virtSysprepOperations = DefaultVirtSysprepOperations
+	virtSysprepExtraArgs  = []string{}
+)
+
+// DefaultVirtSysprepOperations only injects ssh key, other default virt-sysprep
+// operations (e.g. removal of ssh host keys and logs) are not run
+var DefaultVirtSysprepOperations = []string{"ssh-inject"}
+
+// SetVirtSysprepOptions sets operations and extra arguments of virt-sysprep run by InjectSSHKey
+func SetVirtSysprepOptions(operations, extraArgs []string) error {
+	if len(operations) == 0 {
+		return errors.New("at least one virt-sysprep operation is required")
+	}
+	virtSysprepOperations = operations
+	virtSysprepExtraArgs = extraArgs
+	return nil
+}
+
+// ErrNotFound is returned when object is missing from VBoxManage list output
+var ErrNotFound = errors.New("object not found")
+
+// IsObjectNotFound reports whether err means that requested
+// VirtualBox object (vm, medium, etc.) doesn't exist
+func IsObjectNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	var vboxErr *VBoxManageError
+	return errors.As(err, &vboxErr) && vboxErr.HasCode(ObjectNotFound)
+}
+
+// CheckVBoxManage checks that VBoxManage is on PATH. Remote host is checked by the first command instead
+func CheckVBoxManage() error {
+	if IsRemote() {
+		return nil
+	}
+	_, err := exec.LookPath("VBoxManage")
+	if err != nil {
+		return fmt.Errorf("CheckVBoxManage: %w", err)
+	}
+	return nil
+}
+
+// commandRunner runs prepared command and returns its stdout, failed VBoxManage is reported
+// as *VBoxManageError. Tests replace it to fake VBoxManage output
+var commandRunner = execCommand
+
+// runGetOutput runs command and returns its stdout, VBoxManage is killed
+// when ctx is done or when it runs longer than commandTimeout
+func runGetOutput(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	return commandRunner(ctx, cmd)
+}
+
+// execCommand is the default commandRunner executing command on local or remote host
+func execCommand(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	applyCommandUser(cmd)
+	// arguments of VBoxManage itself are reported, not of ssh running it on remote host
+	args := cmd.Args
+	cmd = remoteCommand(cmd)
+	isVBoxManage := len(args) > 0 && args[0] == "VBoxManage"
+	if isVBoxManage && commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+	// Stdin is left nil, so commands read from null device and never wait for
+	// interactive input, e.g. license prompt of import
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		done := make(chan error, 1)
+		go func() {
+			done <- cmd.Wait()
+		}()
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+			<-done
+			recordCommand(args, time.Since(start))
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && isVBoxManage {
+				return stdout.String(), fmt.Errorf("VBoxManage timed out after %d seconds: %s", int(commandTimeout/time.Second), commandLine(args))
+			}
+			return stdout.String(), fmt.Errorf("%s: %w", commandLine(args), ctx.Err())
+		}
+	}
+	recordCommand(args, time.Since(start))
+	if err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		stderrStr := stderr.String()
+		if strings.TrimSpace(stderrStr) == "" {
+			// some subcommands print usage to stdout only
+			stderrStr = stdout.String()
+		}
+		if strings.TrimSpace(stderrStr) == "" {
+			// command wasn't started at all (e.g. not found in PATH)
+			stderrStr = err.Error()
+		}
+		stderrStr = truncateOutput(stderrStr, maxErrorOutputLines)
+		return stdout.String(), &VBoxManageError{
+			Command:  RedactArgs(args),
+			ExitCode: exitCode,
+			Stderr:   stderrStr,
+		}
+	}
+	return stdout.String(), nil
+}
+
+// SetPortRange sets host ports range used by ForwardLocalPort
+func SetPortRange(start, end int) error {
+	if start <= 0 || end > 65535 {
+		return fmt.Errorf("port range %d-%d is out of 1-65535", start, end)
+	}
+	if end-start < MinPortRangeSize {
+		return fmt.Errorf("port range %d-%d must contain at least %d ports", start, end, MinPortRangeSize)
+	}
+	portRangeStart = start
+	portRangeEnd = end
+	return nil
+}
+
+// SetCommandTimeout limits how long single VBoxManage call may run, zero disables limit
+func SetCommandTimeout(timeout time.Duration) {
+	commandTimeout = timeout
+}
+
+// CreateVM imports vm from image into baseFolder, VirtualBox default machine folder
+// is used when baseFolder is empty. acceptEULA accepts license agreement of appliance,
+// extraArgs are appended to import command as is
+func CreateVM(ctx context.Context, imagePath, vmName, baseFolder string, memory, cpus int64, acceptEULA bool, extraArgs []string) (*VirtualboxVMInfo, error) {
+	args := []string{
+		"import",
+		imagePath,
+		"--vsys=0",
+		fmt.Sprintf("--vmname=%s", vmName),
+		fmt.Sprintf("--memory=%d", memory),
+		fmt.Sprintf("--cpus=%d", cpus),
+	}
+	if acceptEULA {
+		args = append(args, "--eula=accept")
+	}
+	if baseFolder != "" {
+		args = append(args, fmt.Sprintf("--basefolder=%s", baseFolder))
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		append(args, extraArgs...)...,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVM: import failed for %q: %w", vmName, err)
+	}
+	err = enableNatLocalhost(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVM: %w", err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// IsVBoxFile reports whether image is vm settings file, which is registered by RegisterVM instead of imported
+func IsVBoxFile(imagePath string) bool {
+	return strings.EqualFold(filepath.Ext(imagePath), ".vbox")
+}
+
+// vboxFileMachine is the part of .vbox settings file RegisterVM needs
+type vboxFileMachine struct {
+	Machine struct {
+		UUID string `xml:"uuid,attr"`
+		Name string `xml:"name,attr"`
+	} `xml:"Machine"`
+}
+
+// RegisterVM registers existing vm from its .vbox settings file, vm files are used in place
+func RegisterVM(ctx context.Context, vboxFilePath string) (*VirtualboxVMInfo, error) {
+	if IsRemote() {
+		return nil, fmt.Errorf("RegisterVM: %w", ErrNotSupportedRemotely)
+	}
+	// registervm prints nothing, vm UUID is read from settings file
+	content, err := os.ReadFile(vboxFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("RegisterVM: %w", err)
+	}
+	settings := vboxFileMachine{}
+	err = xml.Unmarshal(content, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("RegisterVM: parsing %s failed: %w", vboxFilePath, err)
+	}
+	vmID := strings.Trim(settings.Machine.UUID, "{}")
+	if vmID == "" {
+		return nil, fmt.Errorf("RegisterVM: %s has no machine UUID", vboxFilePath)
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"registervm",
+		vboxFilePath,
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("RegisterVM: registervm failed for %q: %w", vboxFilePath, err)
+	}
+	err = enableNatLocalhost(ctx, vmID)
+	if err != nil {
+		_ = UnregisterVM(ctx, vmID)
+		return nil, fmt.Errorf("RegisterVM: %w", err)
+	}
+	return GetVMInfo(ctx, vmID)
+}
+
+// DiskController is type of storage controller created for disk of vm created by CreateVMFromDisk
+type DiskController string
+
+const (
+	DiskControllerSATA DiskController = "sata"
+	DiskControllerNVMe DiskController = "nvme"
+)
+
+// NVMeVersion is the oldest VirtualBox version NVMe disk controller is supported with
+var NVMeVersion = Version{Major: 6, Minor: 1}
+
+// storagectlArgs returns controller name and storagectl arguments adding the controller
+func (c DiskController) storagectlArgs() (string, []string) {
+	if c == DiskControllerNVMe {
+		return "NVMe", []string{"--add=pcie", "--controller=NVMe", "--portcount=4"}
+	}
+	return "SATA", []string{"--add=sata", "--portcount=4"}
+}
+
+// CreateVMFromDisk creates vm with NAT network adapter and copy of registered base disk attached
+// to controller of given type, base disk is referenced by UUID and is left untouched
+func CreateVMFromDisk(ctx context.Context, baseDiskUUID, vmName, baseFolder string, memory, cpus int64, controller DiskController) (*VirtualboxVMInfo, error) {
+	baseDisk, err := GetMediumInfo(ctx, baseDiskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: base disk %s is not registered: %w", baseDiskUUID, err)
+	}
+	args := []string{
+		"createvm",
+		fmt.Sprintf("--name=%s", vmName),
+		"--register",
+	}
+	if baseFolder != "" {
+		args = append(args, fmt.Sprintf("--basefolder=%s", baseFolder))
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: createvm failed for %q: %w", vmName, err)
+	}
+	vminfo, err := ModifyVM(
+		ctx,
+		vmName,
+		fmt.Sprintf("--memory=%d", memory),
+		fmt.Sprintf("--cpus=%d", cpus),
+		"--nic1=nat",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: %w", err)
+	}
+	err = enableNatLocalhost(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: %w", err)
+	}
+	controllerName, controllerArgs := controller.storagectlArgs()
+	cmd = exec.Command(
+		"VBoxManage",
+		append([]string{"storagectl", vmName, fmt.Sprintf("--name=%s", controllerName)}, controllerArgs...)...,
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: storagectl failed for %q: %w", vmName, err)
+	}
+	// disk copy is kept in vm folder, so it's deleted together with vm
+	diskPath := filepath.Join(filepath.Dir(vminfo.ConfigFile), vmName+"."+strings.ToLower(baseDisk.Format))
+	err = ConvertDisk(ctx, baseDisk.ID, diskPath, baseDisk.Format)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: %w", err)
+	}
+	cmd = exec.Command(
+		"VBoxManage",
+		"storageattach",
+		vmName,
+		fmt.Sprintf("--storagectl=%s", controllerName),
+		"--port=0",
+		"--device=0",
+		"--type=hdd",
+		fmt.Sprintf("--medium=%s", diskPath),
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("CreateVMFromDisk: storageattach failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// UnattendedOptions describes guest installation performed by `VBoxManage unattended install`,
+// empty fields are left to VirtualBox defaults
+type UnattendedOptions struct {
+	ISOPath      string
+	Username     string
+	Password     string
+	Hostname     string
+	Country      string
+	Locale       string
+	TimeZone     string
+	Proxy        string
+	AdditionsISO string
+}
+
+var unattendedOSTypeRegexp = regexp.MustCompile(`(?m)^OSTypeId="([^"]*)"`)
+
+// UnattendedInstall prepares powered off vm for automated guest installation from ISO,
+// installation itself runs on the next vm start. Vm OS type is set to the one detected from ISO,
+// VirtualBox picks installation scripts by it
+func UnattendedInstall(ctx context.Context, vmName string, opts UnattendedOptions) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"unattended",
+		"detect",
+		fmt.Sprintf("--iso=%s", opts.ISOPath),
+		"--machine-readable",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("UnattendedInstall: unattended detect failed for %q: %w", opts.ISOPath, err)
+	}
+	if match := unattendedOSTypeRegexp.FindStringSubmatch(stdout); match != nil && match[1] != "" {
+		_, err = ModifyVM(ctx, vmName, fmt.Sprintf("--ostype=%s", match[1]))
+		if err != nil {
+			return fmt.Errorf("UnattendedInstall: %w", err)
+		}
+	}
+	args := []string{
+		"unattended",
+		"install",
+		vmName,
+		fmt.Sprintf("--iso=%s", opts.ISOPath),
+	}
+	for _, option := range []struct{ flag, value string }{
+		{"--user", opts.Username},
+		{"--password", opts.Password},
+		{"--hostname", opts.Hostname},
+		{"--country", opts.Country},
+		{"--locale", opts.Locale},
+		{"--time-zone", opts.TimeZone},
+		{"--proxy", opts.Proxy},
+	} {
+		if option.value != "" {
+			args = append(args, fmt.Sprintf("%s=%s", option.flag, option.value))
+		}
+	}
+	if opts.AdditionsISO != "" {
+		args = append(args, "--install-additions", fmt.Sprintf("--additions-iso=%s", opts.AdditionsISO))
+	}
+	_, err = runGetOutput(ctx, exec.Command("VBoxManage", args...))
+	if err != nil {
+		return fmt.Errorf("UnattendedInstall: unattended install failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// enableNatLocalhost lets guest reach host localhost through NAT of first adapter
+func enableNatLocalhost(ctx context.Context, vmName string) error {
+	flag, err := ModifyVMFlag(ctx, OptionNatLocalhostReachable, 1)
+	if IsUnsupportedOption(err) {
+		// localhost is always reachable from NAT before 7.0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"modifyvm",
+		vmName,
+		flag,
+		"on",
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("modifyvm failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// Startvm retry policy, attempts are separated by startRetryDelay
+const (
+	startRetryAttempts = 3
+	startRetryDelay    = 2 * time.Second
+)
+
+// retryableStartErrors are stderr fragments of transient startvm failures, see AddRetryableStartErrors:
+//   - vm process dies during startup, on Linux desktops because of DISPLAY/session race
+//   - COM/XPCOM IPC initialization race, seen on CI hosts
+var retryableStartErrors = []string{
+	"has terminated unexpectedly during startup",
+	"VERR_MAIN_CONFIG_CONSTRUCTOR_COM_ERROR",
+}
+
+// AddRetryableStartErrors adds stderr fragments of startvm failures which are retried
+func AddRetryableStartErrors(patterns ...string) {
+	retryableStartErrors = append(retryableStartErrors, patterns...)
+}
+
+// retryableStartError returns fragment of retryableStartErrors found in startvm error,
+// or empty string if error isn't transient
+func retryableStartError(err error) string {
+	var vboxErr *VBoxManageError
+	if !errors.As(err, &vboxErr) {
+		return ""
+	}
+	for _, pattern := range retryableStartErrors {
+		if pattern != "" && strings.Contains(vboxErr.Stderr, pattern) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// StartVM starts vm and waits up to timeout for it to become running
+func StartVM(ctx context.Context, vmName string, vmType VMBootType, timeout time.Duration) (*VirtualboxVMInfo, error) {
+	if vmType == DirectHeadless {
+		if IsRemote() {
+			return nil, fmt.Errorf("StartVM: direct headless start of %q: %w", vmName, ErrNotSupportedRemotely)
+		}
+		return startHeadlessDirect(ctx, vmName, timeout)
+	}
+	args := []string{
+		"startvm",
+		vmName,
+		fmt.Sprintf("--type=%s", vmType),
+	}
+	_, err := runGetOutput(ctx, exec.Command("VBoxManage", args...))
+	for attempt := 1; attempt <= startRetryAttempts; attempt++ {
+		pattern := retryableStartError(err)
+		if pattern == "" {
+			break
+		}
+		tflog.Warn(ctx, "startvm failed with transient error, retrying", map[string]interface{}{
+			"vm":      vmName,
+			"error":   pattern,
+			"attempt": attempt,
+		})
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("StartVM: %w", ctx.Err())
+		case <-time.After(startRetryDelay):
+		}
+		_, err = runGetOutput(ctx, exec.Command("VBoxManage", args...))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("StartVM: startvm failed for %q: %w", vmName, err)
+	}
+	return WaitForState(ctx, vmName, timeout, Running)
+}
+
+// startHeadlessDirect launches VBoxHeadless in its own process group,
+// so vm keeps running after provider exits
+func startHeadlessDirect(ctx context.Context, vmName string, timeout time.Duration) (*VirtualboxVMInfo, error) {
+	cmd := exec.Command(
+		"VBoxHeadless",
+		"--startvm",
+		vmName,
+	)
+	applyCommandUser(cmd)
+	detachProcess(cmd)
+	err := cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("StartVM: VBoxHeadless failed for %q: %w", vmName, err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		// reap process, VBoxHeadless exits when vm is powered off
+		exited <- cmd.Wait()
+	}()
+	vminfo, err := WaitForState(ctx, vmName, timeout, Running)
+	if err != nil {
+		select {
+		case exitErr := <-exited:
+			return nil, fmt.Errorf("StartVM: VBoxHeadless exited for %q: %v: %w", vmName, exitErr, err)
+		default:
+		}
+		return nil, fmt.Errorf("StartVM: %w", err)
+	}
+	return vminfo, nil
+}
+
+// StopVM powers vm off and waits up to timeout until it reaches poweroff state,
+// controlvm returns before machine is actually unlocked
+func StopVM(ctx context.Context, vmName string, timeout time.Duration) (*VirtualboxVMInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"controlvm",
+		vmName,
+		"poweroff",
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("StopVM: poweroff failed for %q: %w", vmName, err)
+	}
+	return WaitForState(ctx, vmName, timeout, Poweroff, Aborted)
+}
+
+// ModifyVM changes vm settings, vm must be powered off
+func ModifyVM(ctx context.Context, vmName string, args ...string) (*VirtualboxVMInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		append([]string{"modifyvm", vmName}, args...)...,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ModifyVM: modifyvm failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// ModifyVMOffline changes settings which require powered off vm,
+// running vm is stopped before modification and started again after it
+func ModifyVMOffline(ctx context.Context, vmName string, bootType VMBootType, timeouts StateTimeouts, args ...string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("ModifyVMOffline: %w", err)
+	}
+	wasRunning := vminfo.State == Running
+	if wasRunning {
+		_, err = StopVM(ctx, vmName, timeouts.Stop)
+		if err != nil {
+			return nil, fmt.Errorf("ModifyVMOffline: %w", err)
+		}
+	}
+	vminfo, err = ModifyVM(ctx, vmName, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ModifyVMOffline: %w", err)
+	}
+	if wasRunning {
+		return StartVM(ctx, vmName, bootType, timeouts.Start)
+	}
+	return vminfo, nil
+}
+
+// OnOff converts bool to VBoxManage switch value
+func OnOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
+
+// WaitForState polls vm info until vm reaches one of given states
+func WaitForState(ctx context.Context, vmName string, timeout time.Duration, states ...VMStateType) (*VirtualboxVMInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(statePollInterval)
+	defer ticker.Stop()
+	for {
+		vminfo, err := GetVMInfo(ctx, vmName)
+		if err != nil {
+			return nil, fmt.Errorf("WaitForState: %w", err)
+		}
+		for _, state := range states {
+			if vminfo.State == state {
+				return vminfo, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Timeout waiting for vm %s to reach state %v, current state: %s", vmName, states, vminfo.State)
+		case <-ticker.C:
+		}
+	}
+}
+
+func DeleteVM(ctx context.Context, vmName string) error {
+	// VBoxManage unregistervm <uuid | vmname> [--delete] [--delete-all]
+	cmd := exec.Command(
+		"VBoxManage",
+		"unregistervm",
+		vmName,
+		"--delete",
+		"--delete-all",
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("DeleteVM: unregistervm failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// UnregisterVM removes vm from VirtualBox, leaving its files on disk
+func UnregisterVM(ctx context.Context, vmName string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"unregistervm",
+		vmName,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("UnregisterVM: unregistervm failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// PowerOffVM stops vm unless it is already powered off
+func PowerOffVM(ctx context.Context, vmName string, timeout time.Duration) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("PowerOffVM: %w", err)
+	}
+	if vminfo.State == Poweroff || vminfo.State == Aborted {
+		return vminfo, nil
+	}
+	return StopVM(ctx, vmName, timeout)
+}
+
+func DestroyVM(ctx context.Context, vmName string, stopTimeout time.Duration) error {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		// Machine is possibly already destroyed
+		return fmt.Errorf("DestroyVM: %w", err)
+	}
+	if vminfo.State != Poweroff && vminfo.State != Aborted {
+		_, _ = StopVM(ctx, vmName, stopTimeout)
+		// we can't do anything at this point,
+		// so just ignoring error
+	}
+	return DeleteVM(ctx, vmName)
+}
+
+func vmInfoValueToString(value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	if value[0] == '"' {
+		value = value[1:]
+	}
+	if len(value) > 0 && value[len(value)-1] == '"' {
+		value = value[:len(value)-1]
+	}
+	return value
+}
+
+func GetVmIp(ctx context.Context, vminfo *VirtualboxVMInfo) (string, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"guestproperty",
+		"enumerate",
+		vminfo.ID,
+		"/VirtualBox/GuestInfo/Net/0/V4/IP",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("GetVmIp: guestproperty enumerate failed for %q: %w", vminfo.ID, err)
+	}
+	// example output:
+	// /VirtualBox/GuestInfo/Net/0/V4/IP = '192.168.1.157' @ 2023-02-04T21:42:09.082Z
+	ip := strings.Split(stdout, " ")[2]
+	return ip[1 : len(ip)-1], nil
+}
+
+func GetVMInfo(ctx context.Context, vmName string) (*VirtualboxVMInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"showvminfo",
+		vmName,
+		"--machinereadable",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("GetVMInfo: showvminfo failed for %q: %w", vmName, err)
+	}
+	result := &VirtualboxVMInfo{
+		StorageAttachments: map[string]string{},
+		TPMType:            "none",
+		Raw:                stdout,
+	}
+	controllers := map[string]*StorageController{}
+	controllerIndexes := []string{}
+	controller := func(index string) *StorageController {
+		if _, ok := controllers[index]; !ok {
+			controllers[index] = &StorageController{}
+			controllerIndexes = append(controllerIndexes, index)
+		}
+		return controllers[index]
+	}
+	attachments := []StorageAttachment{}
+	adapters := map[int]*NetworkAdapter{}
+	tracing := map[int]bool{}
+	adapter := func(index int) *NetworkAdapter {
+		if _, ok := adapters[index]; !ok {
+			adapters[index] = &NetworkAdapter{Index: index}
+		}
+		return adapters[index]
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		keyValue := strings.SplitN(line, "=", 2)
+		if len(keyValue) < 2 {
+			// It's either a subkey without value or value with different format
+			// https://docs.oracle.com/en/virtualization/virtualbox/6.0/user/vboxmanage-showvminfo.html
+			continue
+		}
+		key := vmInfoValueToString(keyValue[0])
+		value := vmInfoValueToString(keyValue[1])
+		if forwardingKeyRegexp.MatchString(key) {
+			// example values, guest ip is empty unless rule targets specific guest address:
+			// "terraform_ssh_port_rule,tcp,127.0.0.1,7001,,22"
+			// "web,tcp,,8080,10.0.2.15,80"
+			// fields are taken by position, so empty host or guest ip doesn't shift them
+			splited := strings.Split(value, ",")
+			if len(splited) != 6 {
+				continue
+			}
+			result.ForwardingRules = append(result.ForwardingRules, PortForwardingRule{
+				Name:      splited[0],
+				Protocol:  splited[1],
+				HostIP:    splited[2],
+				HostPort:  splited[3],
+				GuestIP:   splited[4],
+				GuestPort: splited[5],
+			})
+			continue
+		}
+		if index, ok := cutPrefix(key, "storagecontrollername"); ok {
+			controller(index).Name = value
+			continue
+		}
+		if index, ok := cutPrefix(key, "storagecontrollertype"); ok {
+			controller(index).Type = value
+			continue
+		}
+		if index, ok := cutPrefix(key, "storagecontrollerportcount"); ok {
+			controller(index).PortCount, _ = strconv.Atoi(value)
+			continue
+		}
+		if attachment, ok := parseStorageAttachment(key, value); ok {
+			attachments = append(attachments, attachment)
+			continue
+		}
+		if match := nicKeyRegexp.FindStringSubmatch(key); match != nil {
+			// regexp guarantees number
+			index, _ := strconv.Atoi(match[2])
+			switch match[1] {
+			case "nic":
+				adapter(index).Attachment = value
+			case "nicspeed":
+				adapter(index).SpeedKbps, _ = strconv.Atoi(value)
+			case "nicpromisc":
+				adapter(index).PromiscuousMode = value
+			case "nictrace":
+				tracing[index] = value == "on"
+			case "nictracefile":
+				adapter(index).TraceFile = value
+			case "macaddress":
+				adapter(index).MACAddress = value
+			case "hostonlyadapter", "bridgeadapter", "intnet", "nat-network":
+				adapter(index).Network = value
+			case "cableconnected":
+				adapter(index).CableConnected = value == "on"
+			}
+			continue
+		}
+		switch key {
+		case "name":
+			result.Name = value
+		case "UUID":
+			result.ID = value
+		case "chipset":
+			result.Chipset = value
+		case "firmware":
+			result.Firmware = "bios"
+			if strings.HasPrefix(strings.ToUpper(value), "EFI") {
+				result.Firmware = "efi"
+			}
+		case "tpm_type":
+			result.TPMType = value
+		case "groups":
+			result.Groups = strings.Split(value, ",")
+		case "rtcuseutc":
+			result.RTCUseUTC = value == "on"
+		case "hpet":
+			result.HPET = value == "on"
+		case "acpi":
+			result.ACPI = value == "on"
+		case "ioapic":
+			result.IOAPIC = value == "on"
+		case "paravirtprovider":
+			result.ParavirtProvider = value
+		case "hwvirtex":
+			result.HWVirtEx = value == "on"
+		case "nestedpaging":
+			result.NestedPaging = value == "on"
+		case "largepages":
+			result.LargePages = value == "on"
+		case "cpus":
+			result.CPUs, _ = strconv.Atoi(value)
+		case "cpuhotplug":
+			result.CPUHotplug = value == "on"
+		case "memory":
+			result.Memory, _ = strconv.Atoi(value)
+		case "monitorcount":
+			result.MonitorCount, _ = strconv.Atoi(value)
+		case "VRAM":
+			result.VRAM, _ = strconv.Atoi(value)
+		case "CfgFile":
+			result.ConfigFile = value
+		case "VMState":
+			result.State = VMStateType(value)
+		case "vrde":
+			result.VRDEEnabled = value == "on"
+		case "vrdeports":
+			// active port is reported separately as vrdeport
+			if result.VRDEPort == "" {
+				result.VRDEPort = value
+			}
+		case "vrdeport":
+			// -1 means server isn't listening
+			if value != "-1" && value != "0" {
+				result.VRDEPort = value
+			}
+		case "vrdeauthtype":
+			result.VRDEAuthType = value
+		case "vrdemulticon":
+			result.VRDEMultiConnection = value == "on"
+		}
+	}
+	if result.VRDEEnabled && result.State == Running {
+		result.VRDEAddress, err = getVRDEAddress(ctx, vmName)
+		if err != nil {
+			return nil, fmt.Errorf("GetVMInfo: %w", err)
+		}
+	}
+	nicIndexes := []int{}
+	for index := range adapters {
+		nicIndexes = append(nicIndexes, index)
+	}
+	sort.Ints(nicIndexes)
+	for _, index := range nicIndexes {
+		nic := adapters[index]
+		if nic.Attachment == "" || nic.Attachment == "none" {
+			continue
+		}
+		if !tracing[index] {
+			nic.TraceFile = ""
+		}
+		result.NetworkAdapters = append(result.NetworkAdapters, *nic)
+	}
+	for _, index := range controllerIndexes {
+		result.StorageControllers = append(result.StorageControllers, *controllers[index])
+	}
+	for _, attachment := range attachments {
+		// controller names may contain anything, including "-<n>-<n>" suffixes,
+		// so only attachments of known controllers are trusted
+		if len(controllers) > 0 && result.controller(attachment.Controller) == nil {
+			continue
+		}
+		result.Attachments = append(result.Attachments, attachment)
+		result.StorageAttachments[fmt.Sprintf("%s-%d-%d", attachment.Controller, attachment.Port, attachment.Device)] = attachment.Medium
+	}
+	if disks := result.Disks(); len(disks) > 0 {
+		result.VmdkPath = disks[0].Medium
+	}
+	return result, nil
+}
+
+// NetworkAdapter returns enabled network adapter with given index, or nil if adapter is disabled
+func (info *VirtualboxVMInfo) NetworkAdapter(index int) *NetworkAdapter {
+	for i, nic := range info.NetworkAdapters {
+		if nic.Index == index {
+			return &info.NetworkAdapters[i]
+		}
+	}
+	return nil
+}
+
+// controller returns storage controller with given name, or nil if there is no such controller
+func (info *VirtualboxVMInfo) controller(name string) *StorageController {
+	for i, ctl := range info.StorageControllers {
+		if ctl.Name == name {
+			return &info.StorageControllers[i]
+		}
+	}
+	return nil
+}
+
+var (
+	forwardingKeyRegexp        = regexp.MustCompile(`^Forwarding\(\d+\)$`)
+	storageAttachmentKeyRegexp = regexp.MustCompile(`^(.+)-(\d+)-(\d+)$`)
+	nicKeyRegexp               = regexp.MustCompile(`^(nic|nicspeed|nicpromisc|nictrace|nictracefile|cableconnected|macaddress|hostonlyadapter|bridgeadapter|intnet|nat-network)(\d+)$`)
+)
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// parseStorageAttachment parses "<controller>-<port>-<device>" showvminfo key,
+// e.g. "NVMe Controller-0-0"="/path/disk.vmdk"
+func parseStorageAttachment(key, value string) (StorageAttachment, bool) {
+	match := storageAttachmentKeyRegexp.FindStringSubmatch(key)
+	if match == nil || strings.HasSuffix(match[1], "-ImageUUID") {
+		return StorageAttachment{}, false
+	}
+	port, _ := strconv.Atoi(match[2])
+	device, _ := strconv.Atoi(match[3])
+	return StorageAttachment{
+		Controller: match[1],
+		Port:       port,
+		Device:     device,
+		Medium:     value,
+	}, true
+}
+
+// ForwardLocalPort forwards hostPort of 127.0.0.1 to guestPort of vm through NAT of the first adapter,
+// free port of configured range is used when hostPort is 0
+func ForwardLocalPort(ctx context.Context, vmName, ruleName string, hostPort, guestPort int) (*VirtualboxVMInfo, error) {
+	port, err := hostPortForRule(hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("ForwardLocalPort: unable to use host port for %q: %w", vmName, err)
+	}
+
+	// Make sure to configure the network interface to NAT
+	cmd := exec.Command(
+		"VBoxManage",
+		"modifyvm",
+		vmName,
+		"--nic1",
+		"nat",
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ForwardLocalPort: switching nic1 to nat failed for %q: %w", vmName, err)
+	}
+
+	// Create a forwarded port mapping to the VM
+	return AddForwardingRule(ctx, vmName, PortForwardingRule{
+		Name:      ruleName,
+		Protocol:  "tcp",
+		HostIP:    "127.0.0.1",
+		HostPort:  strconv.Itoa(port),
+		GuestPort: strconv.Itoa(guestPort),
+	})
+}
+
+// freeLocalPort returns free tcp port of 127.0.0.1 within configured port range.
+// Port is released before returning, so it may be taken by another process until vm binds it
+func freeLocalPort() (int, error) {
+	port, err := net.ListenRangeConfig{
+		Addr:    "127.0.0.1",
+		Min:     portRangeStart,
+		Max:     portRangeEnd,
+		Network: "tcp",
+	}.Listen(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	port.Listener.Close()
+	return port.Port, nil
+}
+
+// AddForwardingRule adds NAT rule to first network adapter,
+// running vms are reconfigured on the fly
+func AddForwardingRule(ctx context.Context, vmName string, rule PortForwardingRule) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("AddForwardingRule: %w", err)
+	}
+	spec := strings.Join([]string{
+		rule.Name,
+		rule.Protocol,
+		rule.HostIP,
+		rule.HostPort,
+		rule.GuestIP,
+		rule.GuestPort,
+	}, ",")
+	args := []string{"modifyvm", vmName, "--natpf1", spec}
+	if vminfo.State == Running {
+		// controlvm takes subcommands without dashes
+		args = []string{"controlvm", vmName, "natpf1", spec}
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("AddForwardingRule: natpf1 failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// DeleteForwardingRule removes NAT rule from first network adapter,
+// running vms are reconfigured on the fly
+func DeleteForwardingRule(ctx context.Context, vmName, ruleName string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteForwardingRule: %w", err)
+	}
+	args := []string{"modifyvm", vmName, "--natpf1", "delete", ruleName}
+	if vminfo.State == Running {
+		// controlvm takes subcommands without dashes
+		args = []string{"controlvm", vmName, "natpf1", "delete", ruleName}
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteForwardingRule: natpf1 delete %q failed for %q: %w", ruleName, vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// SetCableConnected connects or disconnects network cable of network adapter,
+// running vms are reconfigured on the fly
+func SetCableConnected(ctx context.Context, vmName string, nic int, connected bool) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("SetCableConnected: %w", err)
+	}
+	state := OnOff(connected)
+	flag, err := ModifyVMFlag(ctx, OptionCableConnected, nic)
+	if err != nil {
+		return nil, fmt.Errorf("SetCableConnected: %w", err)
+	}
+	args := []string{"modifyvm", vmName, flag, state}
+	if vminfo.State == Running {
+		args = []string{"controlvm", vmName, fmt.Sprintf("setlinkstate%d", nic), state}
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("SetCableConnected: changing cable state failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// SetPromiscuousMode changes promiscuous mode of network adapter,
+// running vms are reconfigured on the fly
+func SetPromiscuousMode(ctx context.Context, vmName string, nic int, mode string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("SetPromiscuousMode: %w", err)
+	}
+	args := []string{"modifyvm", vmName, fmt.Sprintf("--nicpromisc%d", nic), mode}
+	if vminfo.State == Running {
+		args = []string{"controlvm", vmName, fmt.Sprintf("nicpromisc%d", nic), mode}
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("SetPromiscuousMode: nicpromisc failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// nicNetworkFlags holds modifyvm flags selecting network of attachment types, which need one
+var nicNetworkFlags = map[string]string{
+	"hostonly":   "--hostonlyadapter%d",
+	"bridged":    "--bridgeadapter%d",
+	"intnet":     "--intnet%d",
+	"natnetwork": "--nat-network%d",
+}
+
+// NICArgs returns modifyvm arguments attaching network adapter to network, see NetworkAdapter.Network.
+// Attachment "none" removes adapter
+func NICArgs(nic int, attachment, network string) []string {
+	args := []string{fmt.Sprintf("--nic%d", nic), attachment}
+	if flag, ok := nicNetworkFlags[attachment]; ok && network != "" {
+		args = append(args, fmt.Sprintf(flag, nic), network)
+	}
+	return args
+}
+
+// SetNICAttachment changes network of network adapter, running vms are reconfigured on the fly.
+// Adapters can't be added to or removed from running vm, use NICArgs with ModifyVMOffline for it
+func SetNICAttachment(ctx context.Context, vmName string, nic int, attachment, network string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("SetNICAttachment: %w", err)
+	}
+	args := append([]string{"modifyvm", vmName}, NICArgs(nic, attachment, network)...)
+	if vminfo.State == Running {
+		if attachment == "none" || vminfo.NetworkAdapter(nic) == nil {
+			return nil, fmt.Errorf("SetNICAttachment: adapter %d can't be added to or removed from running vm %s", nic, vmName)
+		}
+		// controlvm takes network as positional argument
+		args = []string{"controlvm", vmName, fmt.Sprintf("nic%d", nic), attachment}
+		if _, ok := nicNetworkFlags[attachment]; ok && network != "" {
+			args = append(args, network)
+		}
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("SetNICAttachment: changing adapter %d failed for %q: %w", nic, vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// SetPluggedCPUs changes number of plugged cpus of vm with cpu hotplug enabled from current to target,
+// cpus are plugged and unplugged in order of their ids. Running vms are reconfigured
+// on the fly, guest has to support cpu hotplug. Cpu 0 is never unplugged
+func SetPluggedCPUs(ctx context.Context, vmName string, current, target int) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("SetPluggedCPUs: %w", err)
+	}
+	if !vminfo.CPUHotplug {
+		return nil, fmt.Errorf("SetPluggedCPUs: cpu hotplug is disabled for vm %s", vmName)
+	}
+	cpuCommand := func(action string, cpu int) *exec.Cmd {
+		if vminfo.State == Running {
+			// controlvm takes subcommands without dashes
+			return exec.Command("VBoxManage", "controlvm", vmName, action, strconv.Itoa(cpu))
+		}
+		return exec.Command("VBoxManage", "modifyvm", vmName, "--"+action, strconv.Itoa(cpu))
+	}
+	for cpu := current; cpu < target; cpu++ {
+		_, err = runGetOutput(ctx, cpuCommand("plugcpu", cpu))
+		if err != nil {
+			return nil, fmt.Errorf("SetPluggedCPUs: plugcpu failed for %q: %w", vmName, err)
+		}
+	}
+	for cpu := current - 1; cpu >= target && cpu > 0; cpu-- {
+		_, err = runGetOutput(ctx, cpuCommand("unplugcpu", cpu))
+		if err != nil {
+			return nil, fmt.Errorf("SetPluggedCPUs: unplugcpu failed for %q: %w", vmName, err)
+		}
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// GetExtraData returns vm extradata value, or empty string if key is not set
+func GetExtraData(ctx context.Context, vmName, key string) (string, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"getextradata",
+		vmName,
+		key,
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("GetExtraData: getextradata failed for %q: %w", vmName, err)
+	}
+	// example output:
+	// Value: 0b9c2a...
+	// or "No value set!" if key is missing
+	value, ok := cutPrefix(strings.TrimSpace(stdout), "Value: ")
+	if !ok {
+		return "", nil
+	}
+	return value, nil
+}
+
+// SetExtraData sets vm extradata value, empty value deletes key
+func SetExtraData(ctx context.Context, vmName, key, value string) error {
+	args := []string{"setextradata", vmName, key}
+	if value != "" {
+		args = append(args, value)
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		args...,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("SetExtraData: setextradata failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// sshKeyMarker returns hash of injected key, user is included
+// so that key is injected again for another user
+func sshKeyMarker(sshUser, sshKey string, customizeArgs []string) (string, error) {
+	key, err := os.ReadFile(sshKey)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(sshUser + "\n"))
+	hash.Write(key)
+	for _, arg := range customizeArgs {
+		hash.Write([]byte("\n" + arg))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// InjectSSHKey adds public key to authorized_keys of guest user. Injection is skipped
+// when vm extradata marker shows that the same key was already injected for the user.
+// osDisk selects disk with guest operating system, see FindOSDisk. customizeArgs are passed to
+// the same virt-sysprep call, e.g. GuestNetworkArgs, so disk image is copied only once
+func InjectSSHKey(ctx context.Context, vmName, osDisk, sshUser, sshKey string, customizeArgs []string) error {
+	if IsRemote() {
+		return fmt.Errorf("InjectSSHKey: %w", ErrNotSupportedRemotely)
+	}
+	marker, injected, err := sshKeyInjected(ctx, vmName, sshUser, sshKey, customizeArgs)
+	if err != nil || injected {
+		return err
+	}
+	err = injectSSHKey(ctx, vmName, osDisk, sshUser, sshKey, customizeArgs)
+	if err != nil {
+		return err
+	}
+	err = SetExtraData(ctx, vmName, SSHKeyMarkerKey, marker)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: %w", err)
+	}
+	return nil
+}
+
+// InjectSSHKeyOffline injects ssh key like InjectSSHKey into powered off vm, so disk image is modified
+// in place instead of copy. Running vm is stopped before injection and started again after it,
+// vm isn't stopped when the same key is already injected
+func InjectSSHKeyOffline(ctx context.Context, vmName, osDisk, sshUser, sshKey string, customizeArgs []string, bootType VMBootType, timeouts StateTimeouts) error {
+	if IsRemote() {
+		return fmt.Errorf("InjectSSHKeyOffline: %w", ErrNotSupportedRemotely)
+	}
+	_, injected, err := sshKeyInjected(ctx, vmName, sshUser, sshKey, customizeArgs)
+	if err != nil || injected {
+		return err
+	}
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKeyOffline: %w", err)
+	}
+	wasRunning := vminfo.State == Running
+	if wasRunning {
+		_, err = StopVM(ctx, vmName, timeouts.Stop)
+		if err != nil {
+			return fmt.Errorf("InjectSSHKeyOffline: %w", err)
+		}
+	}
+	err = InjectSSHKey(ctx, vmName, osDisk, sshUser, sshKey, customizeArgs)
+	if err != nil {
+		return err
+	}
+	if wasRunning {
+		_, err = StartVM(ctx, vmName, bootType, timeouts.Start)
+		if err != nil {
+			return fmt.Errorf("InjectSSHKeyOffline: %w", err)
+		}
+	}
+	return nil
+}
+
+// sshKeyInjected returns marker of the key and whether vm extradata shows that it was already injected
+func sshKeyInjected(ctx context.Context, vmName, sshUser, sshKey string, customizeArgs []string) (string, bool, error) {
+	marker, err := sshKeyMarker(sshUser, sshKey, customizeArgs)
+	if err != nil {
+		return "", false, fmt.Errorf("InjectSSHKey: reading ssh key failed: %w", err)
+	}
+	injected, err := GetExtraData(ctx, vmName, SSHKeyMarkerKey)
+	if err != nil {
+		return "", false, fmt.Errorf("InjectSSHKey: %w", err)
+	}
+	return marker, injected == marker, nil
+}
+
+func injectSSHKey(ctx context.Context, vmName, osDisk, sshUser, sshKey string, customizeArgs []string) error {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: %w", err)
+	}
+	diskPath, err := FindOSDisk(ctx, vminfo, osDisk)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: %w", err)
+	}
+	if vminfo.State != Running {
+		// nothing writes to disk of stopped vm, so it's modified in place. Path is passed
+		// as separate argument without shell, spaces like in "VirtualBox VMs" are fine
+		return runVirtSysprep(ctx, vmName, diskPath, sshUser, sshKey, customizeArgs)
+	}
+	return injectSSHKeyIntoCopy(ctx, vmName, diskPath, sshUser, sshKey, customizeArgs)
+}
+
+// injectSSHKeyIntoCopy modifies temporary copy of disk image of running vm, which then replaces image
+func injectSSHKeyIntoCopy(ctx context.Context, vmName, diskPath, sshUser, sshKey string, customizeArgs []string) error {
+	imageName := path.Base(diskPath)
+
+	input, err := os.Open(diskPath)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: opening disk image failed: %w", err)
+	}
+	defer input.Close()
+	inputInfo, err := input.Stat()
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: opening disk image failed: %w", err)
+	}
+
+	// both temporary copy and replacement made next to image need space for the whole image
+	copyDir := diskTmpDir(diskPath)
+	for _, dir := range []string{copyDir, filepath.Dir(diskPath)} {
+		err = checkFreeSpace(dir, inputInfo.Size())
+		if err != nil {
+			return fmt.Errorf("InjectSSHKey: %w", err)
+		}
+	}
+
+	dst, err := os.CreateTemp(copyDir, "*-"+strings.ReplaceAll(imageName, " ", "_"))
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: creating temporary disk copy failed: %w", err)
+	}
+	tmpPath := dst.Name()
+	defer os.Remove(tmpPath)
+	defer dst.Close()
+
+	_, err = io.Copy(dst, input)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: copying disk image failed: %w", err)
+	}
+	err = dst.Sync()
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: copying disk image failed: %w", err)
+	}
+
+	err = runVirtSysprep(ctx, vmName, tmpPath, sshUser, sshKey, customizeArgs)
+	if err != nil {
+		return err
+	}
+
+	_, err = dst.Seek(0, 0)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: copying disk image back failed: %w", err)
+	}
+	// modified image is copied next to original one and renamed over it,
+	// so failed copy never leaves original image partially overwritten
+	err = replaceFile(diskPath, dst, inputInfo.Mode())
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: copying disk image back failed: %w", err)
+	}
+	return nil
+}
+
+// runVirtSysprep injects ssh key into disk image with virt-sysprep
+func runVirtSysprep(ctx context.Context, vmName, diskPath, sshUser, sshKey string, customizeArgs []string) error {
+	operations := virtSysprepOperations
+	if len(customizeArgs) > 0 && !hasOperation(operations, "customize") {
+		// customizeArgs are only applied by customize operation
+		operations = append(append([]string{}, operations...), "customize")
+	}
+	args := []string{
+		"-a",
+		diskPath,
+		"--operations",
+		strings.Join(operations, ","),
+		"--ssh-inject",
+		fmt.Sprintf("%s:file:%s", sshUser, sshKey),
+	}
+	args = append(args, customizeArgs...)
+	cmd := exec.Command(
+		"virt-sysprep",
+		append(args, virtSysprepExtraArgs...)...,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("InjectSSHKey: virt-sysprep failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// checkFreeSpace fails when filesystem of dir has less than needed bytes available,
+// so disk image copy fails early instead of filling filesystem. Check is skipped
+// where free space can't be detected
+func checkFreeSpace(dir string, needed int64) error {
+	available, ok := availableBytes(dir)
+	if !ok || available >= uint64(needed) {
+		return nil
+	}
+	return fmt.Errorf("not enough free space in %s to copy disk image: %d MB needed, %d MB available, set tmp_dir to another directory",
+		dir, needed/(1024*1024), available/(1024*1024))
+}
+
+// hasOperation reports whether virt-sysprep operations list contains operation
+func hasOperation(operations []string, operation string) bool {
+	for _, o := range operations {
+		if o == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceFile atomically replaces file at target path with content of src
+func replaceFile(target string, src io.Reader, mode os.FileMode) error {
+	output, err := os.CreateTemp(path.Dir(target), "."+path.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	_, err = io.Copy(output, src)
+	if err != nil {
+		return err
+	}
+	err = output.Chmod(mode)
+	if err != nil {
+		return err
+	}
+	err = output.Sync()
+	if err != nil {
+		return err
+	}
+	err = output.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(output.Name(), target)
+}
+
+// SetTmpDir sets directory used for temporary copies of disk images
+func SetTmpDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+	tmpDir = dir
+	tmpDirConfigured = true
+	return nil
 }
 
-func runGetOutput(cmd *exec.Cmd) (string, string, error) {
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	return stdout.String(), stderr.String(), err
+// diskTmpDir returns directory for temporary copy of disk image. Copying image from NFS
+// to local temporary directory sends it over network twice, so copy is made next to image
+// unless temporary directory is configured explicitly
+func diskTmpDir(diskPath string) string {
+	dir := filepath.Dir(diskPath)
+	if tmpDirConfigured || !isNFS(dir) {
+		return tmpDir
+	}
+	return dir
 }
 
-func CreateVM(imagePath, vmName string, memory, cpus int64) (*VirtualboxVMInfo, error) {
+// GetSystemProperties returns parsed `VBoxManage list systemproperties` output
+func GetSystemProperties(ctx context.Context) (map[string]string, error) {
 	cmd := exec.Command(
 		"VBoxManage",
-		"import",
-		imagePath,
-		"--vsys=0",
-		fmt.Sprintf("--vmname=%s", vmName),
-		fmt.Sprintf("--memory=%d", memory),
-		fmt.Sprintf("--cpus=%d", cpus),
+		"list",
+		"systemproperties",
 	)
-	_, stderr, err := runGetOutput(cmd)
+	stdout, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return nil, errors.New(stderr)
+		return nil, fmt.Errorf("GetSystemProperties: list systemproperties failed: %w", err)
 	}
-	cmd = exec.Command(
-		"VBoxManage",
-		"modifyvm",
-		vmName,
-		"--nat-localhostreachable1",
-		"on",
-	)
-	_, stderr, err = runGetOutput(cmd)
-	if err != nil {
-		return nil, errors.New(stderr)
+	// example output:
+	// API version:                     7_0
+	// Default machine folder:          /home/user/VirtualBox VMs
+	// Default Guest Additions ISO:     /usr/share/virtualbox/VBoxGuestAdditions.iso
+	blocks := parseListBlocks(stdout)
+	if len(blocks) == 0 {
+		return map[string]string{}, nil
+	}
+	return blocks[0], nil
+}
+
+// ExtensionPack is installed VirtualBox extension pack
+type ExtensionPack struct {
+	Name    string
+	Version string
+}
+
+// parseExtensionPacks parses `VBoxManage list extpacks` output, example:
+// Extension Packs: 1
+// Pack no. 0:   Oracle VirtualBox Extension Pack
+// Version:      7.0.12
+// Revision:     159484
+func parseExtensionPacks(stdout string) []ExtensionPack {
+	result := []ExtensionPack{}
+	for _, line := range strings.Split(stdout, "\n") {
+		keyValue := strings.SplitN(line, ":", 2)
+		if len(keyValue) < 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(keyValue[0]), strings.TrimSpace(keyValue[1])
+		switch {
+		case strings.HasPrefix(key, "Pack no."):
+			result = append(result, ExtensionPack{Name: value})
+		case key == "Version" && len(result) > 0 && result[len(result)-1].Version == "":
+			result[len(result)-1].Version = value
+		}
 	}
-	return GetVMInfo(vmName)
+	return result
 }
 
-func StartVM(vmName string, vmType VMBootType) (*VirtualboxVMInfo, error) {
+// ListExtensionPacks returns installed extension packs
+func ListExtensionPacks(ctx context.Context) ([]ExtensionPack, error) {
 	cmd := exec.Command(
 		"VBoxManage",
-		"startvm",
-		vmName,
-		fmt.Sprintf("--type=%s", vmType),
+		"list",
+		"extpacks",
 	)
-	_, stderr, err := runGetOutput(cmd)
+	stdout, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return nil, errors.New(stderr)
+		return nil, fmt.Errorf("ListExtensionPacks: list extpacks failed: %w", err)
 	}
-	return GetVMInfo(vmName)
+	return parseExtensionPacks(stdout), nil
 }
 
-func StopVM(vmName string) (*VirtualboxVMInfo, error) {
+// ListCPUProfiles returns names of cpu profiles, which could be set with --cpu-profile besides "host"
+func ListCPUProfiles(ctx context.Context) ([]string, error) {
 	cmd := exec.Command(
 		"VBoxManage",
-		"controlvm",
-		vmName,
-		"poweroff",
+		"list",
+		"cpu-profiles",
 	)
-	_, stderr, err := runGetOutput(cmd)
+	stdout, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return nil, errors.New(stderr)
+		return nil, fmt.Errorf("ListCPUProfiles: list cpu-profiles failed: %w", err)
+	}
+	// example block:
+	// Name:          Intel Core i7-6700K
+	// Full Name:     Intel(R) Core(TM) i7-6700K CPU @ 4.00GHz
+	// GUID:          ...
+	// Architecture:  x86
+	result := []string{}
+	for _, block := range parseListBlocks(stdout) {
+		if name := block["Name"]; name != "" {
+			result = append(result, name)
+		}
 	}
-	return GetVMInfo(vmName)
+	return result, nil
 }
 
-func DeleteVM(vmName string) error {
-	// VBoxManage unregistervm <uuid | vmname> [--delete] [--delete-all]
-	cmd := exec.Command(
-		"VBoxManage",
-		"unregistervm",
-		vmName,
-		"--delete",
-		"--delete-all",
-	)
-	_, stderr, err := runGetOutput(cmd)
+// GetVBoxVersion returns VirtualBox version as reported by `VBoxManage --version`
+func GetVBoxVersion(ctx context.Context) (string, error) {
+	version, err := GetVersion(ctx)
 	if err != nil {
-		return errors.New(stderr)
+		return "", fmt.Errorf("GetVBoxVersion: %w", err)
 	}
-	return nil
+	return version.Raw, nil
 }
 
-func DestroyVM(vmName string) error {
-	vminfo, err := GetVMInfo(vmName)
+// GetVBoxManageVersion returns VirtualBox version components, e.g. 7, 0, 12 for "7.0.12r159484"
+func GetVBoxManageVersion(ctx context.Context) (major, minor, patch int, err error) {
+	version, err := GetVersion(ctx)
 	if err != nil {
-		// Machine is possibly already destroyed
-		return err
+		return 0, 0, 0, fmt.Errorf("GetVBoxManageVersion: %w", err)
 	}
-	if vminfo.State != Poweroff {
-		_, _ = StopVM(vmName)
-		// we can't do anything at this point,
-		// so just ignoring error
+	return version.Major, version.Minor, version.Patch, nil
+}
+
+// GetVMCount returns number of vms registered in VirtualBox
+func GetVMCount(ctx context.Context) (int, error) {
+	vms, err := ListVMs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("GetVMCount: %w", err)
 	}
-	return DeleteVM(vmName)
+	return len(vms), nil
 }
 
-func vmInfoValueToString(value string) string {
-	if len(value) == 0 {
-		return value
+// RegisteredVM is vm listed by `VBoxManage list vms`
+type RegisteredVM struct {
+	Name string
+	ID   string
+	// ConfigFile is path to .vbox settings file, set only by ListAccessibleVMs
+	ConfigFile string
+}
+
+var vrdeDetailsRegexp = regexp.MustCompile(`(?m)^VRDE:\s+enabled \(Address ([^,]+),`)
+
+// getVRDEAddress returns address VRDP server of running vm listens on. Machine readable output
+// reports configured address only, so it's taken from `showvminfo --details` output
+func getVRDEAddress(ctx context.Context, vmName string) (string, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"showvminfo",
+		vmName,
+		"--details",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("getVRDEAddress: showvminfo failed for %q: %w", vmName, err)
 	}
-	if value[0] == '"' {
-		value = value[1:]
+	// example line:
+	// VRDE:                        enabled (Address 0.0.0.0, Ports 3389, MultiConn: off, ReuseSingleConn: off, Authentication type: null)
+	match := vrdeDetailsRegexp.FindStringSubmatch(stdout)
+	if match == nil {
+		return "", nil
 	}
-	if len(value) > 0 && value[len(value)-1] == '"' {
-		value = value[:len(value)-1]
+	return match[1], nil
+}
+
+// VRDEArgs returns modifyvm arguments enabling VRDP server on given port or port range,
+// empty port and nil multiConnection are left as configured in vm
+func VRDEArgs(enabled bool, port string, multiConnection *bool) []string {
+	if !enabled {
+		return []string{"--vrde", "off"}
 	}
-	return value
+	args := []string{"--vrde", "on"}
+	if port != "" {
+		args = append(args, "--vrdeport", port)
+	}
+	if multiConnection != nil {
+		args = append(args, "--vrdemulticon", OnOff(*multiConnection))
+	}
+	return args
 }
 
-func GetVmIp(vminfo *VirtualboxVMInfo) (string, error) {
+var listVMsLineRegexp = regexp.MustCompile(`^"(.*)" \{([^}]+)\}$`)
+
+// ListVMs returns all vms registered in VirtualBox, including ones not managed by provider
+func ListVMs(ctx context.Context) ([]RegisteredVM, error) {
 	cmd := exec.Command(
 		"VBoxManage",
-		"guestproperty",
-		"enumerate",
-		vminfo.ID,
-		"/VirtualBox/GuestInfo/Net/0/V4/IP",
+		"list",
+		"vms",
 	)
-	stdout, stderr, err := runGetOutput(cmd)
+	stdout, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return "", errors.New(stderr)
+		return nil, fmt.Errorf("ListVMs: list vms failed: %w", err)
 	}
 	// example output:
-	// /VirtualBox/GuestInfo/Net/0/V4/IP = '192.168.1.157' @ 2023-02-04T21:42:09.082Z
-	ip := strings.Split(stdout, " ")[2]
-	return ip[1 : len(ip)-1], nil
+	// "ubuntu" {7b4c1ab3-0c7e-4a42-9e3b-8a6c2c0a1f5d}
+	result := []RegisteredVM{}
+	for _, line := range strings.Split(stdout, "\n") {
+		match := listVMsLineRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		result = append(result, RegisteredVM{Name: match[1], ID: match[2]})
+	}
+	return result, nil
+}
+
+// ListAccessibleVMs returns accessible registered vms with their settings files. Single `list vms --long` call
+// replaces showvminfo of every vm, inaccessible vms (e.g. with missing settings file) are skipped
+func ListAccessibleVMs(ctx context.Context) ([]RegisteredVM, error) {
+	accessible, _, err := listVMsLong(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListAccessibleVMs: %w", err)
+	}
+	return accessible, nil
 }
 
-func GetVMInfo(vmName string) (*VirtualboxVMInfo, error) {
+// listVMsLong returns accessible registered vms and ones which settings file is missing or broken,
+// names of the latter are unknown to VirtualBox
+func listVMsLong(ctx context.Context) (accessible, inaccessible []RegisteredVM, err error) {
 	cmd := exec.Command(
 		"VBoxManage",
-		"showvminfo",
-		vmName,
-		"--machinereadable",
+		"list",
+		"vms",
+		"--long",
 	)
-	stdout, stderr, err := runGetOutput(cmd)
+	stdout, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return nil, errors.New(stderr)
+		return nil, nil, fmt.Errorf("list vms failed: %w", err)
+	}
+	accessible, inaccessible = parseLongVMList(stdout)
+	return accessible, inaccessible, nil
+}
+
+// inaccessibleVMName is reported by VirtualBox instead of name of vm which settings can't be read
+const inaccessibleVMName = "<inaccessible!>"
+
+// parseLongVMList parses `list vms --long` output, which is showvminfo output of all vms in a row.
+// Every vm starts with unindented "Name:" line followed by "UUID:" and "Config file:",
+// shared folders have "Name:" lines too, but with quoted value
+func parseLongVMList(stdout string) (accessible, inaccessible []RegisteredVM) {
+	accessible, inaccessible = []RegisteredVM{}, []RegisteredVM{}
+	var current *RegisteredVM
+	currentAccessible := true
+	flush := func() {
+		if current != nil && current.ID != "" && current.ConfigFile != "" {
+			if currentAccessible {
+				accessible = append(accessible, *current)
+			} else {
+				inaccessible = append(inaccessible, *current)
+			}
+		}
+		current = nil
 	}
-	result := &VirtualboxVMInfo{}
 	for _, line := range strings.Split(stdout, "\n") {
-		keyValue := strings.SplitN(line, "=", 2)
+		keyValue := strings.SplitN(strings.TrimRight(line, "\r"), ":", 2)
 		if len(keyValue) < 2 {
-			// It's either a subkey without value or value with different format
-			// https://docs.oracle.com/en/virtualization/virtualbox/6.0/user/vboxmanage-showvminfo.html
 			continue
 		}
-		switch keyValue[0] {
-		case "name":
-			result.Name = vmInfoValueToString(keyValue[1])
-		case "UUID":
-			result.ID = vmInfoValueToString(keyValue[1])
-		case "VMState":
-			result.State = VMStateType(vmInfoValueToString(keyValue[1]))
-		case "\"SATA Controller-0-0\"":
-			result.VmdkPath = vmInfoValueToString(keyValue[1])
-		case "Forwarding(0)":
-			splited := strings.Split(vmInfoValueToString(keyValue[1]), ",")
-			result.SSHPort = splited[len(splited)-3]
+		key, value := keyValue[0], strings.TrimSpace(keyValue[1])
+		switch {
+		case key == "Name" && !strings.HasPrefix(value, "'"):
+			flush()
+			current = &RegisteredVM{Name: value}
+			currentAccessible = value != inaccessibleVMName
+			if !currentAccessible {
+				current.Name = ""
+			}
+		case current == nil:
+		case key == "UUID" && current.ID == "":
+			current.ID = value
+		case key == "Config file" && current.ConfigFile == "":
+			current.ConfigFile = value
+		case key == "Accessible" && value == "no":
+			currentAccessible = false
 		}
 	}
-	return result, nil
+	flush()
+	return accessible, inaccessible
 }
 
-func ForwardLocalPort(vmName string, guestPort int) (*VirtualboxVMInfo, error) {
-	ctx := context.Background()
-	port, err := net.ListenRangeConfig{
-		Addr:    "127.0.0.1",
-		Min:     7000,
-		Max:     8000,
-		Network: "tcp",
-	}.Listen(ctx)
+// uniqueNameAttempts limits suffixes tried by UniqueVMName
+const uniqueNameAttempts = 10
+
+// UniqueVMName returns prefix followed by random suffix, which isn't used by any registered vm
+func UniqueVMName(ctx context.Context, prefix string) (string, error) {
+	vms, err := ListVMs(ctx)
 	if err != nil {
-		err := fmt.Errorf("Error creating port forwarding rule: %s", err)
-		return nil, err
+		return "", fmt.Errorf("UniqueVMName: %w", err)
 	}
-	port.Listener.Close()
+	used := map[string]bool{}
+	for _, vm := range vms {
+		used[vm.Name] = true
+	}
+	suffix := make([]byte, 4)
+	for i := 0; i < uniqueNameAttempts; i++ {
+		_, err = rand.Read(suffix)
+		if err != nil {
+			return "", fmt.Errorf("UniqueVMName: generating suffix failed: %w", err)
+		}
+		name := prefix + hex.EncodeToString(suffix)
+		if !used[name] {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("UniqueVMName: no unused name with prefix %q after %d attempts", prefix, uniqueNameAttempts)
+}
 
-	// Make sure to configure the network interface to NAT
+// MarkManaged tags vm as created by provider, so that it could be told apart
+// from vms of other tools (e.g. Vagrant) sharing the host
+func MarkManaged(ctx context.Context, vmName string) error {
+	err := SetExtraData(ctx, vmName, ManagedMarkerKey, "true")
+	if err != nil {
+		return fmt.Errorf("MarkManaged: %w", err)
+	}
+	return nil
+}
+
+// IsManaged reports whether vm was created by provider, see MarkManaged
+func IsManaged(ctx context.Context, vmName string) (bool, error) {
+	value, err := GetExtraData(ctx, vmName, ManagedMarkerKey)
+	if err != nil {
+		return false, fmt.Errorf("IsManaged: %w", err)
+	}
+	return value == "true", nil
+}
+
+// GetGuestAdditionsISOPath returns path to Guest Additions ISO shipped with VirtualBox
+func GetGuestAdditionsISOPath(ctx context.Context) (string, error) {
+	properties, err := GetSystemProperties(ctx)
+	if err != nil {
+		return "", fmt.Errorf("GetGuestAdditionsISOPath: %w", err)
+	}
+	if isoPath, ok := properties["Default Guest Additions ISO"]; ok {
+		// remote host files can't be checked
+		if IsRemote() {
+			return isoPath, nil
+		}
+		if _, err := os.Stat(isoPath); err == nil {
+			return isoPath, nil
+		}
+	}
+	// Older versions don't report ISO path, fallback to well-known install locations
+	candidates := guestAdditionsISOCandidates()
+	for _, isoPath := range candidates {
+		if _, err := os.Stat(isoPath); err == nil {
+			return isoPath, nil
+		}
+	}
+	if IsRemote() {
+		return "", fmt.Errorf("GetGuestAdditionsISOPath: VirtualBox doesn't report ISO path: %w", ErrNotSupportedRemotely)
+	}
+	return "", fmt.Errorf("GetGuestAdditionsISOPath: unable to find Guest Additions ISO, VirtualBox doesn't report it and none of %s exists. "+
+		"Install Guest Additions ISO package of your distribution, e.g. virtualbox-guest-additions-iso, or specify path explicitly", strings.Join(candidates, ", "))
+}
+
+// guestAdditionsISOCandidates returns well-known Guest Additions ISO locations of Linux, macOS and Windows installs
+func guestAdditionsISOCandidates() []string {
+	candidates := []string{
+		"/usr/share/virtualbox/VBoxGuestAdditions.iso",
+		"/usr/lib/virtualbox/additions/VBoxGuestAdditions.iso",
+		"/usr/lib64/virtualbox/additions/VBoxGuestAdditions.iso",
+		"/opt/VirtualBox/additions/VBoxGuestAdditions.iso",
+		"/Applications/VirtualBox.app/Contents/MacOS/VBoxGuestAdditions.iso",
+	}
+	// VirtualBox installer sets VBOX_MSI_INSTALL_PATH, Program Files may be on another drive
+	for _, env := range []string{"VBOX_MSI_INSTALL_PATH", "VBOX_INSTALL_PATH"} {
+		if dir := os.Getenv(env); dir != "" {
+			candidates = append(candidates, filepath.Join(dir, "VBoxGuestAdditions.iso"))
+		}
+	}
+	if dir := os.Getenv("ProgramFiles"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "Oracle", "VirtualBox", "VBoxGuestAdditions.iso"))
+	}
+	return append(candidates, "C:\\Program Files\\Oracle\\VirtualBox\\VBoxGuestAdditions.iso")
+}
+
+// AttachDVD attaches iso image to the first free slot of vm storage controllers,
+// empty optical drives are preferred
+func AttachDVD(ctx context.Context, vmName, isoPath string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("AttachDVD: %w", err)
+	}
+	controllerName, port, device, found := "", 0, 0, false
+	for _, ctl := range vminfo.StorageControllers {
+		for p := 0; p < ctl.PortCount && !found; p++ {
+			for d := 0; d < ctl.DevicesPerPort(); d++ {
+				if vminfo.StorageAttachments[fmt.Sprintf("%s-%d-%d", ctl.Name, p, d)] == "emptydrive" {
+					controllerName, port, device, found = ctl.Name, p, d, true
+					break
+				}
+			}
+		}
+	}
+	for _, ctl := range vminfo.StorageControllers {
+		for p := 0; p < ctl.PortCount && !found; p++ {
+			for d := 0; d < ctl.DevicesPerPort(); d++ {
+				medium, ok := vminfo.StorageAttachments[fmt.Sprintf("%s-%d-%d", ctl.Name, p, d)]
+				if !ok || medium == "none" {
+					controllerName, port, device, found = ctl.Name, p, d, true
+					break
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("No free storage controller port to attach %s", isoPath)
+	}
 	cmd := exec.Command(
 		"VBoxManage",
-		"modifyvm",
+		"storageattach",
 		vmName,
-		"--nic1",
-		"nat",
+		fmt.Sprintf("--storagectl=%s", controllerName),
+		fmt.Sprintf("--port=%d", port),
+		fmt.Sprintf("--device=%d", device),
+		"--type=dvddrive",
+		fmt.Sprintf("--medium=%s", isoPath),
 	)
-	_, stderr, err := runGetOutput(cmd)
+	_, err = runGetOutput(ctx, cmd)
 	if err != nil {
-		return nil, errors.New(stderr)
+		return nil, fmt.Errorf("AttachDVD: storageattach failed for %q: %w", vmName, err)
 	}
+	return GetVMInfo(ctx, vmName)
+}
 
-	// Create a forwarded port mapping to the VM
-	cmd = exec.Command(
+// ConvertDisk copies disk image into new file of given format, e.g. VDI, VMDK or VHD
+func ConvertDisk(ctx context.Context, srcPath, dstPath, format string) error {
+	cmd := exec.Command(
 		"VBoxManage",
-		"modifyvm",
-		vmName,
-		"--natpf1",
-		fmt.Sprintf("%s,tcp,127.0.0.1,%d,,%d", SshPortRuleName, port.Port, guestPort),
+		"clonemedium",
+		"disk",
+		srcPath,
+		dstPath,
+		fmt.Sprintf("--format=%s", format),
 	)
-	_, stderr, err = runGetOutput(cmd)
+	_, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return nil, errors.New(stderr)
+		return fmt.Errorf("ConvertDisk: clonemedium failed for %q: %w", srcPath, err)
 	}
-	return GetVMInfo(vmName)
+	return nil
 }
 
-func InjectSSHKey(vmName, sshUser, sshKey string) error {
-	vminfo, err := GetVMInfo(vmName)
+// ConvertVMDisk converts first disk of vm into given format, converted disk
+// replaces original one in the same controller slot and original disk is deleted
+func ConvertVMDisk(ctx context.Context, vmName, format string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("ConvertVMDisk: %w", err)
 	}
-	// virt is not able to handle spaces in paths
-	// virtualbox usually call vm dirs like "VirtualBox VMs"
-	imageName := path.Base(vminfo.VmdkPath)
-	tmpPath := path.Join("/tmp", imageName)
-
-	input, err := os.Open(vminfo.VmdkPath)
+	var disk *StorageAttachment
+	for i, attachment := range vminfo.Attachments {
+		if attachment.Medium == vminfo.VmdkPath {
+			disk = &vminfo.Attachments[i]
+			break
+		}
+	}
+	if disk == nil {
+		return nil, fmt.Errorf("ConvertVMDisk: vm %s has no disk attached", vmName)
+	}
+	ext := path.Ext(disk.Medium)
+	if strings.EqualFold(ext, "."+format) {
+		return vminfo, nil
+	}
+	dstPath := strings.TrimSuffix(disk.Medium, ext) + "." + strings.ToLower(format)
+	err = ConvertDisk(ctx, disk.Medium, dstPath, format)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("ConvertVMDisk: %w", err)
 	}
-	defer input.Close()
+	cmd := exec.Command(
+		"VBoxManage",
+		"storageattach",
+		vmName,
+		fmt.Sprintf("--storagectl=%s", disk.Controller),
+		fmt.Sprintf("--port=%d", disk.Port),
+		fmt.Sprintf("--device=%d", disk.Device),
+		"--type=hdd",
+		fmt.Sprintf("--medium=%s", dstPath),
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ConvertVMDisk: storageattach failed for %q: %w", vmName, err)
+	}
+	cmd = exec.Command(
+		"VBoxManage",
+		"closemedium",
+		"disk",
+		disk.Medium,
+		"--delete",
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ConvertVMDisk: closemedium failed for %q: %w", disk.Medium, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
 
-	dst, err := os.Create(tmpPath)
+// Types of bandwidth groups
+const (
+	BandwidthGroupDisk    = "Disk"
+	BandwidthGroupNetwork = "Network"
+)
+
+// BandwidthGroupInfo describes vm bandwidth group limiting disk or network I/O
+type BandwidthGroupInfo struct {
+	Name           string
+	Type           string
+	MaxBytesPerSec int64
+}
+
+// bandwidthLimit formats limit for `bandwidthctl --limit`, which doesn't accept plain bytes.
+// Limit is passed in kilobytes per second, K suffix stands for 1024 bytes
+func bandwidthLimit(maxBytesPerSec int64) string {
+	return fmt.Sprintf("%dK", maxBytesPerSec/1024)
+}
+
+// AddBandwidthGroup creates vm bandwidth group of given type, limit is rounded down to kilobytes
+func AddBandwidthGroup(ctx context.Context, vmName, name, groupType string, maxBytesPerSec int64) (*BandwidthGroupInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"bandwidthctl",
+		vmName,
+		"add",
+		name,
+		fmt.Sprintf("--type=%s", strings.ToLower(groupType)),
+		fmt.Sprintf("--limit=%s", bandwidthLimit(maxBytesPerSec)),
+	)
+	_, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("AddBandwidthGroup: bandwidthctl add failed for %q: %w", vmName, err)
 	}
-	defer dst.Close()
-	defer os.Remove(tmpPath)
+	return GetBandwidthGroup(ctx, vmName, name)
+}
 
-	_, err = io.Copy(dst, input)
+// SetBandwidthGroupLimit changes limit of existing bandwidth group, running vm picks it up immediately
+func SetBandwidthGroupLimit(ctx context.Context, vmName, name string, maxBytesPerSec int64) (*BandwidthGroupInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"bandwidthctl",
+		vmName,
+		"set",
+		name,
+		fmt.Sprintf("--limit=%s", bandwidthLimit(maxBytesPerSec)),
+	)
+	_, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("SetBandwidthGroupLimit: bandwidthctl set failed for %q: %w", vmName, err)
+	}
+	return GetBandwidthGroup(ctx, vmName, name)
+}
+
+// RemoveBandwidthGroup deletes bandwidth group, group must not be assigned to any disk or network adapter
+func RemoveBandwidthGroup(ctx context.Context, vmName, name string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"bandwidthctl",
+		vmName,
+		"remove",
+		name,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	var vboxErr *VBoxManageError
+	if errors.As(err, &vboxErr) && vboxErr.HasCode(ObjectInUse) {
+		return fmt.Errorf("RemoveBandwidthGroup: bandwidth group %s of vm %s is still assigned to disk or network adapter, "+
+			"assign them another group or none first: %w", name, vmName, err)
 	}
-	err = dst.Sync()
 	if err != nil {
-		return err
+		return fmt.Errorf("RemoveBandwidthGroup: bandwidthctl remove failed for %q: %w", vmName, err)
 	}
+	return nil
+}
 
+// GetBandwidthGroups returns parsed `VBoxManage bandwidthctl list --machinereadable` output
+func GetBandwidthGroups(ctx context.Context, vmName string) ([]BandwidthGroupInfo, error) {
 	cmd := exec.Command(
-		"virt-sysprep",
-		"-a",
-		tmpPath,
-		"--ssh-inject",
-		fmt.Sprintf("%s:file:%s", sshUser, sshKey),
+		"VBoxManage",
+		"bandwidthctl",
+		vmName,
+		"list",
+		"--machinereadable",
 	)
-	_, stderr, err := runGetOutput(cmd)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("GetBandwidthGroups: bandwidthctl list failed for %q: %w", vmName, err)
+	}
+	// example output:
+	// name="Limit"
+	// type="Disk"
+	// maxbytespersec=20971520
+	groups := []BandwidthGroupInfo{}
+	for _, line := range strings.Split(stdout, "\n") {
+		keyValue := strings.SplitN(line, "=", 2)
+		if len(keyValue) < 2 {
+			continue
+		}
+		value := vmInfoValueToString(keyValue[1])
+		switch vmInfoValueToString(keyValue[0]) {
+		case "name":
+			groups = append(groups, BandwidthGroupInfo{Name: value})
+		case "type":
+			if len(groups) > 0 {
+				groups[len(groups)-1].Type = value
+			}
+		case "maxbytespersec":
+			if len(groups) > 0 {
+				groups[len(groups)-1].MaxBytesPerSec, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+	}
+	return groups, nil
+}
+
+// GetBandwidthGroup returns vm bandwidth group with given name
+func GetBandwidthGroup(ctx context.Context, vmName, name string) (*BandwidthGroupInfo, error) {
+	groups, err := GetBandwidthGroups(ctx, vmName)
 	if err != nil {
-		return errors.New(stderr)
+		return nil, err
+	}
+	for i, group := range groups {
+		if group.Name == name {
+			return &groups[i], nil
+		}
 	}
+	return nil, fmt.Errorf("Bandwidth group %s of vm %s: %w", name, vmName, ErrNotFound)
+}
 
-	_, err = dst.Seek(0, 0)
+// AssignBandwidthGroupToDisk limits I/O of attached disk with bandwidth group, disk is either UUID or path.
+// Group "none" removes limit. Vm must be powered off
+func AssignBandwidthGroupToDisk(ctx context.Context, vmName, groupName, disk string) (*VirtualboxVMInfo, error) {
+	medium, err := GetMediumInfo(ctx, disk)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("AssignBandwidthGroupToDisk: %w", err)
 	}
-	output, err := os.Create(vminfo.VmdkPath)
+	vminfo, err := GetVMInfo(ctx, vmName)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("AssignBandwidthGroupToDisk: %w", err)
+	}
+	for _, attachment := range vminfo.Attachments {
+		if attachment.Medium != medium.Location {
+			continue
+		}
+		cmd := exec.Command(
+			"VBoxManage",
+			"storageattach",
+			vmName,
+			fmt.Sprintf("--storagectl=%s", attachment.Controller),
+			fmt.Sprintf("--port=%d", attachment.Port),
+			fmt.Sprintf("--device=%d", attachment.Device),
+			"--type=hdd",
+			fmt.Sprintf("--medium=%s", medium.ID),
+			fmt.Sprintf("--bandwidthgroup=%s", groupName),
+		)
+		_, err = runGetOutput(ctx, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("AssignBandwidthGroupToDisk: storageattach failed for %q: %w", vmName, err)
+		}
+		return GetVMInfo(ctx, vmName)
 	}
-	_, err = io.Copy(output, dst)
+	return nil, fmt.Errorf("AssignBandwidthGroupToDisk: disk %s is not attached to vm %s", medium.Location, vmName)
+}
+
+// AssignBandwidthGroupToNIC limits traffic of network adapter with bandwidth group,
+// group "none" removes limit. Vm must be powered off
+func AssignBandwidthGroupToNIC(ctx context.Context, vmName string, nic int, groupName string) (*VirtualboxVMInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"modifyvm",
+		vmName,
+		fmt.Sprintf("--nicbandwidthgroup%d", nic),
+		groupName,
+	)
+	_, err := runGetOutput(ctx, cmd)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("AssignBandwidthGroupToNIC: modifyvm failed for %q: %w", vmName, err)
 	}
-	return nil
+	return GetVMInfo(ctx, vmName)
 }
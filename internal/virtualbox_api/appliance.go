@@ -0,0 +1,219 @@
+package virtualboxapi
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ApplianceInfo describes first virtual system of appliance as declared by image,
+// zero values mean that appliance doesn't declare setting
+type ApplianceInfo struct {
+	OSType string
+	Memory int64
+	CPUs   int64
+	// EULARequired means that import fails unless license agreement is accepted with --eula=accept
+	EULARequired bool
+}
+
+var (
+	applianceOSTypeRegexp = regexp.MustCompile(`(?m)^\s*\d+: Suggested OS type: "([^"]*)"`)
+	applianceMemoryRegexp = regexp.MustCompile(`(?m)^\s*\d+: Guest memory: (\d+) MB`)
+	applianceCPUsRegexp   = regexp.MustCompile(`(?m)^\s*\d+: Number of CPUs: (\d+)`)
+	// dry run lists license as `accept with "--vsys 0 --eula accept"`
+	applianceEULARegexp = regexp.MustCompile(`--vsys 0 --eula accept`)
+)
+
+// Platform returns platform appliance guest is built for, guessed from suggested OS type:
+// ARM guest types have _arm64 or _arm32 suffix, e.g. "Ubuntu_arm64". Empty when OS type is unknown
+func (info *ApplianceInfo) Platform() Platform {
+	switch {
+	case info.OSType == "":
+		return ""
+	case strings.HasSuffix(info.OSType, "_arm64") || strings.HasSuffix(info.OSType, "_arm32"):
+		return PlatformARM
+	}
+	return PlatformX86
+}
+
+// GetApplianceInfo reads appliance settings with `VBoxManage import -n` dry run,
+// nothing is imported
+func GetApplianceInfo(ctx context.Context, imagePath string) (*ApplianceInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"import",
+		imagePath,
+		"-n",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("GetApplianceInfo: import dry run failed for %q: %w", imagePath, err)
+	}
+	return parseApplianceInfo(stdout), nil
+}
+
+// parseApplianceInfo parses dry run output, only first virtual system is taken into account
+func parseApplianceInfo(output string) *ApplianceInfo {
+	info := &ApplianceInfo{}
+	if match := applianceOSTypeRegexp.FindStringSubmatch(output); match != nil {
+		info.OSType = match[1]
+	}
+	// regexps guarantee numbers
+	if match := applianceMemoryRegexp.FindStringSubmatch(output); match != nil {
+		info.Memory, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+	if match := applianceCPUsRegexp.FindStringSubmatch(output); match != nil {
+		info.CPUs, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+	info.EULARequired = applianceEULARegexp.MatchString(output)
+	return info
+}
+
+// OVAValidationResult summarizes import dry run of appliance
+type OVAValidationResult struct {
+	// VSysCount is number of virtual systems in appliance
+	VSysCount int
+	// DiskSizeMB is total capacity of disks declared by OVF descriptor, 0 when it can't be read
+	DiskSizeMB int64
+	// Warnings lists problems which are likely to make import or vm fail
+	Warnings []string
+}
+
+var (
+	applianceVSysRegexp       = regexp.MustCompile(`(?m)^Virtual system \d+:`)
+	applianceTargetPathRegexp = regexp.MustCompile(`(?m)^\s*\d+: Hard disk image: .*target path=([^,]+),`)
+	// capacity units are "byte" optionally multiplied by power of two, e.g. "byte * 2^30"
+	ovfCapacityUnitsRegexp = regexp.MustCompile(`^byte(?:\s*\*\s*2\^(\d+))?$`)
+)
+
+// ovfEnvelope is the part of OVF descriptor ValidateOVA needs
+type ovfEnvelope struct {
+	Disks []struct {
+		Capacity string `xml:"capacity,attr"`
+		Units    string `xml:"capacityAllocationUnits,attr"`
+	} `xml:"DiskSection>Disk"`
+}
+
+// ValidateOVA checks that appliance can be imported with `VBoxManage import --dry-run`, nothing is imported.
+// Disk capacities are read from OVF descriptor of local .ova or .ovf file and compared with
+// free space at import target, files of remote host aren't checked
+func ValidateOVA(ctx context.Context, imagePath string) (*OVAValidationResult, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"import",
+		imagePath,
+		"--dry-run",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ValidateOVA: import dry run failed for %q: %w", imagePath, err)
+	}
+	result := &OVAValidationResult{
+		VSysCount: len(applianceVSysRegexp.FindAllString(stdout, -1)),
+		Warnings:  []string{},
+	}
+	if result.VSysCount == 0 {
+		return nil, fmt.Errorf("ValidateOVA: appliance %q declares no virtual system", imagePath)
+	}
+	if result.VSysCount > 1 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"appliance declares %d virtual systems, only the first one is managed as vm", result.VSysCount))
+	}
+	if IsRemote() {
+		return result, nil
+	}
+
+	result.DiskSizeMB, err = ovfDiskSizeMB(imagePath)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("disk sizes can't be read from OVF descriptor: %s", err))
+		return result, nil
+	}
+	if match := applianceTargetPathRegexp.FindStringSubmatch(stdout); match != nil && result.DiskSizeMB > 0 {
+		dir := existingParent(filepath.Dir(strings.TrimSpace(match[1])))
+		// dynamically allocated disks need less space at first, shortage is a warning only
+		if available, ok := availableBytes(dir); ok && available < uint64(result.DiskSizeMB)*1024*1024 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"appliance disks may grow up to %d MB, but only %d MB is available in %s",
+				result.DiskSizeMB, available/(1024*1024), dir))
+		}
+	}
+	return result, nil
+}
+
+// existingParent returns dir or its nearest existing parent, import creates missing vm folder
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// ovfDiskSizeMB returns total capacity of disks declared by OVF descriptor of .ovf file or .ova archive
+func ovfDiskSizeMB(imagePath string) (int64, error) {
+	descriptor, err := readOVFDescriptor(imagePath)
+	if err != nil {
+		return 0, err
+	}
+	var envelope ovfEnvelope
+	if err := xml.Unmarshal(descriptor, &envelope); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, disk := range envelope.Disks {
+		capacity, err := strconv.ParseInt(disk.Capacity, 10, 64)
+		if err != nil {
+			// capacity may refer to OVF property, which isn't resolved
+			continue
+		}
+		match := ovfCapacityUnitsRegexp.FindStringSubmatch(strings.TrimSpace(disk.Units))
+		if disk.Units != "" && match == nil {
+			return 0, fmt.Errorf("unknown capacity units %q", disk.Units)
+		}
+		if match != nil && match[1] != "" {
+			// regexp guarantees number
+			shift, _ := strconv.Atoi(match[1])
+			capacity <<= shift
+		}
+		total += capacity
+	}
+	return total / (1024 * 1024), nil
+}
+
+// readOVFDescriptor returns content of .ovf file, or of the descriptor packed into .ova archive
+func readOVFDescriptor(imagePath string) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(imagePath), ".ovf") {
+		return os.ReadFile(imagePath)
+	}
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	archive := tar.NewReader(file)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no .ovf descriptor in %s", imagePath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(filepath.Ext(header.Name), ".ovf") {
+			return io.ReadAll(archive)
+		}
+	}
+}
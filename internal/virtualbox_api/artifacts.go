@@ -0,0 +1,188 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kinds of leftovers found by ListOrphanedArtifacts
+const (
+	ArtifactVM             = "vm"
+	ArtifactInaccessibleVM = "inaccessible vm"
+	ArtifactMedium         = "medium"
+	ArtifactFolder         = "folder"
+)
+
+// Artifact is leftover of vm creation, which makes creating vm with the same name fail
+type Artifact struct {
+	Kind string
+	// ID is UUID of vm or medium, empty for folder
+	ID   string
+	Path string
+	// Removable means that artifact was left by interrupted create and is safe to clean up automatically
+	Removable bool
+	// Reason tells why artifact is or isn't removable
+	Reason string
+}
+
+func (a Artifact) String() string {
+	if a.ID == "" {
+		return fmt.Sprintf("%s %s (%s)", a.Kind, a.Path, a.Reason)
+	}
+	return fmt.Sprintf("%s %s at %s (%s)", a.Kind, a.ID, a.Path, a.Reason)
+}
+
+// CleanupHint returns command removing artifact manually
+func (a Artifact) CleanupHint() string {
+	switch a.Kind {
+	case ArtifactVM:
+		return fmt.Sprintf("VBoxManage unregistervm %s --delete", a.ID)
+	case ArtifactInaccessibleVM:
+		return fmt.Sprintf("VBoxManage unregistervm %s", a.ID)
+	case ArtifactMedium:
+		return fmt.Sprintf("VBoxManage closemedium disk %s --delete", a.ID)
+	}
+	return fmt.Sprintf("remove folder %s", a.Path)
+}
+
+// isUnder reports whether file is folder itself or is inside it
+func isUnder(file, folder string) bool {
+	rel, err := filepath.Rel(folder, file)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// hasSnapshotFiles reports whether snapshot folder next to vm settings file has any files
+func hasSnapshotFiles(configFile string) bool {
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(configFile), "Snapshots"))
+	return err == nil && len(entries) > 0
+}
+
+// hasSettingsFile reports whether folder contains .vbox settings file of some vm
+func hasSettingsFile(folder string) bool {
+	matches, _ := filepath.Glob(filepath.Join(folder, "*.vbox"))
+	return len(matches) > 0
+}
+
+// ListOrphanedArtifacts finds leftovers which would make creating vm vmName in machineFolder fail,
+// empty machineFolder means VirtualBox default. Only vms proven to be left by interrupted create are removable:
+// vms carrying CreatingMarkerKey and inaccessible vms without snapshots in vm folder. Disks and folders can't
+// carry the marker, they are never removable, neither are healthy vms. Files of remote host aren't checked
+func ListOrphanedArtifacts(ctx context.Context, vmName, machineFolder string) ([]Artifact, error) {
+	if machineFolder == "" {
+		properties, err := GetSystemProperties(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ListOrphanedArtifacts: %w", err)
+		}
+		machineFolder = properties["Default machine folder"]
+	}
+	if machineFolder == "" {
+		return nil, fmt.Errorf("ListOrphanedArtifacts: VirtualBox doesn't report default machine folder")
+	}
+	vmFolder := filepath.Join(machineFolder, vmName)
+
+	accessible, inaccessible, err := listVMsLong(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListOrphanedArtifacts: %w", err)
+	}
+
+	result := []Artifact{}
+	// folder of registered vm is reported with the vm, removing vm deletes its files
+	folderOwned := false
+	for _, vm := range accessible {
+		inFolder := isUnder(vm.ConfigFile, vmFolder)
+		if vm.Name != vmName && !inFolder {
+			continue
+		}
+		artifact := Artifact{Kind: ArtifactVM, ID: vm.ID, Path: vm.ConfigFile}
+		creating, err := GetExtraData(ctx, vm.ID, CreatingMarkerKey)
+		if IsObjectNotFound(err) {
+			// vm was unregistered after listing
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ListOrphanedArtifacts: %w", err)
+		}
+		if creating == "true" {
+			artifact.Removable, artifact.Reason = true, "left by interrupted create"
+		} else {
+			artifact.Reason = "registered vm which wasn't created by interrupted apply"
+		}
+		folderOwned = folderOwned || inFolder
+		result = append(result, artifact)
+	}
+	for _, vm := range inaccessible {
+		if !isUnder(vm.ConfigFile, vmFolder) {
+			continue
+		}
+		artifact := Artifact{Kind: ArtifactInaccessibleVM, ID: vm.ID, Path: vm.ConfigFile}
+		switch {
+		case IsRemote():
+			artifact.Reason = "inaccessible vm on remote host, its snapshots can't be checked"
+		case hasSnapshotFiles(vm.ConfigFile):
+			artifact.Reason = "inaccessible vm with snapshots"
+		default:
+			artifact.Removable, artifact.Reason = true, "inaccessible vm without snapshots, likely partial import"
+		}
+		folderOwned = true
+		result = append(result, artifact)
+	}
+
+	mediums, err := ListMediums(ctx, MediumTypeHDD)
+	if err != nil {
+		return nil, fmt.Errorf("ListOrphanedArtifacts: %w", err)
+	}
+	for _, medium := range mediums {
+		if medium.InUse || !isUnder(medium.Location, vmFolder) {
+			continue
+		}
+		result = append(result, Artifact{
+			Kind:   ArtifactMedium,
+			ID:     medium.UUID,
+			Path:   medium.Location,
+			Reason: "unattached disk in vm folder, it may hold data of another vm",
+		})
+	}
+
+	if IsRemote() || folderOwned {
+		return result, nil
+	}
+	if _, err := os.Stat(vmFolder); err != nil {
+		return result, nil
+	}
+	artifact := Artifact{Kind: ArtifactFolder, Path: vmFolder, Reason: "folder without registered vm"}
+	if hasSettingsFile(vmFolder) {
+		artifact.Reason = "folder with settings file of unregistered vm, register it with VBoxManage registervm or remove folder"
+	}
+	return append(result, artifact), nil
+}
+
+// CleanupArtifacts removes removable vms found by ListOrphanedArtifacts, other artifacts are skipped.
+// Running vm is stopped within stopTimeout. Files of inaccessible vm can't be deleted by VirtualBox,
+// they are left in place and reported by the next ListOrphanedArtifacts
+func CleanupArtifacts(ctx context.Context, artifacts []Artifact, stopTimeout time.Duration) error {
+	for _, artifact := range artifacts {
+		if !artifact.Removable {
+			continue
+		}
+		var err error
+		switch artifact.Kind {
+		case ArtifactVM:
+			err = DestroyVM(ctx, artifact.ID, stopTimeout)
+		case ArtifactInaccessibleVM:
+			err = UnregisterVM(ctx, artifact.ID)
+		default:
+			return fmt.Errorf("CleanupArtifacts: %s can't be removed automatically", artifact)
+		}
+		if err != nil && !IsObjectNotFound(err) {
+			return fmt.Errorf("CleanupArtifacts: removing %s failed: %w", artifact, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package virtualboxapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeVM is registered vm as listed by `list vms --long`
+type fakeVM struct {
+	name       string
+	id         string
+	configFile string
+	creating   bool
+	// inaccessible vms are listed without name
+	inaccessible bool
+}
+
+// fakeDisk is registered disk as listed by `list hdds`
+type fakeDisk struct {
+	id       string
+	location string
+	inUse    bool
+}
+
+func longVMList(vms []fakeVM) string {
+	var b strings.Builder
+	for _, vm := range vms {
+		if vm.inaccessible {
+			b.WriteString("Name:                        <inaccessible!>\n")
+			b.WriteString("UUID:                        " + vm.id + "\n")
+			b.WriteString("Config file:                 " + vm.configFile + "\n")
+			b.WriteString("Accessible:                  no\n")
+			b.WriteString("Access error details:\n\n")
+			continue
+		}
+		b.WriteString("Name:                        " + vm.name + "\n")
+		b.WriteString("Encryption:                  disabled\n")
+		b.WriteString("Groups:                      /\n")
+		b.WriteString("UUID:                        " + vm.id + "\n")
+		b.WriteString("Config file:                 " + vm.configFile + "\n")
+		b.WriteString("Shared folders:\n\n")
+		b.WriteString("Name: 'data', Host path: '/srv/data' (machine mapping), writable\n\n")
+	}
+	return b.String()
+}
+
+func hddList(disks []fakeDisk) string {
+	var b strings.Builder
+	for _, disk := range disks {
+		b.WriteString("UUID:           " + disk.id + "\n")
+		b.WriteString("Parent UUID:    base\n")
+		b.WriteString("State:          created\n")
+		b.WriteString("Type:           normal (base)\n")
+		b.WriteString("Location:       " + disk.location + "\n")
+		b.WriteString("Storage format: VDI\n")
+		b.WriteString("Capacity:       1024 MBytes\n")
+		b.WriteString("Encryption:     disabled\n")
+		if disk.inUse {
+			b.WriteString("In use by VMs:  other (UUID: 00000000-0000-4000-8000-0000000000ff)\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// fakeRegistry answers listing commands of ListOrphanedArtifacts and fails test on any other command
+func fakeRegistry(t *testing.T, machineFolder string, vms []fakeVM, disks []fakeDisk) *fakeCalls {
+	return fakeVBoxManage(t, func(args []string) (string, error) {
+		switch strings.Join(args, " ") {
+		case "list vms --long":
+			return longVMList(vms), nil
+		case "list hdds":
+			return hddList(disks), nil
+		case "list systemproperties":
+			return "API version:                     7_0\nDefault machine folder:          " + machineFolder + "\n", nil
+		}
+		if args[0] == "getextradata" && args[2] == CreatingMarkerKey {
+			for _, vm := range vms {
+				if vm.id == args[1] {
+					if vm.creating {
+						return "Value: true\n", nil
+					}
+					return "No value set!\n", nil
+				}
+			}
+			return "", vboxManageError(args, "VBOX_E_OBJECT_NOT_FOUND")
+		}
+		t.Fatalf("unexpected command %v", args)
+		return "", nil
+	})
+}
+
+func TestListOrphanedArtifacts(t *testing.T) {
+	type want struct {
+		kind      string
+		id        string
+		removable bool
+	}
+	tests := []struct {
+		name  string
+		vms   []fakeVM
+		disks []fakeDisk
+		// files are created relative to machine folder
+		files []string
+		want  []want
+	}{
+		{
+			name: "nothing left",
+		},
+		{
+			name: "healthy unrelated vms",
+			vms: []fakeVM{
+				{name: "db", id: "id-db", configFile: "{M}/db/db.vbox"},
+				{name: "web2", id: "id-web2", configFile: "{M}/web2/web2.vbox"},
+				{name: "web", id: "id-elsewhere", configFile: "/other/web-copy/web.vbox", creating: false},
+			},
+			want: []want{
+				// same name is reported, it makes import fail
+				{kind: ArtifactVM, id: "id-elsewhere"},
+			},
+		},
+		{
+			name:  "vm with marker",
+			vms:   []fakeVM{{name: "web", id: "id-web", configFile: "{M}/web/web.vbox", creating: true}},
+			files: []string{"web/web.vbox"},
+			want:  []want{{kind: ArtifactVM, id: "id-web", removable: true}},
+		},
+		{
+			name:  "healthy vm with the same name",
+			vms:   []fakeVM{{name: "web", id: "id-web", configFile: "{M}/web/web.vbox"}},
+			files: []string{"web/web.vbox"},
+			want:  []want{{kind: ArtifactVM, id: "id-web"}},
+		},
+		{
+			name:  "healthy vm of another name in vm folder",
+			vms:   []fakeVM{{name: "renamed", id: "id-renamed", configFile: "{M}/web/renamed.vbox"}},
+			files: []string{"web/renamed.vbox"},
+			want:  []want{{kind: ArtifactVM, id: "id-renamed"}},
+		},
+		{
+			name:  "inaccessible vm without snapshots",
+			vms:   []fakeVM{{id: "id-broken", configFile: "{M}/web/web.vbox", inaccessible: true}},
+			files: []string{"web/web.vbox-prev"},
+			want:  []want{{kind: ArtifactInaccessibleVM, id: "id-broken", removable: true}},
+		},
+		{
+			name:  "inaccessible vm with snapshots",
+			vms:   []fakeVM{{id: "id-broken", configFile: "{M}/web/web.vbox", inaccessible: true}},
+			files: []string{"web/Snapshots/{0b7c}.vdi"},
+			want:  []want{{kind: ArtifactInaccessibleVM, id: "id-broken"}},
+		},
+		{
+			name: "inaccessible vm elsewhere",
+			vms:  []fakeVM{{id: "id-broken", configFile: "{M}/db/db.vbox", inaccessible: true}},
+		},
+		{
+			name: "disks",
+			disks: []fakeDisk{
+				{id: "disk-orphan", location: "{M}/web/disk001.vdi"},
+				{id: "disk-attached", location: "{M}/web/disk002.vdi", inUse: true},
+				{id: "disk-other", location: "{M}/db/disk001.vdi"},
+			},
+			files: []string{"web/disk001.vdi", "web/disk002.vdi"},
+			want: []want{
+				{kind: ArtifactMedium, id: "disk-orphan"},
+				{kind: ArtifactFolder},
+			},
+		},
+		{
+			name:  "disk next to vm with marker",
+			vms:   []fakeVM{{name: "web", id: "id-web", configFile: "{M}/web/web.vbox", creating: true}},
+			disks: []fakeDisk{{id: "disk-orphan", location: "{M}/web/disk001.vdi"}},
+			files: []string{"web/web.vbox", "web/disk001.vdi"},
+			want: []want{
+				{kind: ArtifactVM, id: "id-web", removable: true},
+				{kind: ArtifactMedium, id: "disk-orphan"},
+			},
+		},
+		{
+			name:  "folder without settings file",
+			files: []string{"web/disk001.vmdk"},
+			want:  []want{{kind: ArtifactFolder}},
+		},
+		{
+			name:  "folder of unregistered vm",
+			files: []string{"web/web.vbox", "web/disk001.vdi"},
+			want:  []want{{kind: ArtifactFolder}},
+		},
+		{
+			name:  "folder of another vm name prefix",
+			files: []string{"web2/web2.vbox"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machineFolder := t.TempDir()
+			expand := func(p string) string {
+				return filepath.FromSlash(strings.ReplaceAll(p, "{M}", filepath.ToSlash(machineFolder)))
+			}
+			vms := append([]fakeVM{}, tt.vms...)
+			for i := range vms {
+				vms[i].configFile = expand(vms[i].configFile)
+			}
+			disks := append([]fakeDisk{}, tt.disks...)
+			for i := range disks {
+				disks[i].location = expand(disks[i].location)
+			}
+			for _, f := range tt.files {
+				p := filepath.Join(machineFolder, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			calls := fakeRegistry(t, machineFolder, vms, disks)
+
+			artifacts, err := ListOrphanedArtifacts(context.Background(), "web", machineFolder)
+			if err != nil {
+				t.Fatalf("ListOrphanedArtifacts: %v", err)
+			}
+			got := []want{}
+			for _, a := range artifacts {
+				got = append(got, want{kind: a.Kind, id: a.ID, removable: a.Removable})
+				if a.Reason == "" {
+					t.Errorf("%s has no reason", a)
+				}
+			}
+			expected := append([]want{}, tt.want...)
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("artifacts = %+v, want %+v", got, expected)
+			}
+			if n := calls.count("list systemproperties"); n != 0 {
+				t.Errorf("default machine folder requested %d times for explicit folder", n)
+			}
+			// listing must never touch files
+			for _, f := range tt.files {
+				if _, err := os.Stat(filepath.Join(machineFolder, filepath.FromSlash(f))); err != nil {
+					t.Errorf("file %s is gone: %v", f, err)
+				}
+			}
+		})
+	}
+}
+
+func TestListOrphanedArtifactsDefaultMachineFolder(t *testing.T) {
+	machineFolder := t.TempDir()
+	vms := []fakeVM{{name: "web", id: "id-web", configFile: filepath.Join(machineFolder, "web", "web.vbox"), creating: true}}
+	calls := fakeRegistry(t, machineFolder, vms, nil)
+
+	artifacts, err := ListOrphanedArtifacts(context.Background(), "web", "")
+	if err != nil {
+		t.Fatalf("ListOrphanedArtifacts: %v", err)
+	}
+	if len(artifacts) != 1 || !artifacts[0].Removable {
+		t.Errorf("artifacts = %+v, want single removable vm", artifacts)
+	}
+	if n := calls.count("list systemproperties"); n != 1 {
+		t.Errorf("default machine folder requested %d times, want 1", n)
+	}
+}
+
+func TestCleanupArtifactsRemovesOnlyRemovableVMs(t *testing.T) {
+	folder := t.TempDir()
+	kept := filepath.Join(folder, "disk001.vdi")
+	if err := os.WriteFile(kept, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	calls := fakeVBoxManage(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "showvminfo":
+			return showVMInfo("web", "poweroff"), nil
+		case "unregistervm":
+			return "", nil
+		}
+		t.Fatalf("unexpected command %v", args)
+		return "", nil
+	})
+
+	artifacts := []Artifact{
+		{Kind: ArtifactVM, ID: "id-healthy", Path: "/vms/web/web.vbox"},
+		{Kind: ArtifactVM, ID: "id-marked", Path: "/vms/web/web.vbox", Removable: true},
+		{Kind: ArtifactInaccessibleVM, ID: "id-broken", Path: "/vms/web/web.vbox", Removable: true},
+		{Kind: ArtifactInaccessibleVM, ID: "id-snapshots", Path: "/vms/web/web.vbox"},
+		{Kind: ArtifactMedium, ID: "disk-orphan", Path: kept},
+		{Kind: ArtifactFolder, Path: folder},
+	}
+	if err := CleanupArtifacts(context.Background(), artifacts, time.Second); err != nil {
+		t.Fatalf("CleanupArtifacts: %v", err)
+	}
+	if n := calls.count("unregistervm id-marked --delete"); n != 1 {
+		t.Errorf("vm with marker deleted %d times, want 1", n)
+	}
+	if n := calls.count("unregistervm id-broken"); n != 1 {
+		t.Errorf("inaccessible vm unregistered %d times, want 1", n)
+	}
+	for _, id := range []string{"id-healthy", "id-snapshots", "disk-orphan"} {
+		if n := calls.count("unregistervm "+id) + calls.count("closemedium disk "+id); n != 0 {
+			t.Errorf("kept artifact %s removed", id)
+		}
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("file in kept folder is gone: %v", err)
+	}
+}
+
+func TestCleanupArtifactsRefusesFilesMarkedRemovable(t *testing.T) {
+	folder := t.TempDir()
+	fakeVBoxManage(t, func(args []string) (string, error) {
+		t.Fatalf("unexpected command %v", args)
+		return "", nil
+	})
+
+	for _, artifact := range []Artifact{
+		{Kind: ArtifactMedium, ID: "disk-orphan", Path: filepath.Join(folder, "disk001.vdi"), Removable: true},
+		{Kind: ArtifactFolder, Path: folder, Removable: true},
+	} {
+		if err := CleanupArtifacts(context.Background(), []Artifact{artifact}, time.Second); err == nil {
+			t.Errorf("%s removed", artifact)
+		}
+	}
+	if _, err := os.Stat(folder); err != nil {
+		t.Errorf("folder is gone: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Disks returns attached disk images in boot order: by storage controller order,
+// then by port and device. BIOS boots from the first one
+func (info *VirtualboxVMInfo) Disks() []StorageAttachment {
+	controllerOrder := map[string]int{}
+	for i, ctl := range info.StorageControllers {
+		controllerOrder[ctl.Name] = i
+	}
+	disks := []StorageAttachment{}
+	for _, attachment := range info.Attachments {
+		if attachment.IsDisk() {
+			disks = append(disks, attachment)
+		}
+	}
+	sort.SliceStable(disks, func(i, j int) bool {
+		a, b := disks[i], disks[j]
+		if controllerOrder[a.Controller] != controllerOrder[b.Controller] {
+			return controllerOrder[a.Controller] < controllerOrder[b.Controller]
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Device < b.Device
+	})
+	return disks
+}
+
+// DiskUsage returns total size in bytes of attached disk image files. Dynamically allocated
+// images take as much space as they've grown, optical media are shared and aren't counted
+func (info *VirtualboxVMInfo) DiskUsage() (int64, error) {
+	var total int64
+	for _, disk := range info.Disks() {
+		stat, err := os.Stat(disk.Medium)
+		if err != nil {
+			return 0, fmt.Errorf("DiskUsage: %w", err)
+		}
+		total += stat.Size()
+	}
+	return total, nil
+}
+
+// MissingDisks returns attached disk images which files don't exist, e.g. deleted outside of VirtualBox.
+// Files which can't be checked, e.g. because of permissions, aren't reported. Files of remote host can't be checked at all
+func (info *VirtualboxVMInfo) MissingDisks() []string {
+	missing := []string{}
+	if IsRemote() {
+		return missing
+	}
+	for _, disk := range info.Disks() {
+		if _, err := os.Stat(disk.Medium); os.IsNotExist(err) {
+			missing = append(missing, disk.Medium)
+		}
+	}
+	return missing
+}
+
+// GetVMDiskUsage returns total size in bytes of disk image files attached to vm
+func GetVMDiskUsage(ctx context.Context, vmName string) (int64, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return 0, fmt.Errorf("GetVMDiskUsage: %w", err)
+	}
+	return vminfo.DiskUsage()
+}
+
+// CompactVMDisks compacts all disk images attached to vm, vm must be powered off
+func CompactVMDisks(ctx context.Context, vmName string) error {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return fmt.Errorf("CompactVMDisks: %w", err)
+	}
+	if vminfo.State != Poweroff && vminfo.State != Aborted {
+		return fmt.Errorf("CompactVMDisks: vm %s is %s, power vm off to compact its disks", vminfo.Name, vminfo.State)
+	}
+	for _, disk := range vminfo.Disks() {
+		err = CompactDisk(ctx, disk.Medium)
+		if err != nil {
+			return fmt.Errorf("CompactVMDisks: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindOSDisk returns path of disk image containing guest operating system.
+// osDisk overrides detection, it's either full path or file name of attached disk.
+// When vm has several disks, disks are inspected by libguestfs and
+// the only one with operating system is picked
+func FindOSDisk(ctx context.Context, vminfo *VirtualboxVMInfo, osDisk string) (string, error) {
+	disks := vminfo.Disks()
+	if len(disks) == 0 {
+		return "", fmt.Errorf("FindOSDisk: vm %s has no disk attached", vminfo.Name)
+	}
+	if osDisk != "" {
+		for _, disk := range disks {
+			if disk.Medium == osDisk || path.Base(disk.Medium) == osDisk {
+				return disk.Medium, nil
+			}
+		}
+		return "", fmt.Errorf("FindOSDisk: disk %q is not attached to vm %s, attached disks: %s", osDisk, vminfo.Name, diskList(disks))
+	}
+	if len(disks) == 1 {
+		return disks[0].Medium, nil
+	}
+	found := []StorageAttachment{}
+	for _, disk := range disks {
+		if hasOperatingSystem(ctx, disk.Medium) {
+			found = append(found, disk)
+		}
+	}
+	if len(found) == 1 {
+		return found[0].Medium, nil
+	}
+	candidates := found
+	if len(found) == 0 {
+		candidates = disks
+	}
+	return "", fmt.Errorf("FindOSDisk: unable to detect operating system disk of vm %s, set os_disk to one of: %s", vminfo.Name, diskList(candidates))
+}
+
+// hasOperatingSystem reports whether libguestfs inspection finds operating system on disk image,
+// virt-ls fails when it's unable to mount root filesystem
+func hasOperatingSystem(ctx context.Context, diskPath string) bool {
+	cmd := exec.Command(
+		"virt-ls",
+		"-a",
+		diskPath,
+		"/",
+	)
+	_, err := runGetOutput(ctx, cmd)
+	return err == nil
+}
+
+// diskList formats disks for error messages
+func diskList(disks []StorageAttachment) string {
+	items := make([]string, 0, len(disks))
+	for _, disk := range disks {
+		items = append(items, fmt.Sprintf("%s (%s port %d device %d)", disk.Medium, disk.Controller, disk.Port, disk.Device))
+	}
+	return strings.Join(items, ", ")
+}
+
+// SetHostIOCache enables or disables host I/O cache of storage controller the first vm disk
+// is attached to. VirtualBox changes controllers of powered off vm only, running vm is restarted
+func SetHostIOCache(ctx context.Context, vmName string, enabled bool, bootType VMBootType, timeouts StateTimeouts) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("SetHostIOCache: %w", err)
+	}
+	disks := vminfo.Disks()
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("SetHostIOCache: vm %s has no disk attached", vmName)
+	}
+	wasRunning := vminfo.State == Running
+	if wasRunning {
+		_, err = StopVM(ctx, vmName, timeouts.Stop)
+		if err != nil {
+			return nil, fmt.Errorf("SetHostIOCache: %w", err)
+		}
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"storagectl",
+		vmName,
+		fmt.Sprintf("--name=%s", disks[0].Controller),
+		fmt.Sprintf("--hostiocache=%s", OnOff(enabled)),
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("SetHostIOCache: storagectl failed for %q: %w", vmName, err)
+	}
+	if wasRunning {
+		return StartVM(ctx, vmName, bootType, timeouts.Start)
+	}
+	return GetVMInfo(ctx, vmName)
+}
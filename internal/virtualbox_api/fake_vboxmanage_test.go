@@ -0,0 +1,63 @@
+package virtualboxapi
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVBoxManage replaces commandRunner until the end of test. Handler gets VBoxManage
+// arguments without program name and returns stdout or error. Other programs, e.g. virt-sysprep,
+// are passed with program name
+func fakeVBoxManage(t *testing.T, handler func(args []string) (string, error)) *fakeCalls {
+	t.Helper()
+	calls := &fakeCalls{}
+	prev := commandRunner
+	commandRunner = func(ctx context.Context, cmd *exec.Cmd) (string, error) {
+		args := cmd.Args
+		if args[0] == "VBoxManage" {
+			args = args[1:]
+		}
+		calls.record(args)
+		return handler(args)
+	}
+	t.Cleanup(func() { commandRunner = prev })
+	return calls
+}
+
+// fakeCalls records commands run by fake VBoxManage
+type fakeCalls struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *fakeCalls) record(args []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, strings.Join(args, " "))
+}
+
+// count returns how many commands started with given prefix
+func (c *fakeCalls) count(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, line := range c.lines {
+		if strings.HasPrefix(line, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// vboxManageError returns error of failed VBoxManage call with given stderr
+func vboxManageError(args []string, stderr string) error {
+	return &VBoxManageError{Command: append([]string{"VBoxManage"}, args...), ExitCode: 1, Stderr: stderr}
+}
+
+// showVMInfo returns minimal machine readable vm info in given state
+func showVMInfo(name, state string) string {
+	return "name=\"" + name + "\"\nUUID=\"1b2f5d3e-0000-4000-8000-000000000001\"\nVMState=\"" + state + "\"\n"
+}
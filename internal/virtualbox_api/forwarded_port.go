@@ -0,0 +1,223 @@
+package virtualboxapi
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// forwardedPortAttempts limits how many times EnsureForwardedPort moves rule to another host port
+const forwardedPortAttempts = 3
+
+const (
+	// DefaultPortReleaseTimeout is how long WaitForHostPortsReleased waits by default
+	DefaultPortReleaseTimeout   = 10 * time.Second
+	hostPortReleasePollInterval = 500 * time.Millisecond
+)
+
+// natRedirectFailedRegexp matches VBox.log warning written when NAT rule host port can't be bound, e.g.
+// NAT: failed to redirect TCP 127.0.0.1:2222 -> 10.0.2.15:22 (Address already in use)
+var natRedirectFailedRegexp = regexp.MustCompile(`NAT: failed to redirect (TCP|UDP) [^\s]*:(\d+) ->`)
+
+// vmLogPath returns path of log of the current vm session
+func vmLogPath(vminfo *VirtualboxVMInfo) string {
+	return filepath.Join(filepath.Dir(vminfo.ConfigFile), "Logs", "VBox.log")
+}
+
+// natRedirectFailed scans vm log from offset for failure to bind host port of rule.
+// Returned size is used as offset of the next scan, so failures of previous ports are skipped
+func natRedirectFailed(logPath string, offset int64, rule *PortForwardingRule) (bool, int64, error) {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return false, offset, err
+	}
+	defer file.Close()
+	size, err := file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return false, offset, err
+	}
+	failed := false
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		size += int64(len(line))
+		match := natRedirectFailedRegexp.FindStringSubmatch(line)
+		if match != nil && strings.EqualFold(match[1], rule.Protocol) && match[2] == rule.HostPort {
+			failed = true
+		}
+		if err == io.EOF {
+			return failed, size, nil
+		}
+		if err != nil {
+			return false, offset, err
+		}
+	}
+}
+
+// hostPortListening dials host port of tcp rule, nothing listens on it when vm failed to bind it
+// and port was released since then. Udp rules can't be checked this way and are always reported as listening
+func hostPortListening(rule *PortForwardingRule) bool {
+	if rule.Protocol != "tcp" {
+		return true
+	}
+	host := rule.HostIP
+	if host == "" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, rule.HostPort), 5*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// hostPortForRule returns free port of configured range when port is 0, given port is checked to be free otherwise
+func hostPortForRule(port int) (int, error) {
+	if port == 0 {
+		return freeLocalPort()
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return 0, fmt.Errorf("host port %d is already in use: %w", port, err)
+	}
+	listener.Close()
+	return port, nil
+}
+
+// SetForwardedHostPort moves NAT rule to another host port, running vm is reconfigured on the fly.
+// Free port of configured range is used when hostPort is 0
+func SetForwardedHostPort(ctx context.Context, vmName, ruleName string, hostPort int) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("SetForwardedHostPort: %w", err)
+	}
+	rule := vminfo.Rule(ruleName)
+	if rule == nil {
+		return nil, fmt.Errorf("SetForwardedHostPort: vm %q has no NAT rule %q", vmName, ruleName)
+	}
+	port, err := hostPortForRule(hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("SetForwardedHostPort: unable to use host port for %q: %w", vmName, err)
+	}
+	moved := *rule
+	moved.HostPort = strconv.Itoa(port)
+	_, err = DeleteForwardingRule(ctx, vmName, ruleName)
+	if err != nil {
+		return nil, fmt.Errorf("SetForwardedHostPort: %w", err)
+	}
+	vminfo, err = AddForwardingRule(ctx, vmName, moved)
+	if err != nil {
+		return nil, fmt.Errorf("SetForwardedHostPort: %w", err)
+	}
+	return vminfo, nil
+}
+
+// EnsureForwardedPort checks that running vm has bound host port of NAT rule. The port found by
+// ForwardLocalPort may be taken by another process before vm starts, VirtualBox only logs a warning
+// then. Such rule is moved to another free port on the fly when movable, the refreshed vm info is returned.
+// Connecting to the port can't tell vm apart from process which took it, so VBox.log is checked as well,
+// unreadable log isn't an error
+func EnsureForwardedPort(ctx context.Context, vmName, ruleName string, movable bool) (*VirtualboxVMInfo, error) {
+	if IsRemote() {
+		// vm log and host port are on remote host
+		return GetVMInfo(ctx, vmName)
+	}
+	var logOffset int64
+	for attempt := 0; ; attempt++ {
+		vminfo, err := GetVMInfo(ctx, vmName)
+		if err != nil {
+			return nil, fmt.Errorf("EnsureForwardedPort: %w", err)
+		}
+		rule := vminfo.Rule(ruleName)
+		if rule == nil || vminfo.State != Running {
+			return vminfo, nil
+		}
+		failed, size, err := natRedirectFailed(vmLogPath(vminfo), logOffset, rule)
+		if err == nil {
+			logOffset = size
+		}
+		if !failed && hostPortListening(rule) {
+			return vminfo, nil
+		}
+		if !movable || attempt == forwardedPortAttempts {
+			return nil, fmt.Errorf("EnsureForwardedPort: host port %s of rule %q is taken by another process for %q", rule.HostPort, ruleName, vmName)
+		}
+		_, err = SetForwardedHostPort(ctx, vmName, ruleName, 0)
+		if err != nil {
+			return nil, fmt.Errorf("EnsureForwardedPort: %w", err)
+		}
+	}
+}
+
+// DeleteProviderForwardingRules removes NAT rules of vm created by provider, i.e. ones with ProviderRulePrefix
+// and extraRuleNames, e.g. custom ssh rule name. Running vm releases their host ports right away,
+// removed rules are returned for WaitForHostPortsReleased
+func DeleteProviderForwardingRules(ctx context.Context, vmName string, extraRuleNames ...string) ([]PortForwardingRule, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteProviderForwardingRules: %w", err)
+	}
+	extra := map[string]bool{}
+	for _, name := range extraRuleNames {
+		extra[name] = true
+	}
+	removed := []PortForwardingRule{}
+	for _, rule := range vminfo.ForwardingRules {
+		if !strings.HasPrefix(rule.Name, ProviderRulePrefix) && !extra[rule.Name] {
+			continue
+		}
+		_, err = DeleteForwardingRule(ctx, vmName, rule.Name)
+		if err != nil {
+			return removed, fmt.Errorf("DeleteProviderForwardingRules: %w", err)
+		}
+		removed = append(removed, rule)
+	}
+	return removed, nil
+}
+
+// hostPortBindable reports whether tcp host port of rule could be bound again
+func hostPortBindable(rule PortForwardingRule) bool {
+	host := rule.HostIP
+	if host == "" {
+		host = "0.0.0.0"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, rule.HostPort))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// WaitForHostPortsReleased polls tcp host ports of removed rules until they could be bound again, so that
+// vm re-created right away gets the same fixed ports. Exiting vm process may hold them for a while.
+// Ports of remote host can't be checked
+func WaitForHostPortsReleased(ctx context.Context, rules []PortForwardingRule, timeout time.Duration) error {
+	if IsRemote() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, rule := range rules {
+		if rule.Protocol != "tcp" || rule.HostPort == "" {
+			continue
+		}
+		for !hostPortBindable(rule) {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("WaitForHostPortsReleased: host port %s of rule %q is still in use after %s", rule.HostPort, rule.Name, timeout)
+			case <-time.After(hostPortReleasePollInterval):
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package virtualboxapi
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenLocal starts tcp listener on free local port until the end of test
+func listenLocal(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return listener, port
+}
+
+// writeVMLog writes VBox.log of vm with given settings file
+func writeVMLog(t *testing.T, configFile, content string) {
+	t.Helper()
+	logs := filepath.Join(filepath.Dir(configFile), "Logs")
+	if err := os.MkdirAll(logs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(logs, "VBox.log"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNATRedirectFailed(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "VBox.log")
+	content := `00:00:01.000 NAT: failed to redirect TCP 127.0.0.1:2222 -> 10.0.2.15:22 (Address already in use)
+00:00:01.001 NAT: failed to redirect UDP 0.0.0.0:5353 -> 10.0.2.15:53 (Address already in use)
+`
+	if err := os.WriteFile(log, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		rule   PortForwardingRule
+		offset int64
+		want   bool
+	}{
+		{name: "tcp rule", rule: PortForwardingRule{Protocol: "tcp", HostPort: "2222"}, want: true},
+		{name: "udp rule", rule: PortForwardingRule{Protocol: "udp", HostPort: "5353"}, want: true},
+		{name: "other port", rule: PortForwardingRule{Protocol: "tcp", HostPort: "7001"}},
+		{name: "other protocol", rule: PortForwardingRule{Protocol: "udp", HostPort: "2222"}},
+		// failures of previous ports were already handled
+		{name: "scanned part is skipped", rule: PortForwardingRule{Protocol: "tcp", HostPort: "2222"}, offset: int64(len(content))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed, size, err := natRedirectFailed(log, tt.offset, &tt.rule)
+			if err != nil {
+				t.Fatalf("natRedirectFailed: %v", err)
+			}
+			if failed != tt.want {
+				t.Errorf("failed = %v, want %v", failed, tt.want)
+			}
+			if size != int64(len(content)) {
+				t.Errorf("size = %d, want %d", size, len(content))
+			}
+		})
+	}
+	if _, _, err := natRedirectFailed(filepath.Join(t.TempDir(), "missing.log"), 0, &PortForwardingRule{}); err == nil {
+		t.Error("missing log isn't an error")
+	}
+}
+
+func TestHostPortListening(t *testing.T) {
+	listener, port := listenLocal(t)
+	if !hostPortListening(&PortForwardingRule{Protocol: "tcp", HostIP: "0.0.0.0", HostPort: port}) {
+		t.Errorf("port %s isn't listening", port)
+	}
+	listener.Close()
+	if hostPortListening(&PortForwardingRule{Protocol: "tcp", HostPort: port}) {
+		t.Errorf("closed port %s is listening", port)
+	}
+	if !hostPortListening(&PortForwardingRule{Protocol: "udp", HostPort: port}) {
+		t.Error("udp rule isn't reported as listening")
+	}
+}
+
+func TestEnsureForwardedPort(t *testing.T) {
+	_, listeningPort := listenLocal(t)
+	tests := []struct {
+		name     string
+		port     string
+		log      string
+		movable  bool
+		wantErr  bool
+		wantMove bool
+	}{
+		{
+			name: "port bound by vm",
+			port: listeningPort,
+		},
+		{
+			name:    "port taken before start",
+			port:    "2222",
+			log:     "NAT: failed to redirect TCP 127.0.0.1:2222 -> 10.0.2.15:22 (Address already in use)\n",
+			wantErr: true,
+		},
+		{
+			name:     "movable port taken before start",
+			port:     "2222",
+			log:      "NAT: failed to redirect TCP 127.0.0.1:2222 -> 10.0.2.15:22 (Address already in use)\n",
+			movable:  true,
+			wantMove: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "vm", "vm.vbox")
+			writeVMLog(t, configFile, tt.log)
+			port := tt.port
+			calls := fakeVBoxManage(t, func(args []string) (string, error) {
+				switch args[0] {
+				case "showvminfo":
+					return showVMInfo("vm", "running") + `CfgFile="` + configFile + `"
+Forwarding(0)="terraform_ssh_port_rule,tcp,127.0.0.1,` + port + `,,22"
+`, nil
+				case "controlvm":
+					if len(args) == 4 && args[2] == "natpf1" {
+						// vm binds new host port right away
+						port = strings.Split(args[3], ",")[3]
+						listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+						if err != nil {
+							t.Fatalf("new host port %s isn't free: %v", port, err)
+						}
+						t.Cleanup(func() { listener.Close() })
+					}
+					return "", nil
+				}
+				t.Fatalf("unexpected command %v", args)
+				return "", nil
+			})
+
+			vminfo, err := EnsureForwardedPort(context.Background(), "vm", "terraform_ssh_port_rule", tt.movable)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "is taken by another process") {
+					t.Fatalf("error = %v, want port taken error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EnsureForwardedPort: %v", err)
+			}
+			moved := calls.count("controlvm vm natpf1 delete terraform_ssh_port_rule") == 1
+			if moved != tt.wantMove {
+				t.Errorf("rule moved = %v, want %v: %q", moved, tt.wantMove, calls.lines)
+			}
+			if got := vminfo.HostPort("terraform_ssh_port_rule"); got != port || (tt.wantMove && got == tt.port) {
+				t.Errorf("host port = %s, want %s", got, port)
+			}
+		})
+	}
+}
+
+func TestDeleteProviderForwardingRules(t *testing.T) {
+	calls := fakeVBoxManage(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "showvminfo":
+			return showVMInfo("vm", "running") + `Forwarding(0)="terraform_ssh_port_rule,tcp,127.0.0.1,7001,,22"
+Forwarding(1)="custom_ssh,tcp,127.0.0.1,7002,,2222"
+Forwarding(2)="web,tcp,,8080,,80"
+`, nil
+		case "controlvm":
+			return "", nil
+		}
+		t.Fatalf("unexpected command %v", args)
+		return "", nil
+	})
+
+	removed, err := DeleteProviderForwardingRules(context.Background(), "vm", "custom_ssh")
+	if err != nil {
+		t.Fatalf("DeleteProviderForwardingRules: %v", err)
+	}
+	names := []string{}
+	for _, rule := range removed {
+		names = append(names, rule.Name)
+	}
+	if strings.Join(names, ",") != "terraform_ssh_port_rule,custom_ssh" {
+		t.Errorf("removed rules = %v", names)
+	}
+	// rules created outside of provider are kept
+	if calls.count("controlvm vm natpf1 delete web") != 0 || calls.count("controlvm vm natpf1 delete") != 2 {
+		t.Errorf("commands = %q", calls.lines)
+	}
+}
+
+func TestWaitForHostPortsReleased(t *testing.T) {
+	listener, port := listenLocal(t)
+	_, busyPort := listenLocal(t)
+	go func() {
+		// exiting vm process releases port after a while
+		time.Sleep(2 * hostPortReleasePollInterval)
+		listener.Close()
+	}()
+	rules := []PortForwardingRule{
+		{Name: "terraform_ssh_port_rule", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: port},
+		// udp ports and rules without host port aren't waited for
+		{Name: "dns", Protocol: "udp", HostIP: "127.0.0.1", HostPort: busyPort},
+		{Name: "empty", Protocol: "tcp"},
+	}
+	if err := WaitForHostPortsReleased(context.Background(), rules, 10*time.Second); err != nil {
+		t.Fatalf("WaitForHostPortsReleased: %v", err)
+	}
+
+	busy := []PortForwardingRule{{Name: "terraform_web", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: busyPort}}
+	err := WaitForHostPortsReleased(context.Background(), busy, 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "is still in use") {
+		t.Fatalf("error = %v, want port still in use", err)
+	}
+}
@@ -0,0 +1,80 @@
+package virtualboxapi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetVMInfoForwardingRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		info  string
+		rules []PortForwardingRule
+	}{
+		{
+			name: "empty guest ip",
+			info: `Forwarding(0)="terraform_ssh_port_rule,tcp,127.0.0.1,7001,,22"` + "\n",
+			rules: []PortForwardingRule{
+				{Name: "terraform_ssh_port_rule", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: "7001", GuestPort: "22"},
+			},
+		},
+		{
+			name: "guest ip",
+			info: `Forwarding(0)="web,tcp,,8080,10.0.2.15,80"` + "\n",
+			rules: []PortForwardingRule{
+				{Name: "web", Protocol: "tcp", HostPort: "8080", GuestIP: "10.0.2.15", GuestPort: "80"},
+			},
+		},
+		{
+			name: "several rules of several adapters",
+			info: `Forwarding(0)="terraform_ssh_port_rule,tcp,127.0.0.1,7001,,22"
+Forwarding(1)="dns,udp,,5353,10.0.2.15,53"
+Forwarding(0)="metrics,tcp,0.0.0.0,9100,10.0.3.15,9100"
+`,
+			rules: []PortForwardingRule{
+				{Name: "terraform_ssh_port_rule", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: "7001", GuestPort: "22"},
+				{Name: "dns", Protocol: "udp", HostPort: "5353", GuestIP: "10.0.2.15", GuestPort: "53"},
+				{Name: "metrics", Protocol: "tcp", HostIP: "0.0.0.0", HostPort: "9100", GuestIP: "10.0.3.15", GuestPort: "9100"},
+			},
+		},
+		{
+			name: "malformed values are skipped",
+			info: `Forwarding(0)="short,tcp,,8080,80"
+Forwarding(1)="long,tcp,,8080,,80,extra"
+Forwarding="web,tcp,,8080,,80"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeVBoxManage(t, func(args []string) (string, error) {
+				return showVMInfo("vm", "poweroff") + tt.info, nil
+			})
+			vminfo, err := GetVMInfo(context.Background(), "vm")
+			if err != nil {
+				t.Fatalf("GetVMInfo: %v", err)
+			}
+			if !reflect.DeepEqual(vminfo.ForwardingRules, tt.rules) {
+				t.Errorf("rules = %+v, want %+v", vminfo.ForwardingRules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestRuleLookup(t *testing.T) {
+	vminfo := &VirtualboxVMInfo{ForwardingRules: []PortForwardingRule{
+		{Name: "dns", Protocol: "udp", HostPort: "5353", GuestPort: "22"},
+		{Name: "ssh", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: "7001", GuestIP: "10.0.2.15", GuestPort: "22"},
+	}}
+	if got := vminfo.HostPort("ssh"); got != "7001" {
+		t.Errorf("HostPort(ssh) = %q, want 7001", got)
+	}
+	if got := vminfo.HostPort("missing"); got != "" {
+		t.Errorf("HostPort(missing) = %q, want empty", got)
+	}
+	// udp rule to the same guest port isn't ssh
+	if rule := vminfo.RuleForGuestPort("22"); rule == nil || rule.Name != "ssh" {
+		t.Errorf("RuleForGuestPort(22) = %+v, want ssh rule", rule)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package virtualboxapi
+
+import "syscall"
+
+// nfsSuperMagic is NFS_SUPER_MAGIC filesystem type reported by statfs
+const nfsSuperMagic = 0x6969
+
+// isNFS reports whether path is on NFS mount
+func isNFS(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	// Type width differs between architectures
+	return int64(st.Type) == nfsSuperMagic
+}
+
+// availableBytes returns space available to unprivileged users on filesystem of path
+func availableBytes(path string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, false
+	}
+	// field widths differ between architectures
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package virtualboxapi
+
+// isNFS reports whether path is on NFS mount, detection is implemented on Linux only
+func isNFS(path string) bool {
+	return false
+}
+
+// availableBytes returns space available on filesystem of path, detection is implemented on Linux only
+func availableBytes(path string) (uint64, bool) {
+	return 0, false
+}
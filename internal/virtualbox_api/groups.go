@@ -0,0 +1,135 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// rootGroup is group of vms which aren't assigned to any other group
+const rootGroup = "/"
+
+// ValidateGroupPath checks vm group path, e.g. "/production/web"
+func ValidateGroupPath(groupPath string) error {
+	if !strings.HasPrefix(groupPath, "/") || groupPath == rootGroup {
+		return fmt.Errorf("group path %q must start with / and name at least one group", groupPath)
+	}
+	if strings.Contains(groupPath, ",") {
+		return fmt.Errorf("group path %q must not contain commas", groupPath)
+	}
+	for _, part := range strings.Split(groupPath[1:], "/") {
+		if strings.TrimSpace(part) == "" {
+			return fmt.Errorf("group path %q contains empty group name", groupPath)
+		}
+	}
+	return nil
+}
+
+// CreateGroup checks group path. VirtualBox has no standalone groups,
+// group exists while at least one vm is assigned to it, see AddVMToGroup
+func CreateGroup(groupPath string) error {
+	err := ValidateGroupPath(groupPath)
+	if err != nil {
+		return fmt.Errorf("CreateGroup: %w", err)
+	}
+	return nil
+}
+
+// DeleteGroup removes all vms from group, vms themselves are kept
+func DeleteGroup(ctx context.Context, groupPath string) error {
+	vms, err := GetGroupVMs(ctx, groupPath)
+	if err != nil {
+		return fmt.Errorf("DeleteGroup: %w", err)
+	}
+	for _, vm := range vms {
+		_, err = RemoveVMFromGroup(ctx, vm.ID, groupPath)
+		if err != nil && !IsObjectNotFound(err) {
+			return fmt.Errorf("DeleteGroup: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetGroupVMs returns vms assigned to group, vms unregistered while listing are skipped
+func GetGroupVMs(ctx context.Context, groupPath string) ([]RegisteredVM, error) {
+	vms, err := ListVMs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetGroupVMs: %w", err)
+	}
+	result := []RegisteredVM{}
+	for _, vm := range vms {
+		vminfo, err := GetVMInfo(ctx, vm.ID)
+		if IsObjectNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GetGroupVMs: %w", err)
+		}
+		if hasGroup(vminfo.Groups, groupPath) {
+			result = append(result, vm)
+		}
+	}
+	return result, nil
+}
+
+// AddVMToGroup assigns vm to group keeping its other groups, vm must be powered off
+func AddVMToGroup(ctx context.Context, vmName, groupPath string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("AddVMToGroup: %w", err)
+	}
+	if hasGroup(vminfo.Groups, groupPath) {
+		return vminfo, nil
+	}
+	groups := []string{groupPath}
+	for _, group := range vminfo.Groups {
+		if group != rootGroup {
+			groups = append(groups, group)
+		}
+	}
+	return setVMGroups(ctx, vmName, groups)
+}
+
+// RemoveVMFromGroup removes vm from group keeping its other groups, vm must be powered off
+func RemoveVMFromGroup(ctx context.Context, vmName, groupPath string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("RemoveVMFromGroup: %w", err)
+	}
+	if !hasGroup(vminfo.Groups, groupPath) {
+		return vminfo, nil
+	}
+	groups := []string{}
+	for _, group := range vminfo.Groups {
+		if group != groupPath {
+			groups = append(groups, group)
+		}
+	}
+	return setVMGroups(ctx, vmName, groups)
+}
+
+// setVMGroups replaces vm groups, empty list moves vm to root group
+func setVMGroups(ctx context.Context, vmName string, groups []string) (*VirtualboxVMInfo, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"modifyvm",
+		vmName,
+		"--groups",
+		strings.Join(groups, ","),
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("setVMGroups: modifyvm failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+func hasGroup(groups []string, groupPath string) bool {
+	for _, group := range groups {
+		if group == groupPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultGuestAdditionsTimeout is how long WaitForGuestAdditions waits by default
+	DefaultGuestAdditionsTimeout = 300 * time.Second
+	guestAdditionsPollInterval   = 2 * time.Second
+)
+
+// GetGuestAdditionsVersion returns version of Guest Additions running in guest,
+// or empty string when they haven't reported yet
+func GetGuestAdditionsVersion(ctx context.Context, vmName string) (string, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"guestproperty",
+		"get",
+		vmName,
+		"/VirtualBox/GuestAdd/Version",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("GetGuestAdditionsVersion: guestproperty get failed for %q: %w", vmName, err)
+	}
+	// example output:
+	// Value: 7.0.12
+	// or "No value set!" when property doesn't exist
+	version, ok := cutPrefix(strings.TrimSpace(stdout), "Value:")
+	if !ok {
+		return "", nil
+	}
+	return strings.TrimSpace(version), nil
+}
+
+// Guest properties holding user data for guest, read by cloud-init or other agent in guest
+const (
+	UserDataGuestProperty = "/terraform/user-data"
+	// UserDataEncodingGuestProperty is "base64" when user data is base64 encoded binary content
+	UserDataEncodingGuestProperty = "/terraform/user-data-encoding"
+)
+
+// SetGuestProperty sets guest property of vm, property is kept in vm settings and is readable by guest
+func SetGuestProperty(ctx context.Context, vmName, key, value string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"guestproperty",
+		"set",
+		vmName,
+		key,
+		value,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("SetGuestProperty: guestproperty set failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// DeleteGuestProperty removes guest property of vm, missing property isn't an error
+func DeleteGuestProperty(ctx context.Context, vmName, key string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"guestproperty",
+		"delete",
+		vmName,
+		key,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("DeleteGuestProperty: guestproperty delete failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// SetUserData writes user data to guest properties, empty encoding means plain text.
+// Empty user data removes both properties
+func SetUserData(ctx context.Context, vmName, userData, encoding string) error {
+	var err error
+	if userData == "" {
+		err = DeleteGuestProperty(ctx, vmName, UserDataGuestProperty)
+		if err == nil {
+			err = DeleteGuestProperty(ctx, vmName, UserDataEncodingGuestProperty)
+		}
+	} else {
+		err = SetGuestProperty(ctx, vmName, UserDataGuestProperty, userData)
+		if err == nil && encoding != "" {
+			err = SetGuestProperty(ctx, vmName, UserDataEncodingGuestProperty, encoding)
+		} else if err == nil {
+			err = DeleteGuestProperty(ctx, vmName, UserDataEncodingGuestProperty)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("SetUserData: %w", err)
+	}
+	return nil
+}
+
+// WaitForGuestAdditions polls guest properties until Guest Additions report their version
+// or timeout expires, guestcontrol commands work only after that
+func WaitForGuestAdditions(ctx context.Context, vmName string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(guestAdditionsPollInterval)
+	defer ticker.Stop()
+	for {
+		version, err := GetGuestAdditionsVersion(ctx, vmName)
+		if err == nil && version != "" {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("WaitForGuestAdditions: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Timeout waiting for Guest Additions of vm %s after %s", vmName, timeout)
+		case <-ticker.C:
+		}
+	}
+}
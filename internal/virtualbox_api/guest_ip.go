@@ -0,0 +1,69 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// guestNetPropertyRegexp matches guest network properties in both `guestproperty enumerate` formats:
+// VirtualBox 7.0+:  /VirtualBox/GuestInfo/Net/1/V4/IP = '192.168.56.10' @ 2023-02-04T21:42:09.082Z
+// older versions:   Name: /VirtualBox/GuestInfo/Net/1/V4/IP, value: 192.168.56.10, timestamp: ..., flags:
+var guestNetPropertyRegexp = regexp.MustCompile(`/VirtualBox/GuestInfo/Net/(\d+)/(V4/IP|MAC)(?: = '([^']*)'|, value: ([^,]*),)`)
+
+// normalizeMAC converts MAC address to showvminfo format, guest additions of some guests
+// report it lowercase or with separators, e.g. "08:00:27:a1:b2:c3"
+func normalizeMAC(mac string) string {
+	mac = strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.TrimSpace(mac))
+	return strings.ToUpper(mac)
+}
+
+// parseGuestAdapterIPs matches guest interfaces to vm adapters by MAC address. Guest interface
+// numbering follows guest OS and doesn't have to match adapter numbers
+func parseGuestAdapterIPs(output string, adapters []NetworkAdapter) map[int]string {
+	macs := map[string]string{}
+	ips := map[string]string{}
+	for _, match := range guestNetPropertyRegexp.FindAllStringSubmatch(output, -1) {
+		value := match[3]
+		if value == "" {
+			value = strings.TrimSpace(match[4])
+		}
+		if match[2] == "MAC" {
+			macs[match[1]] = normalizeMAC(value)
+		} else {
+			ips[match[1]] = value
+		}
+	}
+	result := map[int]string{}
+	for guestIndex, mac := range macs {
+		ip, ok := ips[guestIndex]
+		if !ok || ip == "" {
+			continue
+		}
+		for _, adapter := range adapters {
+			if normalizeMAC(adapter.MACAddress) == mac {
+				result[adapter.Index] = ip
+			}
+		}
+	}
+	return result
+}
+
+// GetAdapterIPs returns IPv4 addresses reported by guest additions by adapter number.
+// Adapters without reported address are missing, so map is empty without guest additions
+func GetAdapterIPs(ctx context.Context, vminfo *VirtualboxVMInfo) (map[int]string, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"guestproperty",
+		"enumerate",
+		vminfo.ID,
+		"/VirtualBox/GuestInfo/Net/*",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("GetAdapterIPs: guestproperty enumerate failed for %q: %w", vminfo.ID, err)
+	}
+	return parseGuestAdapterIPs(stdout, vminfo.NetworkAdapters), nil
+}
@@ -0,0 +1,220 @@
+package virtualboxapi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	tests := map[string]string{
+		"080027A1B2C3":       "080027A1B2C3",
+		"08:00:27:a1:b2:c3":  "080027A1B2C3",
+		"08-00-27-A1-B2-C3":  "080027A1B2C3",
+		"0800.27a1.b2c3":     "080027A1B2C3",
+		" 080027a1b2c3\n":    "080027A1B2C3",
+		"08:00:27:A1:B2:C3 ": "080027A1B2C3",
+	}
+	for mac, want := range tests {
+		if got := normalizeMAC(mac); got != want {
+			t.Errorf("normalizeMAC(%q) = %q, want %q", mac, got, want)
+		}
+	}
+}
+
+func TestParseGuestAdapterIPs(t *testing.T) {
+	adapters := []NetworkAdapter{
+		{Index: 1, Attachment: "nat", MACAddress: "080027000001"},
+		{Index: 2, Attachment: "hostonly", MACAddress: "080027A1B2C3"},
+		{Index: 4, Attachment: "intnet", MACAddress: "080027D4E5F6"},
+	}
+	tests := []struct {
+		name   string
+		output string
+		want   map[int]string
+	}{
+		{
+			name: "virtualbox 7 format",
+			output: `/VirtualBox/GuestInfo/Net/0/V4/IP = '10.0.2.15' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/0/MAC = '080027000001' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/1/V4/IP = '192.168.56.10' @ 2023-02-04T21:42:09.083Z
+/VirtualBox/GuestInfo/Net/1/MAC = '080027A1B2C3' @ 2023-02-04T21:42:09.083Z
+/VirtualBox/GuestInfo/Net/Count = '2' @ 2023-02-04T21:42:09.083Z
+`,
+			want: map[int]string{1: "10.0.2.15", 2: "192.168.56.10"},
+		},
+		{
+			name: "older format",
+			output: `Name: /VirtualBox/GuestInfo/Net/0/V4/IP, value: 10.0.2.15, timestamp: 1675546929082000000, flags:
+Name: /VirtualBox/GuestInfo/Net/0/MAC, value: 080027000001, timestamp: 1675546929082000000, flags:
+Name: /VirtualBox/GuestInfo/Net/1/V4/IP, value: 192.168.56.10, timestamp: 1675546929083000000, flags:
+Name: /VirtualBox/GuestInfo/Net/1/MAC, value: 080027A1B2C3, timestamp: 1675546929083000000, flags:
+`,
+			want: map[int]string{1: "10.0.2.15", 2: "192.168.56.10"},
+		},
+		{
+			// guest numbers interfaces on its own, e.g. skipping adapter 3
+			name: "guest numbering differs from adapters",
+			output: `/VirtualBox/GuestInfo/Net/0/V4/IP = '172.16.0.5' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/0/MAC = '080027D4E5F6' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/1/V4/IP = '10.0.2.15' @ 2023-02-04T21:42:09.083Z
+/VirtualBox/GuestInfo/Net/1/MAC = '080027000001' @ 2023-02-04T21:42:09.083Z
+`,
+			want: map[int]string{1: "10.0.2.15", 4: "172.16.0.5"},
+		},
+		{
+			name: "lowercase mac with separators",
+			output: `/VirtualBox/GuestInfo/Net/0/V4/IP = '192.168.56.10' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/0/MAC = '08:00:27:a1:b2:c3' @ 2023-02-04T21:42:09.082Z
+`,
+			want: map[int]string{2: "192.168.56.10"},
+		},
+		{
+			name: "unknown mac and missing address",
+			output: `/VirtualBox/GuestInfo/Net/0/V4/IP = '192.168.1.20' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/0/MAC = '0A0027FFFFFF' @ 2023-02-04T21:42:09.082Z
+/VirtualBox/GuestInfo/Net/1/MAC = '080027A1B2C3' @ 2023-02-04T21:42:09.083Z
+/VirtualBox/GuestInfo/Net/2/V4/IP = '' @ 2023-02-04T21:42:09.083Z
+/VirtualBox/GuestInfo/Net/2/MAC = '080027D4E5F6' @ 2023-02-04T21:42:09.083Z
+`,
+			want: map[int]string{},
+		},
+		{
+			name:   "no guest additions",
+			output: "No properties found.\n",
+			want:   map[int]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGuestAdapterIPs(tt.output, adapters)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ips = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVMInfoNetworkAdapters(t *testing.T) {
+	fakeVBoxManage(t, func(args []string) (string, error) {
+		return showVMInfo("vm", "running") + `nic1="nat"
+natnet1="nat"
+macaddress1="080027000001"
+cableconnected1="on"
+nic2="hostonly"
+hostonlyadapter2="vboxnet0"
+macaddress2="080027A1B2C3"
+cableconnected2="on"
+nic3="none"
+nic4="intnet"
+intnet4="cluster"
+macaddress4="080027D4E5F6"
+cableconnected4="off"
+nic5="natnetwork"
+nat-network5="NatNetwork"
+macaddress5="080027D4E5F7"
+nic6="bridged"
+bridgeadapter6="en0: Wi-Fi"
+macaddress6="080027D4E5F8"
+`, nil
+	})
+	vminfo, err := GetVMInfo(context.Background(), "vm")
+	if err != nil {
+		t.Fatalf("GetVMInfo: %v", err)
+	}
+	want := []NetworkAdapter{
+		{Index: 1, Attachment: "nat", MACAddress: "080027000001", CableConnected: true},
+		{Index: 2, Attachment: "hostonly", MACAddress: "080027A1B2C3", CableConnected: true, Network: "vboxnet0"},
+		{Index: 4, Attachment: "intnet", MACAddress: "080027D4E5F6", Network: "cluster"},
+		{Index: 5, Attachment: "natnetwork", MACAddress: "080027D4E5F7", Network: "NatNetwork"},
+		{Index: 6, Attachment: "bridged", MACAddress: "080027D4E5F8", Network: "en0: Wi-Fi"},
+	}
+	if !reflect.DeepEqual(vminfo.NetworkAdapters, want) {
+		t.Errorf("adapters = %+v, want %+v", vminfo.NetworkAdapters, want)
+	}
+}
+
+func TestSetNICAttachment(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      string
+		nic        int
+		attachment string
+		network    string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "running vm",
+			state:      "running",
+			nic:        2,
+			attachment: "bridged",
+			network:    "eth0",
+			want:       "controlvm vm nic2 bridged eth0",
+		},
+		{
+			name:       "running vm nat",
+			state:      "running",
+			nic:        2,
+			attachment: "nat",
+			want:       "controlvm vm nic2 nat",
+		},
+		{
+			name:       "stopped vm",
+			state:      "poweroff",
+			nic:        2,
+			attachment: "intnet",
+			network:    "cluster",
+			want:       "modifyvm vm --nic2 intnet --intnet2 cluster",
+		},
+		{
+			name:       "adding adapter to stopped vm",
+			state:      "poweroff",
+			nic:        3,
+			attachment: "natnetwork",
+			network:    "NatNetwork",
+			want:       "modifyvm vm --nic3 natnetwork --nat-network3 NatNetwork",
+		},
+		{
+			name:       "adding adapter to running vm",
+			state:      "running",
+			nic:        3,
+			attachment: "hostonly",
+			network:    "vboxnet0",
+			wantErr:    true,
+		},
+		{
+			name:       "removing adapter from running vm",
+			state:      "running",
+			nic:        2,
+			attachment: "none",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeVBoxManage(t, func(args []string) (string, error) {
+				if args[0] == "showvminfo" {
+					return showVMInfo("vm", tt.state) + "nic1=\"nat\"\nnic2=\"hostonly\"\nhostonlyadapter2=\"vboxnet0\"\n", nil
+				}
+				return "", nil
+			})
+			_, err := SetNICAttachment(context.Background(), "vm", tt.nic, tt.attachment, tt.network)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("SetNICAttachment succeeded")
+				}
+				if calls.count("controlvm") > 0 || calls.count("modifyvm") > 0 {
+					t.Errorf("vm was modified: %q", calls.lines)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetNICAttachment: %v", err)
+			}
+			if calls.count(tt.want) != 1 {
+				t.Errorf("commands = %q, want %q", calls.lines, tt.want)
+			}
+		})
+	}
+}
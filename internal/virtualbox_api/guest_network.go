@@ -0,0 +1,162 @@
+package virtualboxapi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// GuestNetworkManager selects format of network configuration written into guest disk
+type GuestNetworkManager string
+
+const (
+	Netplan  GuestNetworkManager = "netplan"
+	Networkd GuestNetworkManager = "networkd"
+	Ifcfg    GuestNetworkManager = "ifcfg"
+)
+
+// GuestIPConfig is static IPv4 configuration of guest interface, written into guest disk before first boot.
+// Interface names depend on guest OS, so interface is matched by MAC address of vm network adapter
+type GuestIPConfig struct {
+	// NIC is vm network adapter number, it names configuration file
+	NIC int
+	// MACAddress is adapter MAC address in showvminfo format, e.g. 080027A1B2C3
+	MACAddress string
+	Address    string
+	// Netmask is dotted netmask, e.g. 255.255.255.0
+	Netmask string
+	// Gateway and DNS are optional
+	Gateway string
+	DNS     []string
+}
+
+// prefixLength converts dotted netmask into CIDR prefix length
+func (c GuestIPConfig) prefixLength() (int, error) {
+	ip := net.ParseIP(c.Netmask).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("invalid netmask %q", c.Netmask)
+	}
+	ones, bits := net.IPMask(ip).Size()
+	if bits == 0 {
+		return 0, fmt.Errorf("netmask %q is not contiguous", c.Netmask)
+	}
+	return ones, nil
+}
+
+// guestMAC returns adapter MAC address in the colon separated form used by guest network managers
+func (c GuestIPConfig) guestMAC() (string, error) {
+	mac, err := net.ParseMAC(c.MACAddress)
+	if err != nil {
+		// showvminfo reports MAC address without separators
+		mac, err = hex.DecodeString(normalizeMAC(c.MACAddress))
+	}
+	if err != nil || len(mac) != 6 {
+		return "", fmt.Errorf("invalid MAC address %q of adapter %d", c.MACAddress, c.NIC)
+	}
+	return mac.String(), nil
+}
+
+// Validate checks addresses and netmask, so they can't break generated configuration files.
+// MAC address isn't checked, it's known only once vm is created
+func (c GuestIPConfig) Validate() error {
+	for _, address := range append([]string{c.Address, c.Gateway}, c.DNS...) {
+		if address != "" && net.ParseIP(address).To4() == nil {
+			return fmt.Errorf("invalid address %q of adapter %d", address, c.NIC)
+		}
+	}
+	if c.Address == "" {
+		return fmt.Errorf("address of adapter %d is required", c.NIC)
+	}
+	_, err := c.prefixLength()
+	return err
+}
+
+// guestNetworkFile returns path and content of configuration file of given network manager
+func guestNetworkFile(manager GuestNetworkManager, c GuestIPConfig) (string, string, error) {
+	err := c.Validate()
+	if err != nil {
+		return "", "", err
+	}
+	prefix, err := c.prefixLength()
+	if err != nil {
+		return "", "", err
+	}
+	mac, err := c.guestMAC()
+	if err != nil {
+		return "", "", err
+	}
+	name := fmt.Sprintf("terraform-nic%d", c.NIC)
+	lines := []string{}
+	switch manager {
+	case Netplan:
+		lines = append(lines,
+			"network:",
+			"  version: 2",
+			"  ethernets:",
+			fmt.Sprintf("    %s:", name),
+			"      match:",
+			fmt.Sprintf("        macaddress: \"%s\"", mac),
+			fmt.Sprintf("      addresses: [%s/%d]", c.Address, prefix),
+		)
+		if c.Gateway != "" {
+			lines = append(lines,
+				"      routes:",
+				"        - to: default",
+				fmt.Sprintf("          via: %s", c.Gateway),
+			)
+		}
+		if len(c.DNS) > 0 {
+			lines = append(lines,
+				"      nameservers:",
+				fmt.Sprintf("        addresses: [%s]", strings.Join(c.DNS, ", ")),
+			)
+		}
+		return fmt.Sprintf("/etc/netplan/60-%s.yaml", name), strings.Join(lines, "\n") + "\n", nil
+	case Networkd:
+		lines = append(lines,
+			"[Match]",
+			fmt.Sprintf("MACAddress=%s", mac),
+			"",
+			"[Network]",
+			fmt.Sprintf("Address=%s/%d", c.Address, prefix),
+		)
+		if c.Gateway != "" {
+			lines = append(lines, fmt.Sprintf("Gateway=%s", c.Gateway))
+		}
+		for _, dns := range c.DNS {
+			lines = append(lines, fmt.Sprintf("DNS=%s", dns))
+		}
+		return fmt.Sprintf("/etc/systemd/network/60-%s.network", name), strings.Join(lines, "\n") + "\n", nil
+	case Ifcfg:
+		lines = append(lines,
+			fmt.Sprintf("HWADDR=%s", strings.ToUpper(mac)),
+			"BOOTPROTO=none",
+			"ONBOOT=yes",
+			fmt.Sprintf("IPADDR=%s", c.Address),
+			fmt.Sprintf("NETMASK=%s", c.Netmask),
+		)
+		if c.Gateway != "" {
+			lines = append(lines, fmt.Sprintf("GATEWAY=%s", c.Gateway))
+		}
+		for i, dns := range c.DNS {
+			lines = append(lines, fmt.Sprintf("DNS%d=%s", i+1, dns))
+		}
+		return fmt.Sprintf("/etc/sysconfig/network-scripts/ifcfg-%s", name), strings.Join(lines, "\n") + "\n", nil
+	}
+	return "", "", fmt.Errorf("unknown guest network manager %q", manager)
+}
+
+// GuestNetworkArgs returns virt-sysprep arguments writing static network configuration into guest disk,
+// pass them to InjectSSHKey, so configuration is written in the same pass as ssh key
+func GuestNetworkArgs(manager GuestNetworkManager, configs []GuestIPConfig) ([]string, error) {
+	args := []string{}
+	for _, c := range configs {
+		filePath, content, err := guestNetworkFile(manager, c)
+		if err != nil {
+			return nil, fmt.Errorf("GuestNetworkArgs: %w", err)
+		}
+		args = append(args, "--write", filePath+":"+content)
+	}
+	return args, nil
+}
@@ -0,0 +1,143 @@
+package virtualboxapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGuestNetworkArgs(t *testing.T) {
+	full := GuestIPConfig{
+		NIC:        2,
+		MACAddress: "080027A1B2C3",
+		Address:    "192.168.56.10",
+		Netmask:    "255.255.255.0",
+		Gateway:    "192.168.56.1",
+		DNS:        []string{"1.1.1.1", "8.8.8.8"},
+	}
+	tests := []struct {
+		name    string
+		manager GuestNetworkManager
+		configs []GuestIPConfig
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "netplan",
+			manager: Netplan,
+			configs: []GuestIPConfig{full},
+			want: []string{"--write", "/etc/netplan/60-terraform-nic2.yaml:" +
+				"network:\n" +
+				"  version: 2\n" +
+				"  ethernets:\n" +
+				"    terraform-nic2:\n" +
+				"      match:\n" +
+				"        macaddress: \"08:00:27:a1:b2:c3\"\n" +
+				"      addresses: [192.168.56.10/24]\n" +
+				"      routes:\n" +
+				"        - to: default\n" +
+				"          via: 192.168.56.1\n" +
+				"      nameservers:\n" +
+				"        addresses: [1.1.1.1, 8.8.8.8]\n"},
+		},
+		{
+			name:    "networkd",
+			manager: Networkd,
+			configs: []GuestIPConfig{full},
+			want: []string{"--write", "/etc/systemd/network/60-terraform-nic2.network:" +
+				"[Match]\n" +
+				"MACAddress=08:00:27:a1:b2:c3\n" +
+				"\n" +
+				"[Network]\n" +
+				"Address=192.168.56.10/24\n" +
+				"Gateway=192.168.56.1\n" +
+				"DNS=1.1.1.1\n" +
+				"DNS=8.8.8.8\n"},
+		},
+		{
+			name:    "ifcfg",
+			manager: Ifcfg,
+			configs: []GuestIPConfig{full},
+			want: []string{"--write", "/etc/sysconfig/network-scripts/ifcfg-terraform-nic2:" +
+				"HWADDR=08:00:27:A1:B2:C3\n" +
+				"BOOTPROTO=none\n" +
+				"ONBOOT=yes\n" +
+				"IPADDR=192.168.56.10\n" +
+				"NETMASK=255.255.255.0\n" +
+				"GATEWAY=192.168.56.1\n" +
+				"DNS1=1.1.1.1\n" +
+				"DNS2=8.8.8.8\n"},
+		},
+		{
+			name:    "address only",
+			manager: Networkd,
+			configs: []GuestIPConfig{{NIC: 3, MACAddress: "08:00:27:00:00:03", Address: "10.0.0.5", Netmask: "255.0.0.0"}},
+			want: []string{"--write", "/etc/systemd/network/60-terraform-nic3.network:" +
+				"[Match]\nMACAddress=08:00:27:00:00:03\n\n[Network]\nAddress=10.0.0.5/8\n"},
+		},
+		{
+			name:    "several adapters",
+			manager: Ifcfg,
+			configs: []GuestIPConfig{
+				{NIC: 2, MACAddress: "080027000002", Address: "10.0.0.5", Netmask: "255.0.0.0"},
+				{NIC: 3, MACAddress: "080027000003", Address: "10.1.0.5", Netmask: "255.255.0.0"},
+			},
+			want: []string{
+				"--write", "/etc/sysconfig/network-scripts/ifcfg-terraform-nic2:HWADDR=08:00:27:00:00:02\nBOOTPROTO=none\nONBOOT=yes\nIPADDR=10.0.0.5\nNETMASK=255.0.0.0\n",
+				"--write", "/etc/sysconfig/network-scripts/ifcfg-terraform-nic3:HWADDR=08:00:27:00:00:03\nBOOTPROTO=none\nONBOOT=yes\nIPADDR=10.1.0.5\nNETMASK=255.255.0.0\n",
+			},
+		},
+		{
+			name:    "no configs",
+			manager: Netplan,
+			want:    []string{},
+		},
+		{
+			name:    "invalid address",
+			manager: Netplan,
+			configs: []GuestIPConfig{{NIC: 2, MACAddress: "080027000002", Address: "10.0.0.500", Netmask: "255.0.0.0"}},
+			wantErr: `invalid address "10.0.0.500"`,
+		},
+		{
+			name:    "invalid dns",
+			manager: Netplan,
+			configs: []GuestIPConfig{{NIC: 2, MACAddress: "080027000002", Address: "10.0.0.5", Netmask: "255.0.0.0", DNS: []string{"dns.example.com"}}},
+			wantErr: `invalid address "dns.example.com"`,
+		},
+		{
+			name:    "non contiguous netmask",
+			manager: Netplan,
+			configs: []GuestIPConfig{{NIC: 2, MACAddress: "080027000002", Address: "10.0.0.5", Netmask: "255.0.255.0"}},
+			wantErr: "is not contiguous",
+		},
+		{
+			name:    "adapter without MAC address",
+			manager: Netplan,
+			configs: []GuestIPConfig{{NIC: 2, Address: "10.0.0.5", Netmask: "255.0.0.0"}},
+			wantErr: `invalid MAC address "" of adapter 2`,
+		},
+		{
+			name:    "unknown manager",
+			manager: "nmcli",
+			configs: []GuestIPConfig{{NIC: 2, MACAddress: "080027000002", Address: "10.0.0.5", Netmask: "255.0.0.0"}},
+			wantErr: `unknown guest network manager "nmcli"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GuestNetworkArgs(tt.manager, tt.configs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GuestNetworkArgs: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// leftShiftScancode is set 1 make code of left shift, break code is make code | 0x80
+const leftShiftScancode = 0x2a
+
+// scancodeKey is set 1 make code of key, shifted keys are typed with shift held
+type scancodeKey struct {
+	code    byte
+	shifted bool
+}
+
+// usScancodes maps characters of US keyboard layout to keys
+var usScancodes = map[rune]scancodeKey{}
+
+func init() {
+	rows := []struct {
+		plain, shifted string
+		first          byte
+	}{
+		{"1234567890-=", "!@#$%^&*()_+", 0x02},
+		{"qwertyuiop[]", "QWERTYUIOP{}", 0x10},
+		{"asdfghjkl;'`", "ASDFGHJKL:\"~", 0x1e},
+		{"\\zxcvbnm,./", "|ZXCVBNM<>?", 0x2b},
+	}
+	for _, row := range rows {
+		shifted := []rune(row.shifted)
+		for i, r := range []rune(row.plain) {
+			usScancodes[r] = scancodeKey{row.first + byte(i), false}
+			usScancodes[shifted[i]] = scancodeKey{row.first + byte(i), true}
+		}
+	}
+	usScancodes[' '] = scancodeKey{0x39, false}
+	usScancodes['\n'] = scancodeKey{0x1c, false}
+	usScancodes['\t'] = scancodeKey{0x0f, false}
+}
+
+// characterScancodes returns make and break scancodes typing character on US keyboard
+func characterScancodes(r rune) ([]string, error) {
+	key, ok := usScancodes[r]
+	if !ok {
+		return nil, fmt.Errorf("character %q can't be typed with US keyboard layout", r)
+	}
+	codes := []byte{key.code, key.code | 0x80}
+	if key.shifted {
+		codes = []byte{leftShiftScancode, key.code, key.code | 0x80, leftShiftScancode | 0x80}
+	}
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		result = append(result, fmt.Sprintf("%02x", code))
+	}
+	return result, nil
+}
+
+// KeyboardPutString types text on keyboard of running vm. With zero delay text is sent at once,
+// otherwise characters are typed one by one as US keyboard scancodes with delay between them
+func KeyboardPutString(ctx context.Context, vmName, text string, delay time.Duration) error {
+	if delay == 0 {
+		cmd := exec.Command(
+			"VBoxManage",
+			"controlvm",
+			vmName,
+			"keyboardputstring",
+			text,
+		)
+		_, err := runGetOutput(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("KeyboardPutString: keyboardputstring failed for %q: %w", vmName, err)
+		}
+		return nil
+	}
+	// characters are checked before anything is typed
+	scancodes := [][]string{}
+	for _, r := range text {
+		codes, err := characterScancodes(r)
+		if err != nil {
+			return fmt.Errorf("KeyboardPutString: %w", err)
+		}
+		scancodes = append(scancodes, codes)
+	}
+	for i, codes := range scancodes {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("KeyboardPutString: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		cmd := exec.Command(
+			"VBoxManage",
+			append([]string{"controlvm", vmName, "keyboardputscancode"}, codes...)...,
+		)
+		_, err := runGetOutput(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("KeyboardPutString: keyboardputscancode failed for %q: %w", vmName, err)
+		}
+	}
+	return nil
+}
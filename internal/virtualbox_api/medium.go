@@ -0,0 +1,314 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Variants of disk medium
+const (
+	DiskVariantStandard = "Standard"
+	DiskVariantFixed    = "Fixed"
+)
+
+// Medium describes hard disk registered in VirtualBox
+type Medium struct {
+	ID       string
+	Location string
+	Format   string
+	// Variant is either DiskVariantStandard (dynamically allocated) or DiskVariantFixed
+	Variant string
+	SizeMB  int64
+	// AttachedTo lists UUIDs of vms using disk
+	AttachedTo []string
+}
+
+var (
+	mediumCapacityRegexp = regexp.MustCompile(`^(\d+) MBytes`)
+	mediumInUseRegexp    = regexp.MustCompile(`\(UUID: ([^)]+)\)`)
+)
+
+// CreateDisk creates and registers new disk image
+func CreateDisk(ctx context.Context, diskPath string, sizeMB int64, format, variant string) (*Medium, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"createmedium",
+		"disk",
+		fmt.Sprintf("--filename=%s", diskPath),
+		fmt.Sprintf("--size=%d", sizeMB),
+		fmt.Sprintf("--format=%s", format),
+		fmt.Sprintf("--variant=%s", variant),
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("CreateDisk: createmedium failed for %q: %w", diskPath, err)
+	}
+	return GetMediumInfo(ctx, diskPath)
+}
+
+// GetMediumInfo returns parsed `VBoxManage showmediuminfo` output, disk is either UUID or path
+func GetMediumInfo(ctx context.Context, disk string) (*Medium, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"showmediuminfo",
+		"disk",
+		disk,
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("GetMediumInfo: showmediuminfo failed for %q: %w", disk, err)
+	}
+	// example output:
+	// UUID:           0c5e4b3a-...
+	// Location:       /data/disk.vdi
+	// Storage format: VDI
+	// Format variant: dynamic default
+	// Capacity:       1024 MBytes
+	// In use by VMs:  ubuntu (UUID: 7b4c1ab3-...)
+	result := &Medium{
+		Variant: DiskVariantStandard,
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		keyValue := strings.SplitN(line, ":", 2)
+		if len(keyValue) < 2 {
+			continue
+		}
+		value := strings.TrimSpace(keyValue[1])
+		switch strings.TrimSpace(keyValue[0]) {
+		case "UUID":
+			result.ID = value
+		case "Location":
+			result.Location = value
+		case "Storage format":
+			result.Format = value
+		case "Format variant":
+			if strings.Contains(value, "fixed") {
+				result.Variant = DiskVariantFixed
+			}
+		case "Capacity":
+			if match := mediumCapacityRegexp.FindStringSubmatch(value); match != nil {
+				// regexp guarantees number
+				result.SizeMB, _ = strconv.ParseInt(match[1], 10, 64)
+			}
+		case "In use by VMs":
+			for _, match := range mediumInUseRegexp.FindAllStringSubmatch(value, -1) {
+				result.AttachedTo = append(result.AttachedTo, match[1])
+			}
+		}
+	}
+	return result, nil
+}
+
+// Medium types accepted by ListMediums
+const (
+	MediumTypeHDD    = "hdd"
+	MediumTypeDVD    = "dvd"
+	MediumTypeFloppy = "floppy"
+)
+
+// mediumListCommands maps medium type to `VBoxManage list` subcommand
+var mediumListCommands = map[string]string{
+	MediumTypeHDD:    "hdds",
+	MediumTypeDVD:    "dvds",
+	MediumTypeFloppy: "floppies",
+}
+
+// MediumInfo describes medium of VirtualBox media registry
+type MediumInfo struct {
+	UUID string
+	// ParentUUID is empty for base medium
+	ParentUUID string
+	State      string
+	// Type is medium type with base/diff suffix, e.g. "normal (base)" or "readonly"
+	Type       string
+	Location   string
+	Format     string
+	CapacityMB int64
+	// InUse means that medium is attached to some vm or its snapshot
+	InUse bool
+}
+
+// ListMediums returns registered media of given type, MediumTypeHDD, MediumTypeDVD or MediumTypeFloppy
+func ListMediums(ctx context.Context, mediumType string) ([]MediumInfo, error) {
+	subcommand, ok := mediumListCommands[mediumType]
+	if !ok {
+		return nil, fmt.Errorf("ListMediums: unknown medium type %q", mediumType)
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"list",
+		subcommand,
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ListMediums: list %s failed: %w", subcommand, err)
+	}
+	// example block:
+	// UUID:           0c5e4b3a-...
+	// Parent UUID:    base
+	// State:          created
+	// Type:           normal (base)
+	// Location:       /data/disk.vdi
+	// Storage format: VDI
+	// Capacity:       1024 MBytes
+	result := []MediumInfo{}
+	for _, block := range parseListBlocks(stdout) {
+		medium := MediumInfo{
+			UUID:     block["UUID"],
+			State:    block["State"],
+			Type:     block["Type"],
+			Location: block["Location"],
+			Format:   block["Storage format"],
+		}
+		if medium.UUID == "" {
+			continue
+		}
+		_, medium.InUse = block["In use by VMs"]
+		if parent := block["Parent UUID"]; parent != "base" {
+			medium.ParentUUID = parent
+		}
+		if match := mediumCapacityRegexp.FindStringSubmatch(block["Capacity"]); match != nil {
+			// regexp guarantees number
+			medium.CapacityMB, _ = strconv.ParseInt(match[1], 10, 64)
+		}
+		result = append(result, medium)
+	}
+	return result, nil
+}
+
+// ResizeDisk grows disk to given size, VirtualBox is not able to resize disk in use by running vm
+func ResizeDisk(ctx context.Context, disk string, sizeMB int64) (*Medium, error) {
+	medium, err := GetMediumInfo(ctx, disk)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeDisk: %w", err)
+	}
+	for _, vmID := range medium.AttachedTo {
+		vminfo, err := GetVMInfo(ctx, vmID)
+		if err != nil {
+			return nil, fmt.Errorf("ResizeDisk: %w", err)
+		}
+		if vminfo.State == Running {
+			return nil, fmt.Errorf("ResizeDisk: disk %s is attached to running vm %s, power vm off to resize it", medium.Location, vminfo.Name)
+		}
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"modifymedium",
+		"disk",
+		disk,
+		fmt.Sprintf("--resize=%d", sizeMB),
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeDisk: modifymedium failed for %q: %w", disk, err)
+	}
+	return GetMediumInfo(ctx, disk)
+}
+
+// CompactDisk reclaims zeroed blocks of dynamically allocated disk image, so that its file shrinks.
+// Disk must not be used by running vm
+func CompactDisk(ctx context.Context, vmdkPath string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"modifymedium",
+		"disk",
+		vmdkPath,
+		"--compact",
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("CompactDisk: modifymedium failed for %q: %w", vmdkPath, err)
+	}
+	return nil
+}
+
+// DeleteDisk unregisters disk and deletes its file, disk must be detached from all vms
+func DeleteDisk(ctx context.Context, disk string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"closemedium",
+		"disk",
+		disk,
+		"--delete",
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("DeleteDisk: closemedium failed for %q: %w", disk, err)
+	}
+	return nil
+}
+
+// AttachDisk attaches registered disk to the first free slot of vm storage controllers,
+// vm must be powered off
+func AttachDisk(ctx context.Context, vmName, diskID string) (*VirtualboxVMInfo, error) {
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("AttachDisk: %w", err)
+	}
+	controllerName, port, device, found := "", 0, 0, false
+	for _, ctl := range vminfo.StorageControllers {
+		for p := 0; p < ctl.PortCount && !found; p++ {
+			for d := 0; d < ctl.DevicesPerPort(); d++ {
+				medium, ok := vminfo.StorageAttachments[fmt.Sprintf("%s-%d-%d", ctl.Name, p, d)]
+				if !ok || medium == "none" {
+					controllerName, port, device, found = ctl.Name, p, d, true
+					break
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("No free storage controller port to attach disk %s", diskID)
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"storageattach",
+		vmName,
+		fmt.Sprintf("--storagectl=%s", controllerName),
+		fmt.Sprintf("--port=%d", port),
+		fmt.Sprintf("--device=%d", device),
+		"--type=hdd",
+		fmt.Sprintf("--medium=%s", diskID),
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("AttachDisk: storageattach failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
+
+// DetachDisk removes disk from vm storage controller slot, disk itself is kept.
+// Vm must be powered off, detaching disk which isn't attached is not an error
+func DetachDisk(ctx context.Context, vmName, diskID string) (*VirtualboxVMInfo, error) {
+	medium, err := GetMediumInfo(ctx, diskID)
+	if err != nil {
+		return nil, fmt.Errorf("DetachDisk: %w", err)
+	}
+	vminfo, err := GetVMInfo(ctx, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("DetachDisk: %w", err)
+	}
+	for _, attachment := range vminfo.Attachments {
+		if attachment.Medium != medium.Location {
+			continue
+		}
+		cmd := exec.Command(
+			"VBoxManage",
+			"storageattach",
+			vmName,
+			fmt.Sprintf("--storagectl=%s", attachment.Controller),
+			fmt.Sprintf("--port=%d", attachment.Port),
+			fmt.Sprintf("--device=%d", attachment.Device),
+			"--medium=none",
+		)
+		_, err = runGetOutput(ctx, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("DetachDisk: storageattach failed for %q: %w", vmName, err)
+		}
+	}
+	return GetVMInfo(ctx, vmName)
+}
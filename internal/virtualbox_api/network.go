@@ -0,0 +1,370 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+type HostOnlyInterface struct {
+	Name        string
+	GUID        string
+	DHCP        bool
+	IPAddress   string
+	NetworkMask string
+	NetworkName string
+}
+
+type DHCPServer struct {
+	NetworkName string
+	IPAddress   string
+	LowerIP     string
+	UpperIP     string
+	NetworkMask string
+	Enabled     bool
+}
+
+// HostOnlyNetwork describes host-only network of VirtualBox 7.0+ (macOS hosts use them instead of interfaces)
+type HostOnlyNetwork struct {
+	Name        string
+	GUID        string
+	Enabled     bool
+	NetworkMask string
+	LowerIP     string
+	UpperIP     string
+	NetworkName string
+}
+
+type NATNetwork struct {
+	Name        string
+	Network     string
+	Gateway     string
+	DHCPEnabled bool
+	Enabled     bool
+}
+
+type BridgedInterface struct {
+	Name        string
+	GUID        string
+	IPAddress   string
+	NetworkMask string
+	Status      string
+}
+
+// parseListBlocks parses `VBoxManage list` output, which consists of
+// "Key: value" blocks separated by empty lines
+func parseListBlocks(stdout string) []map[string]string {
+	result := []map[string]string{}
+	block := map[string]string{}
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				result = append(result, block)
+				block = map[string]string{}
+			}
+			continue
+		}
+		keyValue := strings.SplitN(line, ":", 2)
+		if len(keyValue) < 2 {
+			continue
+		}
+		key := strings.TrimSpace(keyValue[0])
+		if _, ok := block[key]; ok {
+			// repeated key, only the first one is meaningful for us
+			continue
+		}
+		block[key] = strings.TrimSpace(keyValue[1])
+	}
+	if len(block) > 0 {
+		result = append(result, block)
+	}
+	return result
+}
+
+func ListHostOnlyInterfaces(ctx context.Context) ([]HostOnlyInterface, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"list",
+		"hostonlyifs",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ListHostOnlyInterfaces: list hostonlyifs failed: %w", err)
+	}
+	result := []HostOnlyInterface{}
+	for _, block := range parseListBlocks(stdout) {
+		result = append(result, HostOnlyInterface{
+			Name:        block["Name"],
+			GUID:        block["GUID"],
+			DHCP:        block["DHCP"] == "Enabled",
+			IPAddress:   block["IPAddress"],
+			NetworkMask: block["NetworkMask"],
+			NetworkName: block["VBoxNetworkName"],
+		})
+	}
+	return result, nil
+}
+
+func GetHostOnlyInterface(ctx context.Context, name string) (*HostOnlyInterface, error) {
+	interfaces, err := ListHostOnlyInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetHostOnlyInterface: %w", err)
+	}
+	for i := range interfaces {
+		if interfaces[i].Name == name {
+			return &interfaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Host-only interface %s: %w", name, ErrNotFound)
+}
+
+var hostOnlyInterfaceCreatedRegexp = regexp.MustCompile(`Interface '([^']+)' was successfully created`)
+
+// CreateHostOnlyInterface creates new host-only interface and returns its name
+func CreateHostOnlyInterface(ctx context.Context) (string, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"hostonlyif",
+		"create",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("CreateHostOnlyInterface: hostonlyif create failed: %w", err)
+	}
+	// example output:
+	// Interface 'vboxnet0' was successfully created
+	match := hostOnlyInterfaceCreatedRegexp.FindStringSubmatch(stdout)
+	if match == nil {
+		return "", fmt.Errorf("Unable to parse created interface name from: %q", stdout)
+	}
+	return match[1], nil
+}
+
+func ConfigureHostOnlyInterface(ctx context.Context, name, ip, netmask string) (*HostOnlyInterface, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"hostonlyif",
+		"ipconfig",
+		name,
+		fmt.Sprintf("--ip=%s", ip),
+		fmt.Sprintf("--netmask=%s", netmask),
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ConfigureHostOnlyInterface: hostonlyif ipconfig failed for %q: %w", name, err)
+	}
+	return GetHostOnlyInterface(ctx, name)
+}
+
+func RemoveHostOnlyInterface(ctx context.Context, name string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"hostonlyif",
+		"remove",
+		name,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("RemoveHostOnlyInterface: hostonlyif remove failed for %q: %w", name, err)
+	}
+	return nil
+}
+
+// HostOnlyNetworkName returns internal network name VirtualBox uses for host-only interface
+func HostOnlyNetworkName(interfaceName string) string {
+	return "HostInterfaceNetworking-" + interfaceName
+}
+
+func ListDHCPServers(ctx context.Context) ([]DHCPServer, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"list",
+		"dhcpservers",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ListDHCPServers: list dhcpservers failed: %w", err)
+	}
+	result := []DHCPServer{}
+	for _, block := range parseListBlocks(stdout) {
+		result = append(result, DHCPServer{
+			NetworkName: block["NetworkName"],
+			IPAddress:   block["Dhcpd IP"],
+			LowerIP:     block["LowerIPAddress"],
+			UpperIP:     block["UpperIPAddress"],
+			NetworkMask: block["NetworkMask"],
+			Enabled:     block["Enabled"] == "Yes",
+		})
+	}
+	return result, nil
+}
+
+// GetDHCPServer returns dhcp server of given network, or nil if network has no dhcp server
+func GetDHCPServer(ctx context.Context, networkName string) (*DHCPServer, error) {
+	servers, err := ListDHCPServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDHCPServer: %w", err)
+	}
+	for i := range servers {
+		if servers[i].NetworkName == networkName {
+			return &servers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SetDHCPServer creates or updates dhcp server of given network
+func SetDHCPServer(ctx context.Context, server DHCPServer) error {
+	existing, err := GetDHCPServer(ctx, server.NetworkName)
+	if err != nil {
+		return fmt.Errorf("SetDHCPServer: %w", err)
+	}
+	command := "add"
+	if existing != nil {
+		command = "modify"
+	}
+	enable := "--disable"
+	if server.Enabled {
+		enable = "--enable"
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"dhcpserver",
+		command,
+		fmt.Sprintf("--netname=%s", server.NetworkName),
+		fmt.Sprintf("--ip=%s", server.IPAddress),
+		fmt.Sprintf("--netmask=%s", server.NetworkMask),
+		fmt.Sprintf("--lowerip=%s", server.LowerIP),
+		fmt.Sprintf("--upperip=%s", server.UpperIP),
+		enable,
+	)
+	_, err = runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("SetDHCPServer: dhcpserver %s failed for %q: %w", command, server.NetworkName, err)
+	}
+	return nil
+}
+
+func RemoveDHCPServer(ctx context.Context, networkName string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"dhcpserver",
+		"remove",
+		fmt.Sprintf("--netname=%s", networkName),
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil && !IsObjectNotFound(err) {
+		return fmt.Errorf("RemoveDHCPServer: dhcpserver remove failed for %q: %w", networkName, err)
+	}
+	return nil
+}
+
+// ListHostOnlyNetworks returns host-only networks, they exist only since VirtualBox 7.0
+func ListHostOnlyNetworks(ctx context.Context) ([]HostOnlyNetwork, error) {
+	result := []HostOnlyNetwork{}
+	version, err := GetVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListHostOnlyNetworks: %w", err)
+	}
+	if !version.AtLeast(7, 0) {
+		return result, nil
+	}
+	cmd := exec.Command(
+		"VBoxManage",
+		"list",
+		"hostonlynets",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ListHostOnlyNetworks: list hostonlynets failed: %w", err)
+	}
+	// example output:
+	// Name:            HostNetwork
+	// GUID:            1c4a3d5e-...
+	// State:           Enabled
+	// NetworkMask:     255.255.255.0
+	// LowerIP:         192.168.56.100
+	// UpperIP:         192.168.56.199
+	// VBoxNetworkName: hostonly-HostNetwork
+	for _, block := range parseListBlocks(stdout) {
+		if block["Name"] == "" {
+			continue
+		}
+		result = append(result, HostOnlyNetwork{
+			Name:        block["Name"],
+			GUID:        block["GUID"],
+			Enabled:     block["State"] == "Enabled",
+			NetworkMask: block["NetworkMask"],
+			LowerIP:     block["LowerIP"],
+			UpperIP:     block["UpperIP"],
+			NetworkName: block["VBoxNetworkName"],
+		})
+	}
+	return result, nil
+}
+
+func ListNATNetworks(ctx context.Context) ([]NATNetwork, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"list",
+		"natnetworks",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ListNATNetworks: list natnetworks failed: %w", err)
+	}
+	// VirtualBox 7.0 prints "Name", "Gateway" and "DHCP Server",
+	// older versions print "NetworkName", "IP" and "DHCP Enabled"
+	result := []NATNetwork{}
+	for _, block := range parseListBlocks(stdout) {
+		name := block["Name"]
+		if name == "" {
+			name = block["NetworkName"]
+		}
+		if name == "" {
+			// "NAT Networks:" header
+			continue
+		}
+		gateway := block["Gateway"]
+		if gateway == "" {
+			gateway = block["IP"]
+		}
+		dhcp := block["DHCP Server"]
+		if dhcp == "" {
+			dhcp = block["DHCP Enabled"]
+		}
+		result = append(result, NATNetwork{
+			Name:        name,
+			Network:     block["Network"],
+			Gateway:     gateway,
+			DHCPEnabled: dhcp == "Yes",
+			Enabled:     block["Enabled"] == "Yes",
+		})
+	}
+	return result, nil
+}
+
+func ListBridgedInterfaces(ctx context.Context) ([]BridgedInterface, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"list",
+		"bridgedifs",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("ListBridgedInterfaces: list bridgedifs failed: %w", err)
+	}
+	result := []BridgedInterface{}
+	for _, block := range parseListBlocks(stdout) {
+		result = append(result, BridgedInterface{
+			Name:        block["Name"],
+			GUID:        block["GUID"],
+			IPAddress:   block["IPAddress"],
+			NetworkMask: block["NetworkMask"],
+			Status:      block["Status"],
+		})
+	}
+	return result, nil
+}
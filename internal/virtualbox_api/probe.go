@@ -0,0 +1,107 @@
+package virtualboxapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ProbeType string
+
+const (
+	TCPProbe  ProbeType = "tcp"
+	HTTPProbe ProbeType = "http"
+)
+
+// ReadinessProbe describes check which is repeated until guest service becomes available
+type ReadinessProbe struct {
+	Type ProbeType
+	Host string
+	Port string
+	// Path and ExpectedStatus are used by http probes only
+	Path               string
+	ExpectedStatus     int
+	TLS                bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	Interval           time.Duration
+}
+
+// WaitForReadiness repeats probe until it succeeds or probe timeout expires,
+// returned error contains the last probe failure
+func WaitForReadiness(ctx context.Context, probe ReadinessProbe) error {
+	ctx, cancel := context.WithTimeout(ctx, probe.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(probe.Interval)
+	defer ticker.Stop()
+	for {
+		err := probe.run(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Readiness probe %s didn't succeed in %s, last error: %s", probe, probe.Timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (probe ReadinessProbe) String() string {
+	if probe.Type == HTTPProbe {
+		return probe.url()
+	}
+	return fmt.Sprintf("%s://%s", probe.Type, net.JoinHostPort(probe.Host, probe.Port))
+}
+
+func (probe ReadinessProbe) url() string {
+	scheme := "http"
+	if probe.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(probe.Host, probe.Port), probe.Path)
+}
+
+func (probe ReadinessProbe) run(ctx context.Context) error {
+	// single attempt should never outlive the interval between attempts
+	ctx, cancel := context.WithTimeout(ctx, probe.Interval)
+	defer cancel()
+	switch probe.Type {
+	case TCPProbe:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(probe.Host, probe.Port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case HTTPProbe:
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					// appliances usually come with self-signed certificates
+					InsecureSkipVerify: probe.InsecureSkipVerify, //nolint:gosec
+				},
+			},
+		}
+		defer client.CloseIdleConnections()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.url(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != probe.ExpectedStatus {
+			return errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode))
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown probe type: %s", probe.Type)
+}
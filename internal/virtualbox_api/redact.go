@@ -0,0 +1,50 @@
+package virtualboxapi
+
+import "strings"
+
+// redactedValue replaces secrets in command lines shown in errors and logs
+const redactedValue = "<redacted>"
+
+// isSecretName reports whether flag or property name carries secret value,
+// e.g. --password, --user-password, VNCPassword or /terraform/user-data guest property.
+// Password files are not secrets themselves.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, "file") {
+		return false
+	}
+	return strings.Contains(name, "password") || strings.Contains(name, "passwd") || strings.Contains(name, "secret") ||
+		strings.Contains(name, "user-data")
+}
+
+// RedactArgs returns copy of command arguments with secret values replaced,
+// both "--flag value" and "--flag=value" forms as well as "Name=value" and "/guest/property value" properties are handled
+func RedactArgs(args []string) []string {
+	result := make([]string, len(args))
+	copy(result, args)
+	for i := 0; i < len(result); i++ {
+		name, _, hasValue := strings.Cut(result[i], "=")
+		if strings.HasPrefix(name, "-") && !hasValue && strings.HasSuffix(strings.ToLower(name), "file") && i+1 < len(result) {
+			// file path like /run/secrets/vm isn't a secret property name
+			i++
+			continue
+		}
+		if !isSecretName(name) {
+			continue
+		}
+		if hasValue {
+			result[i] = name + "=" + redactedValue
+			continue
+		}
+		if (strings.HasPrefix(name, "-") || strings.HasPrefix(name, "/")) && i+1 < len(result) {
+			i++
+			result[i] = redactedValue
+		}
+	}
+	return result
+}
+
+// commandLine returns command line suitable for errors and logs
+func commandLine(args []string) string {
+	return strings.Join(RedactArgs(args), " ")
+}
@@ -0,0 +1,81 @@
+package virtualboxapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "flag with separate value",
+			args: []string{"VBoxManage", "unattended", "install", "vm", "--password", "s3cret", "--user", "admin"},
+			want: []string{"VBoxManage", "unattended", "install", "vm", "--password", redactedValue, "--user", "admin"},
+		},
+		{
+			name: "flag with equal sign",
+			args: []string{"VBoxManage", "unattended", "install", "vm", "--password=s3cret", "--user=admin"},
+			want: []string{"VBoxManage", "unattended", "install", "vm", "--password=" + redactedValue, "--user=admin"},
+		},
+		{
+			name: "compound flag name",
+			args: []string{"VBoxManage", "unattended", "install", "vm", "--user-password", "s3cret", "--admin-password=s3cret"},
+			want: []string{"VBoxManage", "unattended", "install", "vm", "--user-password", redactedValue, "--admin-password=" + redactedValue},
+		},
+		{
+			name: "key value property",
+			args: []string{"VBoxManage", "modifyvm", "vm", "--vrdeproperty", "VNCPassword=s3cret", "--vrdeproperty", "TCP/Ports=5900"},
+			want: []string{"VBoxManage", "modifyvm", "vm", "--vrdeproperty", "VNCPassword=" + redactedValue, "--vrdeproperty", "TCP/Ports=5900"},
+		},
+		{
+			name: "guest property",
+			args: []string{"VBoxManage", "guestproperty", "set", "vm", "/terraform/user-data", "#cloud-config"},
+			want: []string{"VBoxManage", "guestproperty", "set", "vm", "/terraform/user-data", redactedValue},
+		},
+		{
+			name: "teleporter password",
+			args: []string{"VBoxManage", "modifyvm", "vm", "--teleporterpassword", "s3cret", "--teleporterport", "6000"},
+			want: []string{"VBoxManage", "modifyvm", "vm", "--teleporterpassword", redactedValue, "--teleporterport", "6000"},
+		},
+		{
+			name: "password file is kept",
+			args: []string{"VBoxManage", "modifyvm", "vm", "--teleporterpasswordfile", "/run/secrets/vm", "--passwordfile=/run/secrets/vm"},
+			want: []string{"VBoxManage", "modifyvm", "vm", "--teleporterpasswordfile", "/run/secrets/vm", "--passwordfile=/run/secrets/vm"},
+		},
+		{
+			name: "flag without value",
+			args: []string{"VBoxManage", "unattended", "install", "vm", "--password"},
+			want: []string{"VBoxManage", "unattended", "install", "vm", "--password"},
+		},
+		{
+			// positional value looking like secret name isn't a flag
+			name: "positional value",
+			args: []string{"VBoxManage", "createvm", "--name", "password", "--register"},
+			want: []string{"VBoxManage", "createvm", "--name", "password", "--register"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{}, tt.args...)
+			got := RedactArgs(args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RedactArgs = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("RedactArgs modified arguments: %q", args)
+			}
+		})
+	}
+}
+
+func TestVBoxManageErrorRedactsCommand(t *testing.T) {
+	err := vboxManageError([]string{"unattended", "install", "vm", "--password", "s3cret"}, "VBoxManage: error: failed")
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error shows secret: %s", err)
+	}
+}
@@ -0,0 +1,84 @@
+package virtualboxapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// RemoteHost is VirtualBox host reached over ssh, see SetRemoteHost
+type RemoteHost struct {
+	Host string
+	// User, KeyPath and Port are optional, ssh client configuration and agent are used then
+	User    string
+	KeyPath string
+	Port    int
+}
+
+// remoteHost makes VBoxManage run over ssh when set
+var remoteHost *RemoteHost
+
+// ErrNotSupportedRemotely is returned by operations which need local access to files of VirtualBox host
+var ErrNotSupportedRemotely = errors.New("operation needs local access to VirtualBox host and is not supported with remote host")
+
+// SetRemoteHost makes all subsequent VBoxManage invocations run on given host over ssh.
+// System ssh client is used, so host keys are checked against known_hosts as usual
+func SetRemoteHost(host RemoteHost) error {
+	if host.Host == "" || strings.HasPrefix(host.Host, "-") {
+		return fmt.Errorf("invalid remote host %q", host.Host)
+	}
+	if host.Port < 0 || host.Port > 65535 {
+		return fmt.Errorf("remote port %d is out of 1-65535", host.Port)
+	}
+	if host.KeyPath != "" {
+		_, err := os.Stat(host.KeyPath)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := exec.LookPath("ssh")
+	if err != nil {
+		return fmt.Errorf("ssh client not found: %w", err)
+	}
+	remoteHost = &host
+	return nil
+}
+
+// IsRemote reports whether VBoxManage runs on remote host
+func IsRemote() bool {
+	return remoteHost != nil
+}
+
+// shellQuote quotes argument for remote shell, ssh passes command line to it as a single string
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// remoteCommand wraps VBoxManage command into ssh invocation when remote host is configured.
+// Stdout and stderr of remote VBoxManage are passed through ssh as is, killing ssh on
+// context cancellation closes the session
+func remoteCommand(cmd *exec.Cmd) *exec.Cmd {
+	if remoteHost == nil || len(cmd.Args) == 0 || cmd.Args[0] != "VBoxManage" {
+		return cmd
+	}
+	args := []string{"-o", "BatchMode=yes"}
+	if remoteHost.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(remoteHost.Port))
+	}
+	if remoteHost.KeyPath != "" {
+		args = append(args, "-i", remoteHost.KeyPath)
+	}
+	target := remoteHost.Host
+	if remoteHost.User != "" {
+		target = remoteHost.User + "@" + target
+	}
+	quoted := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		quoted[i] = shellQuote(arg)
+	}
+	args = append(args, target, "--", strings.Join(quoted, " "))
+	return exec.Command("ssh", args...)
+}
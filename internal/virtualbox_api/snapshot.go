@@ -0,0 +1,151 @@
+package virtualboxapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Snapshot is vm snapshot, ParentUUID is empty for the root snapshot
+type Snapshot struct {
+	UUID        string
+	Name        string
+	Description string
+	ParentUUID  string
+	IsCurrent   bool
+}
+
+// snapshotKeyRegexp matches snapshot keys of `snapshot list --machinereadable`, where
+// suffix is the node path: SnapshotName is the root, SnapshotName-1 its first child,
+// SnapshotName-1-2 the second child of that one
+var snapshotKeyRegexp = regexp.MustCompile(`^Snapshot(Name|UUID|Description)((?:-\d+)*)$`)
+
+// noSnapshotsMessage is printed with non-zero exit code by `snapshot list` for vm without snapshots
+const noSnapshotsMessage = "does not have any snapshots"
+
+// parseSnapshots flattens snapshot tree, parents come before their children
+func parseSnapshots(output string) []Snapshot {
+	nodes := map[string]*Snapshot{}
+	order := []string{}
+	node := func(path string) *Snapshot {
+		if _, ok := nodes[path]; !ok {
+			nodes[path] = &Snapshot{}
+			order = append(order, path)
+		}
+		return nodes[path]
+	}
+	currentUUID := ""
+	lines := strings.Split(output, "\n")
+	for i := 0; i < len(lines); i++ {
+		keyValue := strings.SplitN(strings.TrimRight(lines[i], "\r"), "=", 2)
+		if len(keyValue) < 2 {
+			continue
+		}
+		key, value := keyValue[0], keyValue[1]
+		// description may span several lines, its value ends with closing quote
+		for strings.HasPrefix(value, `"`) && (len(value) == 1 || !strings.HasSuffix(value, `"`)) && i+1 < len(lines) {
+			i++
+			value += "\n" + strings.TrimRight(lines[i], "\r")
+		}
+		value = vmInfoValueToString(value)
+		if key == "CurrentSnapshotUUID" {
+			currentUUID = value
+			continue
+		}
+		match := snapshotKeyRegexp.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		switch match[1] {
+		case "Name":
+			node(match[2]).Name = value
+		case "UUID":
+			node(match[2]).UUID = value
+		case "Description":
+			node(match[2]).Description = value
+		}
+	}
+	result := make([]Snapshot, 0, len(order))
+	for _, path := range order {
+		snapshot := *nodes[path]
+		if path != "" {
+			if parent, ok := nodes[path[:strings.LastIndex(path, "-")]]; ok {
+				snapshot.ParentUUID = parent.UUID
+			}
+		}
+		snapshot.IsCurrent = currentUUID != "" && snapshot.UUID == currentUUID
+		result = append(result, snapshot)
+	}
+	return result
+}
+
+// ListSnapshots returns snapshot tree of vm as flat list, empty for vm without snapshots
+func ListSnapshots(ctx context.Context, vmName string) ([]Snapshot, error) {
+	cmd := exec.Command(
+		"VBoxManage",
+		"snapshot",
+		vmName,
+		"list",
+		"--machinereadable",
+	)
+	stdout, err := runGetOutput(ctx, cmd)
+	var vboxErr *VBoxManageError
+	if errors.As(err, &vboxErr) && strings.Contains(stdout+vboxErr.Stderr, noSnapshotsMessage) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ListSnapshots: snapshot list failed for %q: %w", vmName, err)
+	}
+	return parseSnapshots(stdout), nil
+}
+
+// TakeSnapshot takes snapshot of vm with given name
+func TakeSnapshot(ctx context.Context, vmName, snapshotName string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"snapshot",
+		vmName,
+		"take",
+		snapshotName,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("TakeSnapshot: snapshot take failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes vm snapshot, vm state is kept
+func DeleteSnapshot(ctx context.Context, vmName, snapshotName string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"snapshot",
+		vmName,
+		"delete",
+		snapshotName,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("DeleteSnapshot: snapshot delete failed for %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// ResetToSnapshot restores vm disks and settings from snapshot, vm must be powered off
+func ResetToSnapshot(ctx context.Context, vmName, snapshotName string) error {
+	cmd := exec.Command(
+		"VBoxManage",
+		"snapshot",
+		vmName,
+		"restore",
+		snapshotName,
+	)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("ResetToSnapshot: snapshot restore failed for %q: %w", vmName, err)
+	}
+	return nil
+}
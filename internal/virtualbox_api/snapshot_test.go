@@ -0,0 +1,121 @@
+package virtualboxapi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const (
+	rootUUID   = "1b2f5d3e-0000-4000-8000-000000000010"
+	childUUID  = "1b2f5d3e-0000-4000-8000-000000000011"
+	nestedUUID = "1b2f5d3e-0000-4000-8000-000000000012"
+	secondUUID = "1b2f5d3e-0000-4000-8000-000000000013"
+)
+
+func TestParseSnapshots(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Snapshot
+	}{
+		{
+			name:   "no snapshots",
+			output: "",
+			want:   []Snapshot{},
+		},
+		{
+			name: "single snapshot is current",
+			output: `SnapshotName="base"
+SnapshotUUID="` + rootUUID + `"
+SnapshotDescription=""
+CurrentSnapshotName="base"
+CurrentSnapshotUUID="` + rootUUID + `"
+CurrentSnapshotNode="SnapshotName"
+`,
+			want: []Snapshot{
+				{UUID: rootUUID, Name: "base", IsCurrent: true},
+			},
+		},
+		{
+			name: "nested tree",
+			output: `SnapshotName="base"
+SnapshotUUID="` + rootUUID + `"
+SnapshotName-1="configured"
+SnapshotUUID-1="` + childUUID + `"
+SnapshotName-1-1="patched"
+SnapshotUUID-1-1="` + nestedUUID + `"
+SnapshotName-2="experiment"
+SnapshotUUID-2="` + secondUUID + `"
+CurrentSnapshotName="patched"
+CurrentSnapshotUUID="` + nestedUUID + `"
+CurrentSnapshotNode="SnapshotName-1-1"
+`,
+			want: []Snapshot{
+				{UUID: rootUUID, Name: "base"},
+				{UUID: childUUID, Name: "configured", ParentUUID: rootUUID},
+				{UUID: nestedUUID, Name: "patched", ParentUUID: childUUID, IsCurrent: true},
+				{UUID: secondUUID, Name: "experiment", ParentUUID: rootUUID},
+			},
+		},
+		{
+			name: "current snapshot in the middle of tree",
+			output: `SnapshotName="base"
+SnapshotUUID="` + rootUUID + `"
+SnapshotName-1="configured"
+SnapshotUUID-1="` + childUUID + `"
+SnapshotName-1-1="patched"
+SnapshotUUID-1-1="` + nestedUUID + `"
+CurrentSnapshotName="configured"
+CurrentSnapshotUUID="` + childUUID + `"
+CurrentSnapshotNode="SnapshotName-1"
+`,
+			want: []Snapshot{
+				{UUID: rootUUID, Name: "base"},
+				{UUID: childUUID, Name: "configured", ParentUUID: rootUUID, IsCurrent: true},
+				{UUID: nestedUUID, Name: "patched", ParentUUID: childUUID},
+			},
+		},
+		{
+			name: "multi-line description with equal sign",
+			output: "SnapshotName=\"base\"\r\n" +
+				"SnapshotUUID=\"" + rootUUID + "\"\r\n" +
+				"SnapshotDescription=\"clean install\r\n" +
+				"key=value\"\r\n" +
+				"CurrentSnapshotUUID=\"" + rootUUID + "\"\r\n",
+			want: []Snapshot{
+				{UUID: rootUUID, Name: "base", Description: "clean install\nkey=value", IsCurrent: true},
+			},
+		},
+		{
+			name: "name with spaces",
+			output: `SnapshotName="before upgrade 2"
+SnapshotUUID="` + rootUUID + `"
+`,
+			want: []Snapshot{
+				{UUID: rootUUID, Name: "before upgrade 2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSnapshots(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("snapshots = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSnapshotsWithoutSnapshots(t *testing.T) {
+	fakeVBoxManage(t, func(args []string) (string, error) {
+		return "", vboxManageError(args, "This machine does not have any snapshots")
+	})
+	snapshots, err := ListSnapshots(context.Background(), "vm")
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("snapshots = %+v, want none", snapshots)
+	}
+}
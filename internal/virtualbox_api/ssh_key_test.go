@@ -0,0 +1,113 @@
+package virtualboxapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInjectSSHKeyOffline(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_rsa.pub")
+	err := os.WriteFile(keyPath, []byte("ssh-ed25519 AAAA user@host\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker, err := sshKeyMarker("root", keyPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		extradata string
+		state     string
+		injected  bool
+		restarted bool
+	}{
+		{
+			name:      "matching marker",
+			extradata: "Value: " + marker,
+			state:     "running",
+		},
+		{
+			name:      "mismatching marker on running vm",
+			extradata: "Value: 0b9c2a",
+			state:     "running",
+			injected:  true,
+			restarted: true,
+		},
+		{
+			name:      "mismatching marker on stopped vm",
+			extradata: "Value: 0b9c2a",
+			state:     "poweroff",
+			injected:  true,
+		},
+		{
+			name:      "missing marker",
+			extradata: "No value set!",
+			state:     "running",
+			injected:  true,
+			restarted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			stored := ""
+			calls := fakeVBoxManage(t, func(args []string) (string, error) {
+				switch args[0] {
+				case "getextradata":
+					return tt.extradata, nil
+				case "setextradata":
+					stored = args[3]
+					return "", nil
+				case "showvminfo":
+					return showVMInfo("vm", state) +
+						"storagecontrollername0=\"SATA\"\n" +
+						"\"SATA-0-0\"=\"/vms/vm/disk.vdi\"\n", nil
+				case "controlvm":
+					state = "poweroff"
+					return "", nil
+				case "startvm":
+					state = "running"
+					return "", nil
+				case "virt-sysprep":
+					if state != "poweroff" {
+						t.Errorf("virt-sysprep ran on %s vm", state)
+					}
+					return "", nil
+				}
+				t.Fatalf("unexpected command %v", args)
+				return "", nil
+			})
+
+			err := InjectSSHKeyOffline(context.Background(), "vm", "", "root", keyPath, nil, Headless, StateTimeouts{Start: time.Second, Stop: time.Second})
+			if err != nil {
+				t.Fatalf("InjectSSHKeyOffline: %v", err)
+			}
+			if got := calls.count("virt-sysprep -a /vms/vm/disk.vdi"); got != boolCount(tt.injected) {
+				t.Errorf("virt-sysprep called %d times, injected = %v", got, tt.injected)
+			}
+			if tt.injected && stored != marker {
+				t.Errorf("stored marker = %q, want %q", stored, marker)
+			}
+			if got := calls.count("controlvm vm poweroff"); got != boolCount(tt.restarted) {
+				t.Errorf("poweroff called %d times, restarted = %v", got, tt.restarted)
+			}
+			if got := calls.count("startvm vm"); got != boolCount(tt.restarted) {
+				t.Errorf("startvm called %d times, restarted = %v", got, tt.restarted)
+			}
+			if state != tt.state {
+				t.Errorf("vm left in state %s, want %s", state, tt.state)
+			}
+		})
+	}
+}
+
+func boolCount(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package virtualboxapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandStats accumulates timing of executed commands, keyed by subcommand
+type commandStats struct {
+	mu       sync.Mutex
+	enabled  bool
+	commands map[string]*CommandStat
+	slowest  time.Duration
+	slowCmd  string
+}
+
+// CommandStat describes calls of one subcommand, e.g. `VBoxManage showvminfo`
+type CommandStat struct {
+	Command string
+	Calls   int
+	Total   time.Duration
+	Max     time.Duration
+}
+
+var stats = &commandStats{commands: map[string]*CommandStat{}}
+
+// EnableStats turns on collection of command counters and durations
+func EnableStats(enabled bool) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.enabled = enabled
+}
+
+// StatsEnabled reports whether command counters are collected
+func StatsEnabled() bool {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return stats.enabled
+}
+
+// recordCommand adds single command execution to stats
+func recordCommand(args []string, duration time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if !stats.enabled || len(args) == 0 {
+		return
+	}
+	key := args[0]
+	if len(args) > 1 {
+		key += " " + args[1]
+	}
+	stat, ok := stats.commands[key]
+	if !ok {
+		stat = &CommandStat{Command: key}
+		stats.commands[key] = stat
+	}
+	stat.Calls++
+	stat.Total += duration
+	if duration > stat.Max {
+		stat.Max = duration
+	}
+	if duration > stats.slowest {
+		stats.slowest = duration
+		stats.slowCmd = commandLine(args)
+	}
+}
+
+// StatsSummary returns table of accumulated command stats, sorted by total duration
+func StatsSummary() string {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	result := []CommandStat{}
+	for _, stat := range stats.commands {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Total > result[j].Total
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-32s %8s %12s %12s\n", "COMMAND", "CALLS", "TOTAL", "MAX")
+	for _, stat := range result {
+		fmt.Fprintf(&b, "%-32s %8d %12s %12s\n", stat.Command, stat.Calls, stat.Total.Round(time.Millisecond), stat.Max.Round(time.Millisecond))
+	}
+	if stats.slowCmd != "" {
+		fmt.Fprintf(&b, "slowest call (%s): %s\n", stats.slowest.Round(time.Millisecond), stats.slowCmd)
+	}
+	return b.String()
+}
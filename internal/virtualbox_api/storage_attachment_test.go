@@ -0,0 +1,207 @@
+package virtualboxapi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseStorageAttachment(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  StorageAttachment
+		ok    bool
+	}{
+		{
+			key:   "SATA Controller-0-0",
+			value: "/vms/vm/disk.vdi",
+			want:  StorageAttachment{Controller: "SATA Controller", Port: 0, Device: 0, Medium: "/vms/vm/disk.vdi"},
+			ok:    true,
+		},
+		{
+			key:   "IDE Controller-1-0",
+			value: "emptydrive",
+			want:  StorageAttachment{Controller: "IDE Controller", Port: 1, Device: 0, Medium: "emptydrive"},
+			ok:    true,
+		},
+		{
+			key:   "NVMe-12-0",
+			value: "/vms/vm/nvme.vdi",
+			want:  StorageAttachment{Controller: "NVMe", Port: 12, Device: 0, Medium: "/vms/vm/nvme.vdi"},
+			ok:    true,
+		},
+		{
+			// controller names may end with "-<n>" themselves
+			key:   "Disks-2-3-0",
+			value: "none",
+			want:  StorageAttachment{Controller: "Disks-2", Port: 3, Device: 0, Medium: "none"},
+			ok:    true,
+		},
+		{key: "SATA Controller-ImageUUID-0-0", value: "1b2f5d3e-0000-4000-8000-000000000002"},
+		{key: "IDE Controller-IsEjected", value: "off"},
+		{key: "storagecontrollername0", value: "SATA Controller"},
+		{key: "SATA Controller-0", value: "/vms/vm/disk.vdi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := parseStorageAttachment(tt.key, tt.value)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("attachment = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVMInfoAttachments(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        string
+		attachments []StorageAttachment
+		disks       []string
+	}{
+		{
+			name: "sata",
+			info: `storagecontrollername0="SATA"
+storagecontrollertype0="IntelAhci"
+storagecontrollerportcount0="30"
+"SATA-0-0"="/vms/vm/disk1.vmdk"
+"SATA-ImageUUID-0-0"="1b2f5d3e-0000-4000-8000-000000000002"
+"SATA-1-0"="/vms/vm/disk2.vdi"
+"SATA-ImageUUID-1-0"="1b2f5d3e-0000-4000-8000-000000000003"
+"SATA-2-0"="none"
+`,
+			attachments: []StorageAttachment{
+				{Controller: "SATA", Port: 0, Device: 0, Medium: "/vms/vm/disk1.vmdk"},
+				{Controller: "SATA", Port: 1, Device: 0, Medium: "/vms/vm/disk2.vdi"},
+				{Controller: "SATA", Port: 2, Device: 0, Medium: "none"},
+			},
+			disks: []string{"/vms/vm/disk1.vmdk", "/vms/vm/disk2.vdi"},
+		},
+		{
+			name: "ide with ejected dvd",
+			info: `storagecontrollername0="IDE Controller"
+storagecontrollertype0="PIIX4"
+storagecontrollerportcount0="2"
+"IDE Controller-0-0"="/vms/vm/disk.vdi"
+"IDE Controller-ImageUUID-0-0"="1b2f5d3e-0000-4000-8000-000000000002"
+"IDE Controller-0-1"="none"
+"IDE Controller-1-0"="emptydrive"
+"IDE Controller-IsEjected"="on"
+"IDE Controller-1-1"="/isos/VBoxGuestAdditions.iso"
+"IDE Controller-ImageUUID-1-1"="1b2f5d3e-0000-4000-8000-000000000004"
+`,
+			attachments: []StorageAttachment{
+				{Controller: "IDE Controller", Port: 0, Device: 0, Medium: "/vms/vm/disk.vdi"},
+				{Controller: "IDE Controller", Port: 0, Device: 1, Medium: "none"},
+				{Controller: "IDE Controller", Port: 1, Device: 0, Medium: "emptydrive"},
+				{Controller: "IDE Controller", Port: 1, Device: 1, Medium: "/isos/VBoxGuestAdditions.iso"},
+			},
+			disks: []string{"/vms/vm/disk.vdi"},
+		},
+		{
+			name: "nvme",
+			info: `storagecontrollername0="NVMe"
+storagecontrollertype0="NVMe"
+storagecontrollerportcount0="1"
+"NVMe-0-0"="/vms/vm/disk.vdi"
+"NVMe-ImageUUID-0-0"="1b2f5d3e-0000-4000-8000-000000000002"
+`,
+			attachments: []StorageAttachment{
+				{Controller: "NVMe", Port: 0, Device: 0, Medium: "/vms/vm/disk.vdi"},
+			},
+			disks: []string{"/vms/vm/disk.vdi"},
+		},
+		{
+			name: "virtio",
+			info: `storagecontrollername0="VirtIO"
+storagecontrollertype0="VirtioSCSI"
+storagecontrollerportcount0="2"
+"VirtIO-0-0"="/vms/vm/disk.vdi"
+"VirtIO-ImageUUID-0-0"="1b2f5d3e-0000-4000-8000-000000000002"
+"VirtIO-1-0"="none"
+`,
+			attachments: []StorageAttachment{
+				{Controller: "VirtIO", Port: 0, Device: 0, Medium: "/vms/vm/disk.vdi"},
+				{Controller: "VirtIO", Port: 1, Device: 0, Medium: "none"},
+			},
+			disks: []string{"/vms/vm/disk.vdi"},
+		},
+		{
+			name: "disks sorted by controller order",
+			info: `storagecontrollername0="IDE"
+storagecontrollertype0="PIIX4"
+storagecontrollername1="SATA"
+storagecontrollertype1="IntelAhci"
+"SATA-0-0"="/vms/vm/data.vdi"
+"IDE-0-0"="/vms/vm/os.vdi"
+"IDE-1-0"="emptydrive"
+`,
+			attachments: []StorageAttachment{
+				{Controller: "SATA", Port: 0, Device: 0, Medium: "/vms/vm/data.vdi"},
+				{Controller: "IDE", Port: 0, Device: 0, Medium: "/vms/vm/os.vdi"},
+				{Controller: "IDE", Port: 1, Device: 0, Medium: "emptydrive"},
+			},
+			disks: []string{"/vms/vm/os.vdi", "/vms/vm/data.vdi"},
+		},
+		{
+			// keys of unknown controllers, e.g. per attachment flags of newer versions, aren't attachments
+			name: "unknown controller keys",
+			info: `storagecontrollername0="SATA"
+storagecontrollertype0="IntelAhci"
+"SATA-0-0"="/vms/vm/disk.vdi"
+"SATA-nonrotational-0-0"="off"
+`,
+			attachments: []StorageAttachment{
+				{Controller: "SATA", Port: 0, Device: 0, Medium: "/vms/vm/disk.vdi"},
+			},
+			disks: []string{"/vms/vm/disk.vdi"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeVBoxManage(t, func(args []string) (string, error) {
+				return showVMInfo("vm", "poweroff") + tt.info, nil
+			})
+			vminfo, err := GetVMInfo(context.Background(), "vm")
+			if err != nil {
+				t.Fatalf("GetVMInfo: %v", err)
+			}
+			if !reflect.DeepEqual(vminfo.Attachments, tt.attachments) {
+				t.Errorf("attachments = %+v, want %+v", vminfo.Attachments, tt.attachments)
+			}
+			disks := []string{}
+			for _, disk := range vminfo.Disks() {
+				disks = append(disks, disk.Medium)
+			}
+			if !reflect.DeepEqual(disks, tt.disks) {
+				t.Errorf("disks = %q, want %q", disks, tt.disks)
+			}
+			if len(tt.disks) > 0 && vminfo.VmdkPath != tt.disks[0] {
+				t.Errorf("VmdkPath = %q, want %q", vminfo.VmdkPath, tt.disks[0])
+			}
+		})
+	}
+}
+
+func TestDiskControllerStoragectlArgs(t *testing.T) {
+	tests := []struct {
+		controller DiskController
+		name       string
+		args       []string
+	}{
+		{controller: DiskControllerSATA, name: "SATA", args: []string{"--add=sata", "--portcount=4"}},
+		{controller: DiskControllerNVMe, name: "NVMe", args: []string{"--add=pcie", "--controller=NVMe", "--portcount=4"}},
+		// vm created before disk_controller existed
+		{controller: "", name: "SATA", args: []string{"--add=sata", "--portcount=4"}},
+	}
+	for _, tt := range tests {
+		name, args := tt.controller.storagectlArgs()
+		if name != tt.name || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("storagectlArgs(%q) = %q %q, want %q %q", tt.controller, name, args, tt.name, tt.args)
+		}
+	}
+}
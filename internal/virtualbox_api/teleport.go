@@ -0,0 +1,42 @@
+package virtualboxapi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// TeleporterArgs returns modifyvm arguments making vm wait for incoming teleport on given port
+// when it's started, empty password is not passed. Teleporter could be configured on powered off vm only
+func TeleporterArgs(enabled bool, port int64, password string) []string {
+	if !enabled {
+		return []string{"--teleporter", "off"}
+	}
+	args := []string{"--teleporter", "on", "--teleporterport", strconv.FormatInt(port, 10)}
+	if password != "" {
+		args = append(args, "--teleporterpassword", password)
+	}
+	return args
+}
+
+// Teleport moves running vm to teleport target listening on host:port,
+// local vm is powered off when teleport succeeds
+func Teleport(ctx context.Context, vmName, host string, port int64, password string) (*VirtualboxVMInfo, error) {
+	args := []string{
+		"controlvm",
+		vmName,
+		"teleport",
+		"--host", host,
+		"--port", strconv.FormatInt(port, 10),
+	}
+	if password != "" {
+		args = append(args, "--password", password)
+	}
+	cmd := exec.Command("VBoxManage", args...)
+	_, err := runGetOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Teleport: teleport failed for %q: %w", vmName, err)
+	}
+	return GetVMInfo(ctx, vmName)
+}
@@ -0,0 +1,48 @@
+package virtualboxapi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTruncateOutput(t *testing.T) {
+	usage := func(n int) []string {
+		lines := []string{}
+		for i := 1; i <= n; i++ {
+			lines = append(lines, fmt.Sprintf("  --option%d <value>", i))
+		}
+		return lines
+	}
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "short output is kept as is",
+			output: "VBoxManage: error: Unknown option: --foo\n\n",
+			want:   "VBoxManage: error: Unknown option: --foo\n\n",
+		},
+		{
+			name:   "usage after error",
+			output: strings.Join(append([]string{"VBoxManage: error: Unknown option: --foo", "Usage:"}, usage(10)...), "\n"),
+			want: strings.Join(append([]string{"VBoxManage: error: Unknown option: --foo", "Usage:"}, usage(3)...), "\n") +
+				"\n... 7 lines skipped",
+		},
+		{
+			// older VBoxManage prints syntax error after usage text
+			name:   "error after usage",
+			output: strings.Join(append(append([]string{"Usage:"}, usage(10)...), "", "Syntax error: Invalid parameter '--foo'"), "\n"),
+			want: strings.Join(append([]string{"Usage:"}, usage(3)...), "\n") +
+				"\n... 7 lines skipped\nSyntax error: Invalid parameter '--foo'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateOutput(tt.output, 5); got != tt.want {
+				t.Errorf("truncateOutput =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package virtualboxapi
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// commandUser describes account VBoxManage is executed as, nil means current user
+type commandUser struct {
+	name string
+	uid  uint32
+	gid  uint32
+	home string
+}
+
+var runAsUser *commandUser
+
+// vboxUserHome overrides VirtualBox registry directory when set, see SetVBoxUserHome
+var vboxUserHome string
+
+// SetVBoxUserHome makes all subsequent VirtualBox invocations use vm registry in given directory
+func SetVBoxUserHome(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("SetVBoxUserHome: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("SetVBoxUserHome: %q is not a directory", dir)
+	}
+	vboxUserHome = dir
+	return nil
+}
+
+// applyCommandUser makes VBoxManage and VBoxHeadless commands run as configured user
+// with configured VirtualBox registry
+func applyCommandUser(cmd *exec.Cmd) {
+	if len(cmd.Args) == 0 || (cmd.Args[0] != "VBoxManage" && cmd.Args[0] != "VBoxHeadless") {
+		return
+	}
+	if runAsUser == nil && vboxUserHome == "" {
+		return
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	if runAsUser != nil {
+		setCredential(cmd, runAsUser)
+		// VirtualBox keeps vm registry in $HOME/.config/VirtualBox
+		env = append(env,
+			"HOME="+runAsUser.home,
+			"USER="+runAsUser.name,
+			"LOGNAME="+runAsUser.name,
+		)
+	}
+	if vboxUserHome != "" {
+		env = append(env, "VBOX_USER_HOME="+vboxUserHome)
+	}
+	cmd.Env = env
+}
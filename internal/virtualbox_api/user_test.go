@@ -0,0 +1,76 @@
+package virtualboxapi
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resetVBoxUserHome restores VirtualBox registry directory at the end of test
+func resetVBoxUserHome(t *testing.T) {
+	prev := vboxUserHome
+	t.Cleanup(func() { vboxUserHome = prev })
+}
+
+func TestSetVBoxUserHome(t *testing.T) {
+	resetVBoxUserHome(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "VirtualBox.xml")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr string
+	}{
+		{name: "directory", dir: dir},
+		{name: "missing directory", dir: filepath.Join(dir, "missing"), wantErr: "SetVBoxUserHome: "},
+		{name: "file", dir: file, wantErr: "is not a directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vboxUserHome = ""
+			err := SetVBoxUserHome(tt.dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if vboxUserHome != "" {
+					t.Errorf("vboxUserHome = %q after failure", vboxUserHome)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetVBoxUserHome: %v", err)
+			}
+			if vboxUserHome != tt.dir {
+				t.Errorf("vboxUserHome = %q, want %q", vboxUserHome, tt.dir)
+			}
+		})
+	}
+}
+
+func TestApplyCommandUserVBoxUserHome(t *testing.T) {
+	resetVBoxUserHome(t)
+	vboxUserHome = "/srv/virtualbox"
+	for _, program := range []string{"VBoxManage", "VBoxHeadless"} {
+		cmd := exec.Command(program, "--version")
+		applyCommandUser(cmd)
+		found := false
+		for _, env := range cmd.Env {
+			found = found || env == "VBOX_USER_HOME=/srv/virtualbox"
+		}
+		if !found {
+			t.Errorf("%s env misses VBOX_USER_HOME", program)
+		}
+	}
+	// helper programs don't touch VirtualBox registry
+	cmd := exec.Command("qemu-img", "--version")
+	applyCommandUser(cmd)
+	if cmd.Env != nil {
+		t.Errorf("qemu-img env was modified")
+	}
+}
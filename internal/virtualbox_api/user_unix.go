@@ -0,0 +1,63 @@
+//go:build !windows
+
+package virtualboxapi
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// SetRunAsUser makes all subsequent VBoxManage invocations run as given user
+func SetRunAsUser(name string) error {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return fmt.Errorf("SetRunAsUser: %w", err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("SetRunAsUser: unexpected uid %q of user %s", u.Uid, name)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("SetRunAsUser: unexpected gid %q of user %s", u.Gid, name)
+	}
+	if euid := os.Geteuid(); euid != 0 && euid != int(uid) {
+		return fmt.Errorf("SetRunAsUser: provider is running as uid %d and has no permission to switch to user %s", euid, name)
+	}
+	runAsUser = &commandUser{
+		name: u.Username,
+		uid:  uint32(uid),
+		gid:  uint32(gid),
+		home: u.HomeDir,
+	}
+	return nil
+}
+
+func setCredential(cmd *exec.Cmd, u *commandUser) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: u.uid, Gid: u.gid}
+}
+
+// detachProcess puts process into its own process group,
+// so it doesn't receive signals sent to provider process group
+func detachProcess(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// CanBindPrivilegedPorts reports whether VirtualBox processes run as root and are able
+// to listen on host ports below 1024
+func CanBindPrivilegedPorts() bool {
+	if runAsUser != nil {
+		return runAsUser.uid == 0
+	}
+	return os.Geteuid() == 0
+}
@@ -0,0 +1,81 @@
+//go:build !windows
+
+package virtualboxapi
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"testing"
+)
+
+// resetRunAsUser restores VBoxManage user at the end of test
+func resetRunAsUser(t *testing.T) {
+	prev := runAsUser
+	t.Cleanup(func() { runAsUser = prev })
+}
+
+func TestSetRunAsUser(t *testing.T) {
+	resetRunAsUser(t)
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("current user is unknown: %v", err)
+	}
+	if err := SetRunAsUser(current.Username); err != nil {
+		t.Fatalf("SetRunAsUser(%s): %v", current.Username, err)
+	}
+	if runAsUser == nil || runAsUser.name != current.Username || runAsUser.home != current.HomeDir {
+		t.Errorf("runAsUser = %+v, want %s", runAsUser, current.Username)
+	}
+
+	cmd := exec.Command("VBoxManage", "list", "vms")
+	applyCommandUser(cmd)
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Credential == nil || cmd.SysProcAttr.Credential.Uid != runAsUser.uid {
+		t.Errorf("VBoxManage credential = %+v, want uid %d", cmd.SysProcAttr, runAsUser.uid)
+	}
+	if !containsString(cmd.Env, "HOME="+current.HomeDir) {
+		t.Errorf("VBoxManage env misses HOME of %s", current.Username)
+	}
+	// helper programs keep provider credentials
+	cmd = exec.Command("virt-sysprep", "--version")
+	applyCommandUser(cmd)
+	if cmd.SysProcAttr != nil || cmd.Env != nil {
+		t.Errorf("virt-sysprep was modified: %+v", cmd)
+	}
+}
+
+func TestSetRunAsUserErrors(t *testing.T) {
+	resetRunAsUser(t)
+	runAsUser = nil
+	err := SetRunAsUser("terraform-provider-virtualbox-missing-user")
+	if err == nil || !strings.HasPrefix(err.Error(), "SetRunAsUser: ") {
+		t.Errorf("SetRunAsUser(missing user) error = %v", err)
+	}
+	if os.Geteuid() != 0 {
+		err = SetRunAsUser("root")
+		if err == nil || !strings.Contains(err.Error(), "no permission to switch to user root") {
+			t.Errorf("SetRunAsUser(root) error = %v", err)
+		}
+	}
+	if runAsUser != nil {
+		t.Errorf("runAsUser is set after failure: %+v", runAsUser)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDetachProcess(t *testing.T) {
+	cmd := exec.Command("VBoxHeadless", "--startvm", "vm")
+	detachProcess(cmd)
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		t.Errorf("VBoxHeadless stays in provider process group: %+v", cmd.SysProcAttr)
+	}
+}
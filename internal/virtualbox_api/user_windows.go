@@ -0,0 +1,33 @@
+//go:build windows
+
+package virtualboxapi
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is DETACHED_PROCESS process creation flag
+const detachedProcess = 0x00000008
+
+// SetRunAsUser makes all subsequent VBoxManage invocations run as given user
+func SetRunAsUser(name string) error {
+	return errors.New("SetRunAsUser: running VBoxManage as another user is not supported on Windows")
+}
+
+func setCredential(cmd *exec.Cmd, u *commandUser) {}
+
+// detachProcess starts process without console in new process group,
+// so it doesn't receive console events sent to provider
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+	}
+}
+
+// CanBindPrivilegedPorts reports whether VirtualBox processes are able to listen on
+// host ports below 1024, Windows doesn't restrict them
+func CanBindPrivilegedPorts() bool {
+	return true
+}
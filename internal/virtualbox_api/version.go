@@ -0,0 +1,261 @@
+package virtualboxapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is parsed output of `VBoxManage --version`, e.g. "7.0.12r159484"
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether version is equal to or newer than major.minor
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+// Less reports whether version is older than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+var versionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+// ParseVersion parses VBoxManage version string, distribution specific suffixes
+// like "6.1.38_Ubuntur153438" are ignored
+func ParseVersion(raw string) (Version, error) {
+	raw = strings.TrimSpace(raw)
+	match := versionRegexp.FindStringSubmatch(raw)
+	if match == nil {
+		return Version{}, fmt.Errorf("Unable to parse VirtualBox version: %q", raw)
+	}
+	// regexp guarantees numbers
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	return Version{Major: major, Minor: minor, Patch: patch, Raw: raw}, nil
+}
+
+var (
+	versionOnce   sync.Once
+	cachedVersion Version
+	versionErr    error
+)
+
+// GetVersion returns installed VirtualBox version, it's detected only once per provider run
+func GetVersion(ctx context.Context) (Version, error) {
+	versionOnce.Do(func() {
+		cmd := exec.Command(
+			"VBoxManage",
+			"--version",
+		)
+		stdout, err := runGetOutput(ctx, cmd)
+		if err != nil {
+			versionErr = err
+			return
+		}
+		cachedVersion, versionErr = ParseVersion(stdout)
+	})
+	return cachedVersion, versionErr
+}
+
+// Option is a logical vm setting, which is spelled differently by different VirtualBox versions
+type Option string
+
+const (
+	OptionNatLocalhostReachable Option = "nat_localhostreachable"
+	OptionAudioDriver           Option = "audio_driver"
+	OptionGraphicsController    Option = "graphics_controller"
+	OptionCableConnected        Option = "cable_connected"
+	OptionTPMType               Option = "tpm_type"
+	// OptionX86Guests is not a modifyvm flag, it's ability to run x86 guests gated by host platform
+	OptionX86Guests Option = "x86_guests"
+)
+
+type optionFlag struct {
+	major, minor int
+	// flag is a format string, %d is replaced with adapter number for per-nic options
+	flag string
+}
+
+// modifyvmFlags lists modifyvm flag spellings, newest first.
+// Options missing for some version are not supported by it.
+var modifyvmFlags = map[Option][]optionFlag{
+	OptionNatLocalhostReachable: {
+		{7, 0, "--nat-localhostreachable%d"},
+	},
+	OptionAudioDriver: {
+		{7, 0, "--audio-driver"},
+		{6, 0, "--audio"},
+	},
+	OptionGraphicsController: {
+		{6, 0, "--graphicscontroller"},
+	},
+	OptionCableConnected: {
+		{7, 0, "--cable-connected%d"},
+		{6, 0, "--cableconnected%d"},
+	},
+	OptionTPMType: {
+		{7, 0, "--tpm-type"},
+	},
+}
+
+// UnsupportedOptionError means that installed VirtualBox doesn't have requested feature
+type UnsupportedOptionError struct {
+	Option  Option
+	Version Version
+	// Required is the oldest version supporting option
+	Required Version
+}
+
+func (e *UnsupportedOptionError) Error() string {
+	return fmt.Sprintf(
+		"%s support requires VirtualBox %d.%d or later; detected version is %d.%d",
+		e.Option, e.Required.Major, e.Required.Minor, e.Version.Major, e.Version.Minor,
+	)
+}
+
+// IsUnsupportedOption reports whether err means that feature doesn't exist in installed VirtualBox
+func IsUnsupportedOption(err error) bool {
+	var unsupportedErr *UnsupportedOptionError
+	return errors.As(err, &unsupportedErr)
+}
+
+// ModifyVMFlagForVersion returns modifyvm flag for option spelled as given version expects it,
+// nic is used by per-adapter options only
+func ModifyVMFlagForVersion(v Version, option Option, nic int) (string, error) {
+	for _, f := range modifyvmFlags[option] {
+		if v.AtLeast(f.major, f.minor) {
+			if strings.Contains(f.flag, "%d") {
+				return fmt.Sprintf(f.flag, nic), nil
+			}
+			return f.flag, nil
+		}
+	}
+	return "", &UnsupportedOptionError{Option: option, Version: v, Required: RequiredVersion(option)}
+}
+
+// RequiredVersion returns the oldest VirtualBox version supporting option
+func RequiredVersion(option Option) Version {
+	flags := modifyvmFlags[option]
+	if len(flags) == 0 {
+		return Version{}
+	}
+	oldest := flags[len(flags)-1]
+	return Version{Major: oldest.major, Minor: oldest.minor}
+}
+
+// ModifyVMFlag returns modifyvm flag for option spelled as installed VirtualBox expects it
+func ModifyVMFlag(ctx context.Context, option Option, nic int) (string, error) {
+	v, err := GetVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	return ModifyVMFlagForVersion(v, option, nic)
+}
+
+// Platform is architecture of guests VirtualBox host runs, ARM builds of VirtualBox 7.1+ run ARM guests only
+type Platform string
+
+const (
+	PlatformX86 Platform = "x86"
+	PlatformARM Platform = "arm"
+)
+
+// platformUnsupportedOptions lists options missing on host platform regardless of VirtualBox version
+var platformUnsupportedOptions = map[Platform][]Option{
+	PlatformARM: {
+		OptionX86Guests,
+	},
+}
+
+// UnsupportedPlatformError means that host platform doesn't have requested feature
+type UnsupportedPlatformError struct {
+	Option   Option
+	Platform Platform
+}
+
+func (e *UnsupportedPlatformError) Error() string {
+	return fmt.Sprintf("%s is not supported by VirtualBox on %s hosts", e.Option, e.Platform)
+}
+
+// CheckPlatformSupport returns UnsupportedPlatformError if option isn't available on platform,
+// unknown platform supports everything
+func CheckPlatformSupport(platform Platform, option Option) error {
+	for _, unsupported := range platformUnsupportedOptions[platform] {
+		if unsupported == option {
+			return &UnsupportedPlatformError{Option: option, Platform: platform}
+		}
+	}
+	return nil
+}
+
+var hostArchitectureRegexp = regexp.MustCompile(`(?mi)^[^:\n]*architecture:\s*(\S+)`)
+
+// platformFromArch maps architecture name reported by VirtualBox or Go runtime to platform
+func platformFromArch(arch string) Platform {
+	switch strings.ToLower(arch) {
+	case "arm", "arm64", "aarch64", "arm32", "armv8":
+		return PlatformARM
+	case "x86", "amd64", "x86_64", "386", "x64":
+		return PlatformX86
+	}
+	return ""
+}
+
+var (
+	platformOnce   sync.Once
+	cachedPlatform Platform
+	platformErr    error
+)
+
+// GetHostPlatform returns platform of VirtualBox host, detected once per provider run. Architecture is read
+// from `VBoxManage list hostinfo`, versions which don't report it are assumed to run on provider host.
+// Empty platform means that it's unknown
+func GetHostPlatform(ctx context.Context) (Platform, error) {
+	platformOnce.Do(func() {
+		cmd := exec.Command(
+			"VBoxManage",
+			"list",
+			"hostinfo",
+		)
+		stdout, err := runGetOutput(ctx, cmd)
+		if err != nil {
+			platformErr = fmt.Errorf("GetHostPlatform: list hostinfo failed: %w", err)
+			return
+		}
+		if match := hostArchitectureRegexp.FindStringSubmatch(stdout); match != nil {
+			cachedPlatform = platformFromArch(match[1])
+			return
+		}
+		// architecture of remote host can't be guessed
+		if !IsRemote() {
+			cachedPlatform = platformFromArch(runtime.GOARCH)
+		}
+	})
+	return cachedPlatform, platformErr
+}
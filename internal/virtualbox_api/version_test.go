@@ -0,0 +1,115 @@
+package virtualboxapi
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Version
+		wantErr bool
+	}{
+		{raw: "6.0.24r139119\n", want: Version{Major: 6, Minor: 0, Patch: 24, Raw: "6.0.24r139119"}},
+		{raw: "6.1.38_Ubuntur153438", want: Version{Major: 6, Minor: 1, Patch: 38, Raw: "6.1.38_Ubuntur153438"}},
+		{raw: "6.1.50_BETA1r161072", want: Version{Major: 6, Minor: 1, Patch: 50, Raw: "6.1.50_BETA1r161072"}},
+		{raw: "7.0.12r159484", want: Version{Major: 7, Minor: 0, Patch: 12, Raw: "7.0.12r159484"}},
+		{raw: "7.0.14_Debianr161095", want: Version{Major: 7, Minor: 0, Patch: 14, Raw: "7.0.14_Debianr161095"}},
+		{raw: "  7.1.0r164728\r\n", want: Version{Major: 7, Minor: 1, Patch: 0, Raw: "7.1.0r164728"}},
+		{raw: "", wantErr: true},
+		{raw: "7.0", wantErr: true},
+		{raw: "WARNING: The vboxdrv kernel module is not loaded.\n7.0.12r159484", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseVersion(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseVersion(%q) = %v, want error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseVersion(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	v := Version{Major: 6, Minor: 1, Patch: 38}
+	tests := []struct {
+		major, minor int
+		atLeast      bool
+	}{
+		{5, 2, true},
+		{6, 0, true},
+		{6, 1, true},
+		{6, 2, false},
+		{7, 0, false},
+	}
+	for _, tt := range tests {
+		if got := v.AtLeast(tt.major, tt.minor); got != tt.atLeast {
+			t.Errorf("%s AtLeast(%d, %d) = %v, want %v", v, tt.major, tt.minor, got, tt.atLeast)
+		}
+	}
+	if !v.Less(Version{Major: 6, Minor: 1, Patch: 40}) || v.Less(Version{Major: 6, Minor: 0, Patch: 50}) || v.Less(v) {
+		t.Errorf("Less of %s compares versions wrong", v)
+	}
+}
+
+func TestModifyVMFlagForVersion(t *testing.T) {
+	v60 := Version{Major: 6, Minor: 0, Patch: 24}
+	v61 := Version{Major: 6, Minor: 1, Patch: 38}
+	v70 := Version{Major: 7, Minor: 0, Patch: 12}
+	tests := []struct {
+		name    string
+		version Version
+		option  Option
+		nic     int
+		want    string
+		wantErr string
+	}{
+		{name: "6.0 cable", version: v60, option: OptionCableConnected, nic: 1, want: "--cableconnected1"},
+		{name: "6.1 cable", version: v61, option: OptionCableConnected, nic: 2, want: "--cableconnected2"},
+		{name: "7.0 cable", version: v70, option: OptionCableConnected, nic: 1, want: "--cable-connected1"},
+		{name: "6.1 audio", version: v61, option: OptionAudioDriver, want: "--audio"},
+		{name: "7.0 audio", version: v70, option: OptionAudioDriver, want: "--audio-driver"},
+		{name: "7.0 tpm", version: v70, option: OptionTPMType, want: "--tpm-type"},
+		{
+			name:    "6.1 tpm",
+			version: v61,
+			option:  OptionTPMType,
+			wantErr: "tpm_type support requires VirtualBox 7.0 or later; detected version is 6.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ModifyVMFlagForVersion(tt.version, tt.option, tt.nic)
+			if tt.wantErr != "" {
+				if !IsUnsupportedOption(err) || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ModifyVMFlagForVersion: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("flag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+	if got := RequiredVersion(OptionCableConnected); got != (Version{Major: 6, Minor: 0}) {
+		t.Errorf("RequiredVersion(cable_connected) = %+v, want 6.0", got)
+	}
+	if got := RequiredVersion(OptionTPMType); got != (Version{Major: 7, Minor: 0}) {
+		t.Errorf("RequiredVersion(tpm_type) = %+v, want 7.0", got)
+	}
+}
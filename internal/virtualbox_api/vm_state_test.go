@@ -0,0 +1,203 @@
+package virtualboxapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStopVMWaitsForDelayedPoweroff(t *testing.T) {
+	polls := 0
+	calls := fakeVBoxManage(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "controlvm":
+			return "", nil
+		case "showvminfo":
+			polls++
+			// controlvm poweroff returns before vm actually stops
+			if polls < 3 {
+				return showVMInfo("vm", "stopping"), nil
+			}
+			return showVMInfo("vm", "poweroff"), nil
+		}
+		t.Fatalf("unexpected command %v", args)
+		return "", nil
+	})
+
+	vminfo, err := StopVM(context.Background(), "vm", 10*time.Second)
+	if err != nil {
+		t.Fatalf("StopVM: %v", err)
+	}
+	if vminfo.State != Poweroff {
+		t.Errorf("state = %q, want %q", vminfo.State, Poweroff)
+	}
+	if got := calls.count("controlvm vm poweroff"); got != 1 {
+		t.Errorf("poweroff called %d times, want 1", got)
+	}
+	if polls != 3 {
+		t.Errorf("polled %d times, want 3", polls)
+	}
+}
+
+func TestWaitForState(t *testing.T) {
+	tests := []struct {
+		name    string
+		states  []string
+		want    []VMStateType
+		timeout time.Duration
+		result  VMStateType
+		wantErr string
+	}{
+		{
+			name:    "already in state",
+			states:  []string{"poweroff"},
+			want:    []VMStateType{Poweroff, Aborted},
+			timeout: time.Second,
+			result:  Poweroff,
+		},
+		{
+			name:    "any of states",
+			states:  []string{"stopping", "aborted"},
+			want:    []VMStateType{Poweroff, Aborted},
+			timeout: 10 * time.Second,
+			result:  Aborted,
+		},
+		{
+			name:    "timeout",
+			states:  []string{"running"},
+			want:    []VMStateType{Poweroff},
+			timeout: 100 * time.Millisecond,
+			wantErr: "current state: running",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+			fakeVBoxManage(t, func(args []string) (string, error) {
+				// last state repeats once sequence is over
+				state := tt.states[len(tt.states)-1]
+				if polls < len(tt.states) {
+					state = tt.states[polls]
+				}
+				polls++
+				return showVMInfo("vm", state), nil
+			})
+
+			vminfo, err := WaitForState(context.Background(), "vm", tt.timeout, tt.want...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WaitForState: %v", err)
+			}
+			if vminfo.State != tt.result {
+				t.Errorf("state = %q, want %q", vminfo.State, tt.result)
+			}
+		})
+	}
+}
+
+func TestWaitForStateCancelled(t *testing.T) {
+	fakeVBoxManage(t, func(args []string) (string, error) {
+		return showVMInfo("vm", "running"), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WaitForState(ctx, "vm", time.Minute, Poweroff)
+	if err == nil {
+		t.Fatal("WaitForState succeeded on cancelled context")
+	}
+}
+
+func TestWaitForStateReportsVBoxManageError(t *testing.T) {
+	fakeVBoxManage(t, func(args []string) (string, error) {
+		return "", vboxManageError(args, "VBoxManage: error: Could not find a registered machine named 'vm'\nVBOX_E_OBJECT_NOT_FOUND")
+	})
+
+	_, err := WaitForState(context.Background(), "vm", time.Second, Poweroff)
+	if !IsObjectNotFound(err) {
+		t.Fatalf("error = %v, want object not found", err)
+	}
+}
+
+func TestRetryableStartError(t *testing.T) {
+	args := []string{"startvm", "vm", "--type=headless"}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "startup race",
+			err:  vboxManageError(args, "VBoxManage: error: The virtual machine 'vm' has terminated unexpectedly during startup with exit code 1 (0x1)"),
+			want: "has terminated unexpectedly during startup",
+		},
+		{
+			name: "COM initialization race",
+			err:  vboxManageError(args, "VBoxManage: error: Failed to create the VirtualBox object!\nVERR_MAIN_CONFIG_CONSTRUCTOR_COM_ERROR"),
+			want: "VERR_MAIN_CONFIG_CONSTRUCTOR_COM_ERROR",
+		},
+		{
+			name: "permanent error",
+			err:  vboxManageError(args, "VBoxManage: error: Could not find a registered machine named 'vm'"),
+		},
+		{
+			name: "no error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableStartError(tt.err); got != tt.want {
+				t.Errorf("retryableStartError = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartVMRetriesStartupRace(t *testing.T) {
+	starts := 0
+	calls := fakeVBoxManage(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "startvm":
+			starts++
+			if starts == 1 {
+				return "", vboxManageError(args, "VBoxManage: error: The virtual machine 'vm' has terminated unexpectedly during startup")
+			}
+			return "", nil
+		case "showvminfo":
+			return showVMInfo("vm", "running"), nil
+		}
+		t.Fatalf("unexpected command %v", args)
+		return "", nil
+	})
+
+	vminfo, err := StartVM(context.Background(), "vm", Headless, 10*time.Second)
+	if err != nil {
+		t.Fatalf("StartVM: %v", err)
+	}
+	if vminfo.State != Running {
+		t.Errorf("state = %q, want %q", vminfo.State, Running)
+	}
+	if got := calls.count("startvm vm --type=headless"); got != 2 {
+		t.Errorf("startvm called %d times, want 2", got)
+	}
+}
+
+func TestStartVMDoesNotRetryPermanentError(t *testing.T) {
+	calls := fakeVBoxManage(t, func(args []string) (string, error) {
+		return "", vboxManageError(args, "VBoxManage: error: Could not find a registered machine named 'vm'\nVBOX_E_OBJECT_NOT_FOUND")
+	})
+
+	_, err := StartVM(context.Background(), "vm", Headless, 10*time.Second)
+	if !IsObjectNotFound(err) {
+		t.Fatalf("error = %v, want object not found", err)
+	}
+	if got := calls.count("startvm"); got != 1 {
+		t.Errorf("startvm called %d times, want 1", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/AvoidMe/terraform-provider-virtualbox/internal/provider"
+	virtualboxapi "github.com/AvoidMe/terraform-provider-virtualbox/internal/virtualbox_api"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 )
 
@@ -42,6 +43,10 @@ func main() {
 
 	err := providerserver.Serve(context.Background(), provider.New(version), opts)
 
+	if virtualboxapi.StatsEnabled() {
+		log.Printf("[INFO] VBoxManage call stats on provider shutdown\n%s", virtualboxapi.StatsSummary())
+	}
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}